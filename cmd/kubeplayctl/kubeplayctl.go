@@ -1,12 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"strings"
 
-	"github.com/kubeplay/gameserver/pkg/rest"
 	"github.com/kubeplay/gameserver/pkg/types"
 	"github.com/kubeplay/gameserver/pkg/utils"
 
@@ -46,22 +46,16 @@ func cmd() *cobra.Command {
 					return err
 				}
 			}
-			kind := obj.GetObjectKind()
-			if kind == types.GameKind {
+			if obj.GetObjectKind() == types.GameKind {
 				return fmt.Errorf("kind %q not implemented", types.GameKind)
-			} else if kind == types.PolicyKind {
-				kind = "policie"
 			}
-			restResourceKind := fmt.Sprintf("%ss", strings.ToLower(kind))
-			err := rest.NewRequest(nil, cli.GameServerURL).Post().
-				Bearer(cli.AccessToken.String()).
-				RequestURI("v1", restResourceKind).
-				Body(obj).
-				Do().
-				Into(obj)
+			c, err := cli.Client()
 			if err != nil {
 				return err
 			}
+			if err := c.Create(context.Background(), obj); err != nil {
+				return err
+			}
 			meta := obj.GetObjectMeta()
 			fmt.Printf("%s %q created with uid %s\n", obj.GetObjectKind(), meta.Name, meta.UID)
 			return nil
@@ -75,6 +69,14 @@ func cmd() *cobra.Command {
 		Use:   "delete RESOURCE",
 		Short: "Delete a resource from the game server.",
 	}
+	authCmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Inspect authorization.",
+	}
+	challenge := &cobra.Command{
+		Use:   "challenge",
+		Short: "Manage challenge bundles and revisions.",
+	}
 	join := &cobra.Command{
 		Use:   "join",
 		Short: "Join into a particular event.",
@@ -84,6 +86,8 @@ func cmd() *cobra.Command {
 		Use:     "kubeplay",
 		Short:   "kubeplay manages the game server.",
 		PostRun: cleanup,
+		// configure TLS for every subcommand
+		PersistentPreRunE: cli.LoadTLSConfig,
 		Run: func(cmd *cobra.Command, args []string) {
 			if cli.O.ShowVersionAndExit {
 				version.PrintAndExit()
@@ -96,6 +100,9 @@ func cmd() *cobra.Command {
 		cli.EventCreateCmd(),
 		cli.ChallengeCreateCmd(),
 		cli.PolicyCreateCmd(),
+		cli.UserCreateCmd(),
+		cli.RoleBindingCreateCmd(),
+		cli.EventHookCreateCmd(),
 	)
 	create.Flags().StringVarP(&cli.O.CreateInput, "filename", "f", "", "Filename, directory, or URL to files to use to create the resource.")
 	get.AddCommand(
@@ -103,23 +110,54 @@ func cmd() *cobra.Command {
 		cli.ChallengeGetCmd(),
 		cli.EventGetCmd(),
 		cli.PolicyGetCmd(),
+		cli.UserGetCmd(),
+		cli.SessionGetCmd(),
+		cli.RoleGetCmd(),
+		cli.RoleBindingGetCmd(),
+		cli.EventHookGetCmd(),
 	)
+	get.PersistentFlags().StringVarP(&cli.O.Selectors.Labels, "selector", "l", "", "Label selector to filter on, e.g.: -l track=kubernetes,level in (easy,medium).")
+	get.PersistentFlags().StringVar(&cli.O.Selectors.Fields, "field-selector", "", "Field selector to filter on, e.g.: --field-selector challenge=foo,status.phase=Running.")
 	del.AddCommand(
 		cli.EventDeleteCmd(),
+		cli.GameDeleteCmd(),
 		cli.ChallengeDeleteCmd(),
+		cli.UserDeleteCmd(),
+		cli.SessionDeleteCmd(),
+		cli.RoleDeleteCmd(),
+		cli.RoleBindingDeleteCmd(),
+		cli.EventHookDeleteCmd(),
+	)
+	challenge.AddCommand(
+		cli.ChallengePushCmd(),
+		cli.ChallengePullCmd(),
+		cli.ChallengeRevisionsCmd(),
+		cli.ChallengeDiffCmd(),
 	)
+	del.PersistentFlags().StringVar(&cli.O.Cascade, "cascade", string(types.DeletionBlock), "What happens to the games of the resource: block (refuse to delete), cascade (delete them) or orphan (keep them).")
+	del.PersistentFlags().Lookup("cascade").NoOptDefVal = string(types.DeletionCascade)
 	join.AddCommand(cli.EventJoinCmd())
+	authCmd.AddCommand(cli.AuthCanICmd())
 	root.AddCommand(
 		create,
 		del,
 		get,
 		join,
+		challenge,
+		authCmd,
 		cli.LoginCmd(),
+		cli.LogoutCmd(),
 		cli.GameSolveCmd(),
 		cli.HackChallengeCmd(),
 		cli.GameStartCmd(),
+		cli.ExportCmd(),
+		cli.ImportCmd(),
 	)
 	root.Flags().BoolVar(&cli.O.ShowVersionAndExit, "version", false, "Print version and exit.")
+	root.PersistentFlags().StringVar(&cli.O.TLS.CAFile, "certificate-authority", "", "Path to a CA bundle to verify the server certificate [$"+cli.KubeplayCAFileEnv+"].")
+	root.PersistentFlags().BoolVar(&cli.O.TLS.InsecureSkipVerify, "insecure-skip-tls-verify", false, "Don't verify the server certificate, insecure [$"+cli.KubeplayInsecureSkipVerifyEnv+"].")
+	root.PersistentFlags().StringVar(&cli.O.TLS.CertFile, "client-certificate", "", "Path to a client certificate to authenticate [$"+cli.KubeplayClientCertEnv+"].")
+	root.PersistentFlags().StringVar(&cli.O.TLS.KeyFile, "client-key", "", "Path to the private key of the client certificate [$"+cli.KubeplayClientKeyEnv+"].")
 	return &root
 }
 
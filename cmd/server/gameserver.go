@@ -1,19 +1,343 @@
 package main
 
 import (
-	"log"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sort"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/kubeplay/gameserver/pkg/api"
 	"github.com/kubeplay/gameserver/pkg/api/auth"
+	"github.com/kubeplay/gameserver/pkg/api/handlers"
+	"github.com/kubeplay/gameserver/pkg/audit"
+	"github.com/kubeplay/gameserver/pkg/config"
+	"github.com/kubeplay/gameserver/pkg/provisioner"
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+	"github.com/kubeplay/gameserver/pkg/version"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
-func main() {
+type cmdOptions struct {
+	ShowVersionAndExit bool
+	ConfigFile         string
+	GitHubRoleMappings []string
+
+	// values of the flags, only the ones set explicitly override the config file
+	config *config.ServerConfig
+}
+
+var o = cmdOptions{config: config.Default()}
+
+func cmd() *cobra.Command {
+	root := &cobra.Command{
+		Use:          "gameserver",
+		Short:        "gameserver serves the kubeplay API.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if o.ShowVersionAndExit {
+				version.PrintAndExit()
+			}
+			cfg, err := loadConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			return serve(cfg)
+		},
+	}
+	c := o.config
+	root.Flags().BoolVar(&o.ShowVersionAndExit, "version", false, "Print version and exit.")
+	root.Flags().StringVarP(&o.ConfigFile, "config", "c", "", "Path of the YAML configuration file, flags override its values.")
+	root.Flags().StringVar(&c.ListenAddr, "listen-addr", c.ListenAddr, "The address to serve the API.")
+	root.Flags().DurationVar(&c.ShutdownTimeout, "shutdown-timeout", c.ShutdownTimeout, "How long in-flight requests are drained when the server receives SIGTERM.")
+	root.Flags().StringVar(&c.TLS.CertFile, "tls-cert-file", "", "PEM encoded certificate to serve the API over HTTPS, it's reloaded when it changes.")
+	root.Flags().StringVar(&c.TLS.KeyFile, "tls-key-file", "", "PEM encoded private key of the TLS certificate.")
+	root.Flags().BoolVar(&c.TLS.SelfSigned, "tls-self-signed", false, "Generate a self-signed certificate pair when the files don't exist (development only).")
+	root.Flags().StringVar(&c.TLS.ClientCAFile, "tls-client-ca-file", "", "CA bundle to verify client certificates, the common name is the login and the organizations are the roles.")
+	root.Flags().BoolVar(&c.TLS.RequireClientCert, "tls-require-client-cert", false, "Reject connections without a valid client certificate.")
+	root.Flags().StringVar(&c.PoliciesFile, "policies-file", c.PoliciesFile, "Path where the casbin policies are persisted.")
+	root.Flags().StringSliceVar(&c.SigningKeys, "signing-key", nil, "PEM encoded private keys (RSA or ECDSA) to sign player tokens, the first key signs new tokens.")
+	root.Flags().StringVar(&c.Store.File, "db-file", c.Store.File, "Path of the database file.")
+	root.Flags().StringVar(&c.Store.Bucket, "db-bucket", c.Store.Bucket, "The bucket of the database where objects are stored.")
+	root.Flags().StringVar(&c.Audit.File, "audit-log-file", "", "Record the mutating requests as JSON lines in this file.")
+	root.Flags().IntVar(&c.Audit.MaxSizeMB, "audit-log-max-size", c.Audit.MaxSizeMB, "Rotate the audit log when it reaches this size in megabytes, 0 disables the rotation.")
+	root.Flags().IntVar(&c.Audit.MaxBackups, "audit-log-max-backups", c.Audit.MaxBackups, "How many rotated audit logs are kept, 0 keeps all of them.")
+	root.Flags().StringVar(&c.Audit.WebhookURL, "audit-webhook-url", "", "Send the audit events to this URL.")
+	root.Flags().StringVar(&c.Audit.PolicyFile, "audit-policy-file", "", "YAML policy deciding which requests and bodies are recorded.")
+	root.Flags().StringVar(&c.Provisioners.Exec.Command, "provisioner-exec-command", "", "Register the exec provisioner running this command, it receives the action and the game in environment variables.")
+	root.Flags().StringSliceVar(&c.Provisioners.Exec.Args, "provisioner-exec-args", nil, "Arguments passed to the exec provisioner command before the action.")
+	root.Flags().BoolVar(&c.Provisioners.Kubernetes.Enabled, "provisioner-kubernetes", false, "Register the kubernetes provisioner creating a namespace per game.")
+	root.Flags().StringVar(&c.Provisioners.Kubernetes.Host, "kubernetes-host", "", "URL of the Kubernetes API, the in-cluster configuration is used when it's empty.")
+	root.Flags().StringVar(&c.Provisioners.Kubernetes.TokenFile, "kubernetes-token-file", "", "Bearer token file to authenticate in the Kubernetes API.")
+	root.Flags().StringVar(&c.Provisioners.Kubernetes.CAFile, "kubernetes-ca-file", "", "CA bundle to verify the Kubernetes API.")
+	root.Flags().StringVar(&c.Provisioners.Kubernetes.NamespacePrefix, "kubernetes-namespace-prefix", "", "Prefix of the namespaces created for the games, e.g.: kubeplay-.")
+	root.Flags().StringSliceVar(&o.GitHubRoleMappings, "github-role-mapping", nil, "Grant a role to members of a GitHub organization or team, e.g.: kubeplay/hosts=host.")
+	root.Flags().StringVar(&c.GitHub.FakeMembershipsFile, "github-fake-memberships", "", "Path of a YAML file with static GitHub memberships used instead of the GitHub API.")
+	root.Flags().StringVar(&c.Bootstrap.Subject, "bootstrap-subject", "", "Grant the bootstrap role to this subject in every event, e.g.: github|user.")
+	root.Flags().StringVar(&c.Bootstrap.Role, "bootstrap-role", c.Bootstrap.Role, "The role granted to the bootstrap subject.")
+	root.AddCommand(backupCmd(), restoreCmd(), migrateCmd())
+	return root
+}
+
+func backupCmd() *cobra.Command {
+	var out string
+	cmd := &cobra.Command{
+		Use:          "backup --out FILE",
+		Short:        "Write a consistent copy of the database, the server could be running.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if out == "" {
+				return fmt.Errorf("missing the backup file (--out)")
+			}
+			cfg, err := loadStoreConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			f, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+			if err != nil {
+				return err
+			}
+			size, err := store.Backup(cfg.File, f)
+			if err != nil {
+				f.Close()
+				os.Remove(out)
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			logrus.Infof("Backup of %s written to %s (%d bytes)", cfg.File, out, size)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&out, "out", "", "Path of the backup file.")
+	addStoreFlags(cmd)
+	return cmd
+}
+
+func restoreCmd() *cobra.Command {
+	var from string
+	cmd := &cobra.Command{
+		Use:          "restore --from FILE",
+		Short:        "Replace the database with a backup, stop the server first.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if from == "" {
+				return fmt.Errorf("missing the backup file (--from)")
+			}
+			cfg, err := loadStoreConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			if err := store.Restore(cfg.File, cfg.Bucket, from); err != nil {
+				return err
+			}
+			logrus.Infof("Restored %s from %s", cfg.File, from)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&from, "from", "", "Path of the backup file.")
+	addStoreFlags(cmd)
+	return cmd
+}
+
+func migrateCmd() *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Rewrite the objects stored with a previous version with the storage version (" + types.StorageVersion + ").",
+		Long: `Rewrite the objects stored with a previous version with the storage version.
+Objects are converted when they're read, migrating the database avoids converting
+them on every request. The objects are rewritten in a single transaction, the
+requests of a running server wait for it. Take a backup first (gameserver backup).`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadStoreConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+			result, err := store.Migrate(cfg.File, cfg.Bucket, dryRun)
+			if err != nil {
+				return err
+			}
+			var kinds []string
+			for kind := range result.Converted {
+				kinds = append(kinds, kind)
+			}
+			sort.Strings(kinds)
+			converted := 0
+			for _, kind := range kinds {
+				count := result.Converted[kind]
+				logrus.WithField("kind", kind).Infof("%d object(s) converted to %s", count, types.StorageVersion)
+				converted += count
+			}
+			msg := "Migrated"
+			if dryRun {
+				msg = "Dry run, nothing was written:"
+			}
+			logrus.Infof("%s %d of %d object(s) in %s", msg, converted, result.Objects, cfg.File)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Count the objects which would be converted without writing them.")
+	addStoreFlags(cmd)
+	return cmd
+}
+
+// addStoreFlags adds the flags of the database to the commands which don't serve the API
+func addStoreFlags(cmd *cobra.Command) {
+	c := o.config
+	cmd.Flags().StringVarP(&o.ConfigFile, "config", "c", "", "Path of the YAML configuration file, flags override its values.")
+	cmd.Flags().StringVar(&c.Store.File, "db-file", c.Store.File, "Path of the database file.")
+	cmd.Flags().StringVar(&c.Store.Bucket, "db-bucket", c.Store.Bucket, "The bucket of the database where objects are stored.")
+}
+
+// loadStoreConfig reads the database of the config file and the flags
+func loadStoreConfig(flags *pflag.FlagSet) (*config.StoreConfig, error) {
+	cfg := config.Default()
+	if o.ConfigFile != "" {
+		var err error
+		if cfg, err = config.Load(o.ConfigFile); err != nil {
+			return nil, err
+		}
+	}
+	if flags.Changed("db-file") {
+		cfg.Store.File = o.config.Store.File
+	}
+	if flags.Changed("db-bucket") {
+		cfg.Store.Bucket = o.config.Store.Bucket
+	}
+	return &cfg.Store, nil
+}
+
+// loadConfig reads the config file and overrides it with the flags set explicitly
+func loadConfig(flags *pflag.FlagSet) (*config.ServerConfig, error) {
+	cfg := config.Default()
+	if o.ConfigFile != "" {
+		var err error
+		if cfg, err = config.Load(o.ConfigFile); err != nil {
+			return nil, err
+		}
+	}
+	flags.Visit(func(f *pflag.Flag) {
+		switch f.Name {
+		case "listen-addr":
+			cfg.ListenAddr = o.config.ListenAddr
+		case "shutdown-timeout":
+			cfg.ShutdownTimeout = o.config.ShutdownTimeout
+		case "tls-cert-file":
+			cfg.TLS.CertFile = o.config.TLS.CertFile
+		case "tls-key-file":
+			cfg.TLS.KeyFile = o.config.TLS.KeyFile
+		case "tls-self-signed":
+			cfg.TLS.SelfSigned = o.config.TLS.SelfSigned
+		case "tls-client-ca-file":
+			cfg.TLS.ClientCAFile = o.config.TLS.ClientCAFile
+		case "tls-require-client-cert":
+			cfg.TLS.RequireClientCert = o.config.TLS.RequireClientCert
+		case "policies-file":
+			cfg.PoliciesFile = o.config.PoliciesFile
+		case "signing-key":
+			cfg.SigningKeys = o.config.SigningKeys
+		case "db-file":
+			cfg.Store.File = o.config.Store.File
+		case "db-bucket":
+			cfg.Store.Bucket = o.config.Store.Bucket
+		case "audit-log-file":
+			cfg.Audit.File = o.config.Audit.File
+		case "audit-log-max-size":
+			cfg.Audit.MaxSizeMB = o.config.Audit.MaxSizeMB
+		case "audit-log-max-backups":
+			cfg.Audit.MaxBackups = o.config.Audit.MaxBackups
+		case "audit-webhook-url":
+			cfg.Audit.WebhookURL = o.config.Audit.WebhookURL
+		case "audit-policy-file":
+			cfg.Audit.PolicyFile = o.config.Audit.PolicyFile
+		case "provisioner-exec-command":
+			cfg.Provisioners.Exec.Command = o.config.Provisioners.Exec.Command
+		case "provisioner-exec-args":
+			cfg.Provisioners.Exec.Args = o.config.Provisioners.Exec.Args
+		case "provisioner-kubernetes":
+			cfg.Provisioners.Kubernetes.Enabled = o.config.Provisioners.Kubernetes.Enabled
+		case "kubernetes-host":
+			cfg.Provisioners.Kubernetes.Host = o.config.Provisioners.Kubernetes.Host
+		case "kubernetes-token-file":
+			cfg.Provisioners.Kubernetes.TokenFile = o.config.Provisioners.Kubernetes.TokenFile
+		case "kubernetes-ca-file":
+			cfg.Provisioners.Kubernetes.CAFile = o.config.Provisioners.Kubernetes.CAFile
+		case "kubernetes-namespace-prefix":
+			cfg.Provisioners.Kubernetes.NamespacePrefix = o.config.Provisioners.Kubernetes.NamespacePrefix
+		case "github-fake-memberships":
+			cfg.GitHub.FakeMembershipsFile = o.config.GitHub.FakeMembershipsFile
+		case "bootstrap-subject":
+			cfg.Bootstrap.Subject = o.config.Bootstrap.Subject
+		case "bootstrap-role":
+			cfg.Bootstrap.Role = o.config.Bootstrap.Role
+		}
+	})
+	if len(o.GitHubRoleMappings) > 0 {
+		mappings, err := auth.ParseRoleMappings(strings.Join(o.GitHubRoleMappings, ","))
+		if err != nil {
+			return nil, err
+		}
+		cfg.GitHub.RoleMappings = mappings
+	}
+	// Secrets shouldn't be exposed in the process arguments
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		cfg.GitHub.Token = token
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %v", err)
+	}
+	data, err := json.Marshal(cfg.Redacted())
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("Loaded configuration: %s", string(data))
+	return cfg, nil
+}
+
+func serve(cfg *config.ServerConfig) error {
+	handlers.SetDatabase(cfg.Store.File, cfg.Store.Bucket)
+	if strings.HasPrefix(cfg.Store.File, os.TempDir()) {
+		logrus.WithField("file", cfg.Store.File).Warn("The database is in a temporary directory, it may be lost on reboot. Set --db-file or take backups (gameserver backup)")
+	}
+	handlers.SetPoliciesFile(cfg.PoliciesFile)
+
+	prometheus.MustRegister(handlers.NewGameCollector())
+	if cfg.Audit.Enabled() {
+		auditor, err := newAuditor(cfg.Audit)
+		if err != nil {
+			return err
+		}
+		defer auditor.Close()
+		api.Config.Auditor = auditor
+	}
+	if err := registerProvisioners(cfg.Provisioners); err != nil {
+		return err
+	}
+
 	muxr := mux.NewRouter()
-	root := muxr.PathPrefix("/v1").Subrouter()
+	for _, p := range api.Config.Probes() {
+		muxr.HandleFunc(p.Path, p.Handler).Methods(p.Methods...)
+	}
+	for _, d := range api.Config.Discovery() {
+		muxr.HandleFunc(d.Path, d.Handler).Methods(d.Methods...)
+	}
+	root := muxr.PathPrefix(api.Config.Version).Subrouter()
 	for _, r := range api.Config.Routes() {
 		if r.PathPrefix == "" {
 			for _, sr := range r.SubRoutes {
@@ -46,14 +370,176 @@ func main() {
 		return nil
 	})
 
-	err = auth.NewUserPolicy(api.UserPoliciesFile, "github|sandromello", false)
+	// The first key signs new tokens, the remaining ones are kept to verify
+	// tokens issued before rotating the keys
+	signingKeys, err := auth.LoadKeySet(cfg.SigningKeys...)
 	if err != nil {
-		log.Fatalf(err.Error())
+		return err
+	}
+	handlers.SetSigningKeys(signingKeys)
+	logrus.WithField("kid", signingKeys.Signer().ID).Info("Loaded signing keys")
+
+	var membership auth.GitHubMembership = auth.NewGitHubMembership(cfg.GitHub.Token)
+	if cfg.GitHub.FakeMembershipsFile != "" {
+		membership, err = auth.LoadFakeGitHubMembership(cfg.GitHub.FakeMembershipsFile)
+		if err != nil {
+			return err
+		}
+	}
+	handlers.SetGitHubRoleMappings(membership, cfg.GitHub.RoleMappings)
+
+	if cfg.Bootstrap.Subject != "" {
+		err = handlers.BootstrapRoleBinding("bootstrap", cfg.Bootstrap.Subject, cfg.Bootstrap.Role)
+		if err != nil {
+			return err
+		}
+	}
+	if err := handlers.SyncPolicies(); err != nil {
+		return err
+	}
+	// the background jobs finish their current run before the server stops
+	stopJobs := make(chan struct{})
+	var jobs sync.WaitGroup
+	jobs.Add(2)
+	go func() {
+		defer jobs.Done()
+		handlers.PruneExpiredTokensEvery(time.Hour, stopJobs)
+	}()
+	go func() {
+		defer jobs.Done()
+		handlers.ExpireGamesEvery(time.Minute, stopJobs)
+	}()
+	defer jobs.Wait()
+	defer close(stopJobs)
+	// the other versions are served by the routes of the hub
+	logrus.WithField("versions", strings.Join(api.ServedPaths(), ",")).Info("Serving API versions")
+	srv := &http.Server{Addr: cfg.ListenAddr, Handler: api.Config.VersionHandler(muxr)}
+	if cfg.TLS.Enabled() {
+		srv.TLSConfig, err = newTLSConfig(cfg)
+		if err != nil {
+			return err
+		}
+	}
+	// the address is bound before reporting ready, a port already in use fails
+	// the startup instead of a ready server refusing connections
+	ln, err := net.Listen("tcp", cfg.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("failed listening to %s: %v", cfg.ListenAddr, err)
+	}
+	serve := func() error { return srv.Serve(ln) }
+	if srv.TLSConfig != nil {
+		// the certificate is served by the reloader
+		serve = func() error { return srv.ServeTLS(ln, "", "") }
+		logrus.Infof("Listening to %s (TLS) ...", ln.Addr())
+	} else {
+		logrus.Warn("Serving the API over plain HTTP, credentials could leak in an insecure network")
+		logrus.Infof("Listening to %s ...", ln.Addr())
+	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- serve() }()
+	handlers.SetReady(true)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	select {
+	case err := <-serveErr:
+		return err
+	case sig := <-sigs:
+		logrus.Infof("Received %v, draining requests for up to %v ...", sig, cfg.ShutdownTimeout)
+	}
+	// Fail the readiness probe while draining, new connections are refused
+	// and the in-flight requests (e.g.: solving games) are able to finish
+	handlers.SetReady(false)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed draining requests: %v", err)
 	}
+	logrus.Info("Server stopped gracefully")
+	return nil
+}
 
+func newAuditor(cfg config.AuditConfig) (*audit.Auditor, error) {
+	policy := audit.DefaultPolicy()
+	if cfg.PolicyFile != "" {
+		var err error
+		if policy, err = audit.LoadPolicy(cfg.PolicyFile); err != nil {
+			return nil, err
+		}
+	}
+	var sinks []audit.Sink
+	if cfg.File != "" {
+		sink, err := audit.NewFileSink(cfg.File, cfg.MaxSizeMB, cfg.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if cfg.WebhookURL != "" {
+		sinks = append(sinks, audit.NewWebhookSink(cfg.WebhookURL))
+	}
+	logrus.WithField("level", policy.Level).Info("Recording audit events")
+	return audit.New(policy, sinks...), nil
+}
+
+func registerProvisioners(cfg config.ProvisionersConfig) error {
+	if cfg.Exec.Command != "" {
+		p, err := provisioner.NewExec(cfg.Exec.Command, cfg.Exec.Args...)
+		if err != nil {
+			return fmt.Errorf("failed registering the exec provisioner: %v", err)
+		}
+		provisioner.Register("exec", p)
+		logrus.WithField("command", cfg.Exec.Command).Info("Registered the exec provisioner")
+	}
+	if k := cfg.Kubernetes; k.Enabled {
+		p, err := provisioner.NewKubernetes(provisioner.KubernetesConfig{
+			Host:            k.Host,
+			TokenFile:       k.TokenFile,
+			CAFile:          k.CAFile,
+			NamespacePrefix: k.NamespacePrefix,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed registering the kubernetes provisioner: %v", err)
+		}
+		provisioner.Register("kubernetes", p)
+		logrus.Info("Registered the kubernetes provisioner")
+	}
+	return nil
+}
+
+func newTLSConfig(cfg *config.ServerConfig) (*tls.Config, error) {
+	if cfg.TLS.SelfSigned {
+		if _, err := os.Stat(cfg.TLS.CertFile); os.IsNotExist(err) {
+			host, _, _ := net.SplitHostPort(cfg.ListenAddr)
+			if err := auth.GenerateSelfSignedCertificate(cfg.TLS.CertFile, cfg.TLS.KeyFile, host); err != nil {
+				return nil, fmt.Errorf("failed generating self-signed certificate: %v", err)
+			}
+			logrus.WithField("cert", cfg.TLS.CertFile).Warn("Generated a self-signed certificate, don't use it in production")
+		}
+	}
+	reloader, err := auth.NewCertificateReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile)
 	if err != nil {
-		log.Fatalf(err.Error())
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if cfg.TLS.ClientCAFile != "" {
+		tlsConfig.ClientCAs, err = auth.LoadCertPool(cfg.TLS.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		if cfg.TLS.RequireClientCert {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return tlsConfig, nil
+}
+
+func main() {
+	if err := cmd().Execute(); err != nil {
+		os.Exit(1)
 	}
-	logrus.Info("Listening to 0.0.0.0:8080 ...")
-	http.ListenAndServe("0.0.0.0:8080", muxr)
 }
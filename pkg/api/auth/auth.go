@@ -1,67 +1,150 @@
 package auth
 
 import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+
 	"github.com/casbin/casbin"
 	"github.com/casbin/casbin/persist/file-adapter"
+	"github.com/kubeplay/gameserver/pkg/types"
 )
 
 const (
+	DefaultPoliciesFile = "/tmp/user-policies.csv"
+
+	// HostRole and GuestRole are the built-in roles
+	HostRole  = "host"
+	GuestRole = "guest"
+
+	// AllDomains is the domain of policies and role bindings not scoped to an event
+	AllDomains = "*"
+
 	casbinModel = `
 [request_definition]
-r = sub, obj, act
+r = sub, dom, obj, act
 
 [policy_definition]
-p = sub, obj, act
+p = sub, dom, obj, act
+
+[role_definition]
+g = _, _, _
 
 [policy_effect]
 e = some(where (p.eft == allow))
 
 [matchers]
-m = r.sub == p.sub && keyMatch2(r.obj, p.obj) && regexMatch(r.act, p.act)
+m = (g(r.sub, p.sub, r.dom) || g(r.sub, p.sub, "*")) && (p.dom == "*" || p.dom == r.dom) && keyMatch2(r.obj, p.obj) && regexMatch(r.act, p.act)
 `
 )
 
 var (
-	guestPerms = []interface{}{
-		[]string{"/v1/policies", "GET"},
-		[]string{"/v1/events", "GET"},
-		[]string{"/v1/events:resourceName", "GET"},
-		[]string{"/v1/events/:parent/games", "(GET)|(POST)"},
-		[]string{"/v1/events/:parent/games/:resourceName", "GET"},
-		[]string{"/v1/events/:parent/games/:resourceName/solve", "POST"},
+	eventPathRe = regexp.MustCompile(`^/v1/events/([^/]+)`)
+
+	guestRules = []types.PolicyRule{
+		{Object: "/v1/policies", Actions: "GET"},
+		{Object: "/v1/events", Actions: "GET"},
+		{Object: "/v1/events/:resourceName", Actions: "GET"},
+		{Object: "/v1/events/:parent/games", Actions: "(GET)|(POST)"},
+		{Object: "/v1/events/:parent/games/:resourceName", Actions: "GET"},
+		{Object: "/v1/events/:parent/games/:resourceName/solve", Actions: "POST"},
+		{Object: "/v1/games", Actions: "GET"},
+		{Object: "/v1/challenges/:resourceName/bundle", Actions: "GET"},
+		{Object: "/v1/assets/:resourceName", Actions: "GET"},
+		{Object: "/v1/logout", Actions: "POST"},
+		{Object: "/v1/selfsubjectaccessreviews", Actions: "POST"},
 	}
-	hostPerms = []interface{}{
-		[]string{"/v1/policies", "(GET)|(POST)"},
-		[]string{"/v1/challenges", "(GET)|(POST)"},
-		[]string{"/v1/policies/:resourceName,", "(GET)|(DELETE)|(PUT)"},
-		[]string{"/v1/challenges", "(GET)|(POST)"},
-		[]string{"/v1/challenges/:resourceName", "(GET)|(PUT)|(DELETE)"},
-		[]string{"/v1/events", "(GET)|(POST)"},
-		[]string{"/v1/events:resourceName", "(GET)|(PUT)|(DELETE)"},
-		[]string{"/v1/events/:parent/games", "(GET)|(POST)"},
-		[]string{"/v1/events/:parent/games/:resourceName", "(GET)|(DELETE)"},
-		[]string{"/v1/events/:parent/games/:resourceName/solve", "POST"},
-		[]string{"/v1/events/:parent/games/:resourceName/start", "POST"},
+	hostRules = []types.PolicyRule{
+		{Object: "/v1/policies", Actions: "(GET)|(POST)"},
+		{Object: "/v1/policies/:resourceName", Actions: "(GET)|(DELETE)"},
+		{Object: "/v1/roles", Actions: "(GET)|(POST)"},
+		{Object: "/v1/roles/:resourceName", Actions: "(GET)|(DELETE)|(PUT)"},
+		{Object: "/v1/rolebindings", Actions: "(GET)|(POST)"},
+		{Object: "/v1/rolebindings/:resourceName", Actions: "(GET)|(DELETE)|(PUT)"},
+		{Object: "/v1/challenges", Actions: "(GET)|(POST)"},
+		{Object: "/v1/challenges/:resourceName", Actions: "(GET)|(PUT)|(DELETE)"},
+		{Object: "/v1/challenges/:resourceName/bundle", Actions: "(GET)|(PUT)"},
+		{Object: "/v1/challenges/:parent/revisions", Actions: "GET"},
+		{Object: "/v1/challenges/:parent/revisions/:resourceName", Actions: "GET"},
+		{Object: "/v1/assets/:resourceName", Actions: "GET"},
+		{Object: "/v1/events", Actions: "(GET)|(POST)"},
+		{Object: "/v1/events/:resourceName", Actions: "(GET)|(DELETE)"},
+		{Object: "/v1/events/:parent/games", Actions: "(GET)|(POST)"},
+		{Object: "/v1/events/:parent/games/:resourceName", Actions: "(GET)|(DELETE)"},
+		{Object: "/v1/events/:parent/games/:resourceName/solve", Actions: "POST"},
+		{Object: "/v1/events/:parent/games/:resourceName/start", Actions: "POST"},
+		{Object: "/v1/games", Actions: "GET"},
+		{Object: "/v1/eventhooks", Actions: "(GET)|(POST)"},
+		{Object: "/v1/eventhooks/:resourceName", Actions: "(GET)|(PUT)|(DELETE)"},
+		{Object: "/v1/users", Actions: "(GET)|(POST)"},
+		{Object: "/v1/users/:resourceName", Actions: "(GET)|(PUT)|(DELETE)"},
+		{Object: "/v1/sessions", Actions: "GET"},
+		{Object: "/v1/sessions/:resourceName", Actions: "(GET)|(DELETE)"},
+		{Object: "/v1/logout", Actions: "POST"},
+		{Object: "/v1/selfsubjectaccessreviews", Actions: "POST"},
+		{Object: "/v1/subjectaccessreviews", Actions: "POST"},
+		{Object: "/v1/export", Actions: "GET"},
+		{Object: "/v1/import", Actions: "POST"},
+	}
+
+	// BuiltinRoles are always present and couldn't be overridden
+	BuiltinRoles = map[string][]types.PolicyRule{
+		HostRole:  hostRules,
+		GuestRole: guestRules,
 	}
 )
 
-func NewUserPolicy(policyPath, username string, isHost bool) error {
-	e, err := casbin.NewEnforcerSafe(
-		casbin.NewModel(casbinModel),
-		fileadapter.NewAdapter(policyPath),
-	)
+// RoleSubject is the casbin subject of a role
+func RoleSubject(role string) string {
+	return fmt.Sprintf("role:%s", role)
+}
+
+// Domain returns the event of the request path, requests which aren't
+// scoped to an event belongs to all domains.
+func Domain(path string) string {
+	if m := eventPathRe.FindStringSubmatch(path); m != nil {
+		return m[1]
+	}
+	return AllDomains
+}
+
+// SyncPolicies rewrites the policy file with the built-in roles, the custom roles,
+// the role bindings and the policies of individual subjects.
+func SyncPolicies(policyPath string, roles []types.Role, bindings []types.RoleBinding, policies []types.Policy) error {
+	// The policy file is derived from the store, start from a clean file
+	// to avoid loading rules from an old model.
+	if err := ioutil.WriteFile(policyPath, nil, 0600); err != nil {
+		return err
+	}
+	e, err := NewEnforcer(policyPath)
 	if err != nil {
 		return err
 	}
-	if isHost {
-		for _, perms := range hostPerms {
-			newPerms := append([]string{username}, perms.([]string)...)
-			e.AddPolicySafe([]interface{}{newPerms}...)
+	for name, rules := range BuiltinRoles {
+		for _, rule := range rules {
+			e.AddPolicy(RoleSubject(name), AllDomains, rule.Object, rule.Actions)
+		}
+	}
+	for _, role := range roles {
+		if _, builtin := BuiltinRoles[role.Name]; builtin {
+			continue
+		}
+		for _, rule := range role.Rules {
+			e.AddPolicy(RoleSubject(role.Name), AllDomains, rule.Object, rule.Actions)
+		}
+	}
+	for _, rb := range bindings {
+		domain := rb.Event
+		if domain == "" {
+			domain = AllDomains
+		}
+		for _, subject := range rb.Subjects {
+			e.AddGroupingPolicy(subject, RoleSubject(rb.Role), domain)
 		}
-	} else {
-		for _, perms := range guestPerms {
-			newPerms := append([]string{username}, perms.([]string)...)
-			e.AddPolicySafe([]interface{}{newPerms}...)
+	}
+	for _, p := range policies {
+		for _, rule := range p.Rules {
+			e.AddPolicy(p.Subject, AllDomains, rule.Object, rule.Actions)
 		}
 	}
 	return e.SavePolicy()
@@ -73,3 +156,25 @@ func NewEnforcer(policyPath string) (*casbin.Enforcer, error) {
 		fileadapter.NewAdapter(policyPath),
 	)
 }
+
+// Review evaluates if the subject or any of the roles granted to the subject
+// outside of role bindings (e.g.: GitHub teams) is allowed to perform the verb in the path.
+// Every authenticated subject has the guest role.
+func Review(policyPath, subject, path, verb string, roles ...string) (bool, error) {
+	e, err := NewEnforcer(policyPath)
+	if err != nil {
+		return false, err
+	}
+	domain := Domain(path)
+	allowed, err := e.EnforceSafe(subject, domain, path, verb)
+	if err != nil || allowed {
+		return allowed, err
+	}
+	for _, role := range append(append([]string{}, roles...), GuestRole) {
+		allowed, err := e.EnforceSafe(RoleSubject(role), domain, path, verb)
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+	return false, nil
+}
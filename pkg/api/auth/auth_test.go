@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+func TestDomain(t *testing.T) {
+	for path, want := range map[string]string{
+		"/v1/events":                     AllDomains,
+		"/v1/events/meetup":              "meetup",
+		"/v1/events/meetup/games/g1":     "meetup",
+		"/v1/challenges/foo":             AllDomains,
+		"/v1/selfsubjectaccessreviews":   AllDomains,
+		"/v1/events/meetup-2/games/g1/x": "meetup-2",
+	} {
+		if got := Domain(path); got != want {
+			t.Errorf("%s: expected the domain %q, got %q", path, want, got)
+		}
+	}
+}
+
+func TestReview(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeplay-policies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "policies.csv")
+	roles := []types.Role{
+		{
+			Metadata: types.Metadata{Name: "judge"},
+			Rules:    []types.PolicyRule{{Object: "/v1/events/:parent/games/:resourceName/start", Actions: "POST"}},
+		},
+		// the built-in roles couldn't be overridden
+		{
+			Metadata: types.Metadata{Name: GuestRole},
+			Rules:    []types.PolicyRule{{Object: "/v1/challenges", Actions: "POST"}},
+		},
+	}
+	bindings := []types.RoleBinding{
+		{Role: HostRole, Subjects: []string{"local|admin"}},
+		{Role: HostRole, Subjects: []string{"local|alice"}, Event: "meetup"},
+		{Role: "judge", Subjects: []string{"local|bob"}, Event: "meetup"},
+	}
+	policies := []types.Policy{
+		{Subject: "local|carol", Rules: []types.PolicyRule{{Object: "/v1/challenges", Actions: "(GET)|(POST)"}}},
+	}
+	if err := SyncPolicies(file, roles, bindings, policies); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		subject string
+		roles   []string
+		verb    string
+		path    string
+		want    bool
+	}{
+		// every subject is a guest
+		{subject: "local|nobody", verb: "GET", path: "/v1/events", want: true},
+		{subject: "local|nobody", verb: "GET", path: "/v1/events/meetup/games/g1", want: true},
+		{subject: "local|nobody", verb: "POST", path: "/v1/events", want: false},
+		{subject: "local|nobody", verb: "POST", path: "/v1/challenges", want: false},
+		// bindings in every event
+		{subject: "local|admin", verb: "POST", path: "/v1/challenges", want: true},
+		{subject: "local|admin", verb: "DELETE", path: "/v1/events/other/games/g1", want: true},
+		// bindings scoped to an event
+		{subject: "local|alice", verb: "DELETE", path: "/v1/events/meetup/games/g1", want: true},
+		{subject: "local|alice", verb: "DELETE", path: "/v1/events/other/games/g1", want: false},
+		{subject: "local|alice", verb: "POST", path: "/v1/challenges", want: false},
+		// custom roles
+		{subject: "local|bob", verb: "POST", path: "/v1/events/meetup/games/g1/start", want: true},
+		{subject: "local|bob", verb: "POST", path: "/v1/events/other/games/g1/start", want: false},
+		// policies of individual subjects
+		{subject: "local|carol", verb: "POST", path: "/v1/challenges", want: true},
+		{subject: "local|carol", verb: "DELETE", path: "/v1/challenges/foo", want: false},
+		// roles granted outside of the bindings
+		{subject: "github|dave", roles: []string{HostRole}, verb: "POST", path: "/v1/challenges", want: true},
+		{subject: "github|dave", roles: []string{"judge"}, verb: "POST", path: "/v1/events/other/games/g1/start", want: true},
+		{subject: "github|dave", roles: []string{"unknown"}, verb: "POST", path: "/v1/challenges", want: false},
+	} {
+		got, err := Review(file, tc.subject, tc.path, tc.verb, tc.roles...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%s %v: expected allowed=%v to %s %s, got %v", tc.subject, tc.roles, tc.want, tc.verb, tc.path, got)
+		}
+	}
+}
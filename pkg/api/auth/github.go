@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const gitHubAPIURL = "https://api.github.com"
+
+// GitHubMembership verifies if GitHub users belong to organizations or teams
+type GitHubMembership interface {
+	// IsMember verifies if the user belongs to the group, the group
+	// is an organization (org) or a team of an organization (org/team).
+	IsMember(login, group string) (bool, error)
+}
+
+// RoleMapping grants a role to the members of a GitHub organization or team
+type RoleMapping struct {
+	Group string `json:"group" yaml:"group"`
+	Role  string `json:"role" yaml:"role"`
+}
+
+// ParseRoleMappings decodes a list of mappings in the format: org/team=role,org=role
+func ParseRoleMappings(mappings string) ([]RoleMapping, error) {
+	var result []RoleMapping
+	for _, m := range strings.Split(mappings, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		parts := strings.SplitN(m, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("wrong role mapping %q, expected <org>[/<team>]=<role>", m)
+		}
+		result = append(result, RoleMapping{Group: parts[0], Role: parts[1]})
+	}
+	return result, nil
+}
+
+// ResolveRoles returns the roles granted to the user by its memberships
+func ResolveRoles(m GitHubMembership, mappings []RoleMapping, login string) ([]string, error) {
+	var roles []string
+	for _, mapping := range mappings {
+		if hasRole(roles, mapping.Role) {
+			continue
+		}
+		isMember, err := m.IsMember(login, mapping.Group)
+		if err != nil {
+			return nil, err
+		}
+		if isMember {
+			roles = append(roles, mapping.Role)
+		}
+	}
+	return roles, nil
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type gitHubAPIMembership struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewGitHubMembership verifies memberships using the GitHub API, the token must have
+// the read:org scope to verify private memberships and teams.
+func NewGitHubMembership(token string) GitHubMembership {
+	return &gitHubAPIMembership{
+		baseURL: gitHubAPIURL,
+		token:   token,
+		client:  http.DefaultClient,
+	}
+}
+
+func (g *gitHubAPIMembership) IsMember(login, group string) (bool, error) {
+	parts := strings.SplitN(group, "/", 2)
+	if len(parts) == 1 {
+		// 204 for members, it redirects to the public members when the
+		// token doesn't belong to a member of the organization
+		resp, err := g.get(fmt.Sprintf("/orgs/%s/members/%s", parts[0], login))
+		if err != nil {
+			return false, err
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode == http.StatusNoContent, nil
+	}
+	resp, err := g.get(fmt.Sprintf("/orgs/%s/teams/%s/memberships/%s", parts[0], parts[1], login))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, nil
+	}
+	var membership struct {
+		State string `json:"state"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&membership); err != nil {
+		return false, err
+	}
+	return membership.State == "active", nil
+}
+
+func (g *gitHubAPIMembership) get(path string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", g.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if g.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("token %s", g.token))
+	}
+	return g.client.Do(req)
+}
+
+// FakeGitHubMembership maps GitHub users to their organizations and teams,
+// it's useful for events without access to the GitHub API or for testing.
+type FakeGitHubMembership map[string][]string
+
+// LoadFakeGitHubMembership reads the memberships from a YAML file, e.g.:
+//
+//	sandromello: [kubeplay, kubeplay/hosts]
+func LoadFakeGitHubMembership(file string) (FakeGitHubMembership, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	m := FakeGitHubMembership{}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed decoding memberships: %v", err)
+	}
+	return m, nil
+}
+
+func (f FakeGitHubMembership) IsMember(login, group string) (bool, error) {
+	for _, g := range f[login] {
+		if g == group {
+			return true, nil
+		}
+	}
+	return false, nil
+}
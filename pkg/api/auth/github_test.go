@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRoleMappings(t *testing.T) {
+	for _, tc := range []struct {
+		mappings string
+		want     []RoleMapping
+		wantErr  bool
+	}{
+		{mappings: "", want: nil},
+		{mappings: "kubeplay=guest", want: []RoleMapping{{Group: "kubeplay", Role: "guest"}}},
+		{
+			mappings: "kubeplay/hosts=host, kubeplay=judge,",
+			want:     []RoleMapping{{Group: "kubeplay/hosts", Role: "host"}, {Group: "kubeplay", Role: "judge"}},
+		},
+		{mappings: "kubeplay", wantErr: true},
+		{mappings: "kubeplay=", wantErr: true},
+		{mappings: "=host", wantErr: true},
+	} {
+		got, err := ParseRoleMappings(tc.mappings)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("%q: unexpected error: %v", tc.mappings, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: expected %+v, got %+v", tc.mappings, tc.want, got)
+		}
+	}
+}
+
+type failingMembership struct{}
+
+func (failingMembership) IsMember(login, group string) (bool, error) {
+	return false, fmt.Errorf("rate limited")
+}
+
+func TestResolveRoles(t *testing.T) {
+	m := FakeGitHubMembership{
+		"alice": {"kubeplay", "kubeplay/hosts"},
+		"bob":   {"kubeplay"},
+	}
+	mappings := []RoleMapping{
+		{Group: "kubeplay/hosts", Role: HostRole},
+		{Group: "kubeplay", Role: "judge"},
+		{Group: "other", Role: HostRole},
+	}
+	for login, want := range map[string][]string{
+		"alice":    {HostRole, "judge"},
+		"bob":      {"judge"},
+		"nobody":   nil,
+		"kubeplay": nil,
+	} {
+		got, err := ResolveRoles(m, mappings, login)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected the roles %v, got %v", login, want, got)
+		}
+	}
+	if _, err := ResolveRoles(failingMembership{}, mappings, "alice"); err == nil {
+		t.Error("expected the error of the membership")
+	}
+}
+
+func TestGitHubAPIMembership(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/orgs/kubeplay/members/alice":
+			w.WriteHeader(http.StatusNoContent)
+		case "/orgs/kubeplay/members/bob":
+			// not a member, or the token doesn't belong to a member
+			w.WriteHeader(http.StatusNotFound)
+		case "/orgs/kubeplay/teams/hosts/memberships/alice":
+			fmt.Fprint(w, `{"state": "active"}`)
+		case "/orgs/kubeplay/teams/hosts/memberships/bob":
+			fmt.Fprint(w, `{"state": "pending"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	m := &gitHubAPIMembership{baseURL: srv.URL, token: "secret", client: srv.Client()}
+
+	for _, tc := range []struct {
+		login string
+		group string
+		want  bool
+	}{
+		{login: "alice", group: "kubeplay", want: true},
+		{login: "bob", group: "kubeplay", want: false},
+		{login: "alice", group: "kubeplay/hosts", want: true},
+		{login: "bob", group: "kubeplay/hosts", want: false},
+		{login: "carol", group: "kubeplay/hosts", want: false},
+	} {
+		got, err := m.IsMember(tc.login, tc.group)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%s in %s: expected %v, got %v", tc.login, tc.group, tc.want, got)
+		}
+	}
+}
+
+func TestLoadFakeGitHubMembership(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeplay-memberships")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "memberships.yaml")
+	if err := ioutil.WriteFile(file, []byte("alice: [kubeplay, kubeplay/hosts]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadFakeGitHubMembership(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := m.IsMember("alice", "kubeplay/hosts"); !ok {
+		t.Error("expected alice to be a member of kubeplay/hosts")
+	}
+	if ok, _ := m.IsMember("alice", "kubeplay/judges"); ok {
+		t.Error("expected alice not to be a member of kubeplay/judges")
+	}
+
+	if err := ioutil.WriteFile(file, []byte("alice: kubeplay: hosts"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFakeGitHubMembership(file); err == nil || !strings.Contains(err.Error(), "failed decoding memberships") {
+		t.Errorf("expected a decoding error, got %v", err)
+	}
+}
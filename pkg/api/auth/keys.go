@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// SigningKey is a private key used to sign and verify player tokens
+type SigningKey struct {
+	// ID is the key identifier published in the "kid" header of the tokens
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+}
+
+// Public returns the public part of the signing key
+func (k *SigningKey) Public() crypto.PublicKey {
+	return k.Private.Public()
+}
+
+// KeySet holds all the active signing keys. The first key signs new tokens,
+// the others are only used for verifying tokens allowing keys to be rotated.
+type KeySet struct {
+	keys []*SigningKey
+}
+
+// JSONWebKey is the public representation of a signing key (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// ECDSA
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// LoadKeySet reads PEM encoded RSA or ECDSA private keys from files
+func LoadKeySet(files ...string) (*KeySet, error) {
+	ks := &KeySet{}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ParseSigningKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing key %q: %v", file, err)
+		}
+		if ks.Lookup(key.ID) != nil {
+			return nil, fmt.Errorf("duplicated key %q", file)
+		}
+		ks.keys = append(ks.keys, key)
+	}
+	if len(ks.keys) == 0 {
+		return nil, fmt.Errorf("missing signing keys")
+	}
+	return ks, nil
+}
+
+// ParseSigningKey decodes a PEM encoded private key (PKCS1, PKCS8 or SEC1)
+func ParseSigningKey(data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("it's not a PEM encoded key")
+	}
+	var priv interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		priv, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		priv, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		priv, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	key := &SigningKey{}
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		key.Method = jwt.SigningMethodRS256
+		key.Private = k
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			key.Method = jwt.SigningMethodES256
+		case elliptic.P384():
+			key.Method = jwt.SigningMethodES384
+		case elliptic.P521():
+			key.Method = jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("unsupported elliptic curve")
+		}
+		key.Private = k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", priv)
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(der)
+	key.ID = hex.EncodeToString(hash[:8])
+	return key, nil
+}
+
+// Signer returns the key used to sign new tokens
+func (ks *KeySet) Signer() *SigningKey {
+	return ks.keys[0]
+}
+
+// Lookup finds a key by its ID, returns nil if it doesn't exist
+func (ks *KeySet) Lookup(kid string) *SigningKey {
+	for _, k := range ks.keys {
+		if k.ID == kid {
+			return k
+		}
+	}
+	return nil
+}
+
+// JWKS returns the public keys of the set
+func (ks *KeySet) JWKS() *JSONWebKeySet {
+	set := &JSONWebKeySet{}
+	for _, k := range ks.keys {
+		jwk := JSONWebKey{
+			Use: "sig",
+			Kid: k.ID,
+			Alg: k.Method.Alg(),
+		}
+		switch pub := k.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = encodeBase64URL(pub.N.Bytes())
+			jwk.E = encodeBase64URL(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = pub.Curve.Params().Name
+			jwk.X = encodeBase64URL(padBytes(pub.X.Bytes(), size))
+			jwk.Y = encodeBase64URL(padBytes(pub.Y.Bytes(), size))
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func encodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// padBytes left pads the coordinates of an elliptic curve point
+func padBytes(data []byte, size int) []byte {
+	if len(data) >= size {
+		return data
+	}
+	return append(make([]byte, size-len(data)), data...)
+}
@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func encodePEM(blockType string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+func pkcs8(t *testing.T, priv interface{}) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encodePEM("PRIVATE KEY", der)
+}
+
+// keyID derives the kid of a public key, the first bytes of the hash of its DER encoding
+func keyID(t *testing.T, pub crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(der)
+	return hex.EncodeToString(hash[:8])
+}
+
+func TestParseSigningKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p224, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKID := keyID(t, rsaKey.Public())
+	ecKID := keyID(t, ecKey.Public())
+	for _, tc := range []struct {
+		name    string
+		data    []byte
+		wantAlg string
+		wantKID string
+		wantErr string
+	}{
+		{
+			name:    "RSA PKCS1",
+			data:    encodePEM("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)),
+			wantAlg: "RS256",
+			wantKID: rsaKID,
+		},
+		{
+			name:    "RSA PKCS8",
+			data:    pkcs8(t, rsaKey),
+			wantAlg: "RS256",
+			wantKID: rsaKID,
+		},
+		{
+			name:    "ECDSA SEC1",
+			data:    encodePEM("EC PRIVATE KEY", ecDER),
+			wantAlg: "ES256",
+			wantKID: ecKID,
+		},
+		{
+			name:    "ECDSA PKCS8",
+			data:    pkcs8(t, ecKey),
+			wantAlg: "ES256",
+			wantKID: ecKID,
+		},
+		{
+			name:    "ECDSA P-384",
+			data:    pkcs8(t, p384),
+			wantAlg: "ES384",
+			wantKID: keyID(t, p384.Public()),
+		},
+		{
+			name:    "unsupported curve",
+			data:    pkcs8(t, p224),
+			wantErr: "unsupported elliptic curve",
+		},
+		{
+			name:    "not PEM encoded",
+			data:    []byte("secret"),
+			wantErr: "not a PEM encoded key",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			key, err := ParseSigningKey(tc.data)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key.Method.Alg() != tc.wantAlg {
+				t.Errorf("expected the algorithm %s, got %s", tc.wantAlg, key.Method.Alg())
+			}
+			// the kid only depends on the public key, not on the encoding
+			if key.ID != tc.wantKID {
+				t.Errorf("expected the kid %s, got %s", tc.wantKID, key.ID)
+			}
+		})
+	}
+}
+
+func writeKeys(t *testing.T, dir string, keys ...[]byte) []string {
+	var files []string
+	for i, data := range keys {
+		file := filepath.Join(dir, string(rune('a'+i))+".pem")
+		if err := ioutil.WriteFile(file, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+func TestLoadKeySetAndJWKS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeplay-keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := writeKeys(t, dir, pkcs8(t, ecKey), pkcs8(t, rsaKey))
+
+	ks, err := LoadKeySet(files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first key signs new tokens, the others only verify them
+	ecKID, rsaKID := keyID(t, ecKey.Public()), keyID(t, rsaKey.Public())
+	if ks.Signer().ID != ecKID {
+		t.Errorf("expected the first key to sign tokens, got %s", ks.Signer().ID)
+	}
+	if ks.Lookup(rsaKID) == nil || ks.Lookup("missing") != nil {
+		t.Error("expected only the keys of the set to be found")
+	}
+
+	jwks := ks.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(jwks.Keys))
+	}
+	ec := jwks.Keys[0]
+	if ec.Kty != "EC" || ec.Crv != "P-256" || ec.Alg != "ES256" || ec.Use != "sig" || ec.Kid != ecKID {
+		t.Errorf("unexpected EC key %+v", ec)
+	}
+	for name, coord := range map[string]string{"x": ec.X, "y": ec.Y} {
+		data, err := base64.RawURLEncoding.DecodeString(coord)
+		if err != nil || len(data) != 32 {
+			t.Errorf("expected the %s coordinate padded to 32 bytes, got %d: %v", name, len(data), err)
+		}
+	}
+	r := jwks.Keys[1]
+	if r.Kty != "RSA" || r.Alg != "RS256" || r.Kid != rsaKID || r.Crv != "" {
+		t.Errorf("unexpected RSA key %+v", r)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(r.N)
+	if err != nil || new(big.Int).SetBytes(n).Cmp(rsaKey.N) != 0 {
+		t.Errorf("expected the modulus of the key: %v", err)
+	}
+	if r.E != "AQAB" {
+		t.Errorf("expected the exponent AQAB, got %s", r.E)
+	}
+
+	if _, err := LoadKeySet(writeKeys(t, dir, pkcs8(t, ecKey), pkcs8(t, ecKey))...); err == nil || !strings.Contains(err.Error(), "duplicated key") {
+		t.Errorf("expected an error for duplicated keys, got %v", err)
+	}
+	if _, err := LoadKeySet(); err == nil {
+		t.Error("expected an error without keys")
+	}
+}
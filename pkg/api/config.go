@@ -5,14 +5,16 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/kubeplay/gameserver/pkg/api/handlers"
+	"github.com/kubeplay/gameserver/pkg/audit"
 	"github.com/kubeplay/gameserver/pkg/types"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-const UserPoliciesFile = "/tmp/user-policies.csv"
-
 type config struct {
 	RegisteredAPITypes []types.Object
 	Version            string
+	// Auditor records the mutating requests, nothing is recorded when it's nil
+	Auditor *audit.Auditor
 }
 
 type Route struct {
@@ -41,7 +43,7 @@ func (c *config) Routes() []RouteInfo {
 				{
 					Path:    "/{resourceName}",
 					Handler: handlers.Event.Handler(),
-					Methods: []string{"GET", "DELETE", "PUT"},
+					Methods: []string{"GET", "DELETE"},
 				},
 				{
 					Path:    "/{parent}/games",
@@ -51,7 +53,7 @@ func (c *config) Routes() []RouteInfo {
 				{
 					Path:    "/{parent}/games/{resourceName}",
 					Handler: handlers.Event.HandlerGame(),
-					Methods: []string{"GET", "DELETE", "PUT"},
+					Methods: []string{"GET", "DELETE"},
 				},
 				{
 					Path:    "/{parent}/games/{resourceName}/start",
@@ -65,6 +67,17 @@ func (c *config) Routes() []RouteInfo {
 				},
 			},
 		},
+		{
+			PathPrefix:  "/games",
+			Middlewares: handlers.Event.Middlewares(),
+			SubRoutes: []Route{
+				{
+					Path:    "",
+					Handler: handlers.Event.HandlerAllGamesList(),
+					Methods: []string{"GET"},
+				},
+			},
+		},
 		{
 			PathPrefix:  "/challenges",
 			Middlewares: handlers.Challenge.Middlewares(),
@@ -79,6 +92,32 @@ func (c *config) Routes() []RouteInfo {
 					Handler: handlers.Challenge.Handler(),
 					Methods: []string{"GET", "DELETE", "PUT"},
 				},
+				{
+					Path:    "/{parent}/revisions",
+					Handler: handlers.Challenge.HandlerRevisionList(),
+					Methods: []string{"GET"},
+				},
+				{
+					Path:    "/{parent}/revisions/{resourceName}",
+					Handler: handlers.Challenge.HandlerRevision(),
+					Methods: []string{"GET"},
+				},
+				{
+					Path:    "/{resourceName}/bundle",
+					Handler: handlers.Challenge.HandlerBundle(),
+					Methods: []string{"GET", "PUT"},
+				},
+			},
+		},
+		{
+			PathPrefix:  "/assets",
+			Middlewares: handlers.Challenge.Middlewares(),
+			SubRoutes: []Route{
+				{
+					Path:    "/{resourceName}",
+					Handler: handlers.Challenge.HandlerAsset(),
+					Methods: []string{"GET"},
+				},
 			},
 		},
 		{
@@ -93,10 +132,166 @@ func (c *config) Routes() []RouteInfo {
 				{
 					Path:    "/{resourceName}",
 					Handler: handlers.Policy.Handler(),
+					Methods: []string{"GET", "DELETE"},
+				},
+			},
+		},
+		{
+			PathPrefix:  "/roles",
+			Middlewares: handlers.Role.Middlewares(),
+			SubRoutes: []Route{
+				{
+					Path:    "",
+					Handler: handlers.Role.HandlerList(),
+					Methods: []string{"POST", "GET"},
+				},
+				{
+					Path:    "/{resourceName}",
+					Handler: handlers.Role.Handler(),
+					Methods: []string{"GET", "DELETE", "PUT"},
+				},
+			},
+		},
+		{
+			PathPrefix:  "/rolebindings",
+			Middlewares: handlers.Role.Middlewares(),
+			SubRoutes: []Route{
+				{
+					Path:    "",
+					Handler: handlers.Role.HandlerBindingList(),
+					Methods: []string{"POST", "GET"},
+				},
+				{
+					Path:    "/{resourceName}",
+					Handler: handlers.Role.HandlerBinding(),
+					Methods: []string{"GET", "DELETE", "PUT"},
+				},
+			},
+		},
+		{
+			PathPrefix:  "/users",
+			Middlewares: handlers.User.Middlewares(),
+			SubRoutes: []Route{
+				{
+					Path:    "",
+					Handler: handlers.User.HandlerList(),
+					Methods: []string{"POST", "GET"},
+				},
+				{
+					Path:    "/{resourceName}",
+					Handler: handlers.User.Handler(),
+					Methods: []string{"GET", "DELETE", "PUT"},
+				},
+			},
+		},
+		{
+			PathPrefix:  "/eventhooks",
+			Middlewares: handlers.EventHook.Middlewares(),
+			SubRoutes: []Route{
+				{
+					Path:    "",
+					Handler: handlers.EventHook.HandlerList(),
+					Methods: []string{"POST", "GET"},
+				},
+				{
+					Path:    "/{resourceName}",
+					Handler: handlers.EventHook.Handler(),
 					Methods: []string{"GET", "DELETE", "PUT"},
 				},
 			},
 		},
+		{
+			PathPrefix:  "/sessions",
+			Middlewares: handlers.Session.Middlewares(),
+			SubRoutes: []Route{
+				{
+					Path:    "",
+					Handler: handlers.Session.HandlerList(),
+					Methods: []string{"GET"},
+				},
+				{
+					Path:    "/{resourceName}",
+					Handler: handlers.Session.Handler(),
+					Methods: []string{"GET", "DELETE"},
+				},
+			},
+		},
+		{
+			PathPrefix:  "/refresh",
+			Middlewares: handlers.Session.Middlewares(),
+			SubRoutes: []Route{
+				{
+					Path:    "",
+					Handler: handlers.Session.HandlerRefresh(),
+					Methods: []string{"POST"},
+				},
+			},
+		},
+		{
+			PathPrefix:  "/logout",
+			Middlewares: handlers.Session.Middlewares(),
+			SubRoutes: []Route{
+				{
+					Path:    "",
+					Handler: handlers.Session.HandlerLogout(),
+					Methods: []string{"POST"},
+				},
+			},
+		},
+		{
+			PathPrefix:  "/selfsubjectaccessreviews",
+			Middlewares: handlers.AccessReview.Middlewares(),
+			SubRoutes: []Route{
+				{
+					Path:    "",
+					Handler: handlers.AccessReview.HandlerSelf(),
+					Methods: []string{"POST"},
+				},
+			},
+		},
+		{
+			PathPrefix:  "/subjectaccessreviews",
+			Middlewares: handlers.AccessReview.Middlewares(),
+			SubRoutes: []Route{
+				{
+					Path:    "",
+					Handler: handlers.AccessReview.HandlerSubject(),
+					Methods: []string{"POST"},
+				},
+			},
+		},
+		{
+			PathPrefix:  "/export",
+			Middlewares: handlers.Export.Middlewares(),
+			SubRoutes: []Route{
+				{
+					Path:    "",
+					Handler: handlers.Export.HandlerExport(),
+					Methods: []string{"GET"},
+				},
+			},
+		},
+		{
+			PathPrefix:  "/import",
+			Middlewares: handlers.Export.Middlewares(),
+			SubRoutes: []Route{
+				{
+					Path:    "",
+					Handler: handlers.Export.HandlerImport(),
+					Methods: []string{"POST"},
+				},
+			},
+		},
+		{
+			PathPrefix: "/.well-known",
+			SubRoutes: []Route{
+				{
+					Path:    "/jwks.json",
+					Handler: handlers.Auth.HandlerJWKS(),
+					Methods: []string{"GET"},
+				},
+			},
+		},
 		{
 			PathPrefix:  "/login",
 			Middlewares: handlers.Auth.Middlewares(),
@@ -111,8 +306,32 @@ func (c *config) Routes() []RouteInfo {
 	}
 }
 
+// Probes are served outside of the API version for the liveness and readiness
+// probes and the Prometheus scraper
+func (c *config) Probes() []Route {
+	return []Route{
+		{
+			Path:    "/healthz",
+			Handler: handlers.Health.HandlerLiveness(),
+			Methods: []string{"GET"},
+		},
+		{
+			Path:    "/readyz",
+			Handler: handlers.Health.HandlerReadiness(),
+			Methods: []string{"GET"},
+		},
+		{
+			Path:    "/metrics",
+			Handler: promhttp.Handler().ServeHTTP,
+			Methods: []string{"GET"},
+		},
+	}
+}
+
 func (c *config) Middlewares() []mux.MiddlewareFunc {
 	return []mux.MiddlewareFunc{
+		metricsMiddleware,
+		auditMiddleware,
 		authenticationMiddleware,
 		decoderMiddleware,
 	}
@@ -0,0 +1,275 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/kubeplay/gameserver/pkg/api/handlers"
+	"github.com/kubeplay/gameserver/pkg/selector"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// Discovery are the documents describing the API, they're built from the
+// routes and the registered types and served without authentication.
+func (c *config) Discovery() []Route {
+	return []Route{
+		{
+			Path:    c.Version,
+			Handler: c.discoveryHandler,
+			Methods: []string{"GET"},
+		},
+		{
+			Path:    "/openapi" + c.Version,
+			Handler: c.openAPIHandler,
+			Methods: []string{"GET"},
+		},
+	}
+}
+
+func (c *config) discoveryHandler(w http.ResponseWriter, r *http.Request) {
+	version := RequestVersion(r)
+	resources := c.APIResources()
+	// the paths of the other versions are rewritten to the hub
+	prefix := "/" + strings.TrimPrefix(version, types.GroupName+"/")
+	for i := range resources {
+		for j, p := range resources[i].Paths {
+			resources[i].Paths[j] = prefix + strings.TrimPrefix(p, c.Version)
+		}
+	}
+	handlers.NewResponse(w).WriteJSON(&types.APIResourceList{
+		TypeMeta:     types.TypeMeta{Kind: types.APIResourceListKind},
+		GroupVersion: version,
+		Resources:    resources,
+	})
+}
+
+func (c *config) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := json.Marshal(c.OpenAPI())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	handlers.NewResponse(w).WriteRawJSON(data)
+}
+
+// routePath is a route of the API with the parts of its path
+type routePath struct {
+	Route
+	// resource is the plural name of the kind of the route, subresource is
+	// the path after the name of the object, e.g.: solve
+	resource    string
+	subresource string
+	// item is true when the route ends with the name of an object
+	item bool
+}
+
+// routePaths returns the routes with their full path, e.g.: /v1/events/{parent}/games
+func (c *config) routePaths() []routePath {
+	kinds := c.resourceKinds()
+	var paths []routePath
+	for _, ri := range c.Routes() {
+		for _, sr := range ri.SubRoutes {
+			rp := routePath{Route: sr}
+			rp.Path = c.Version + ri.PathPrefix + sr.Path
+			var subresources []string
+			for _, seg := range strings.Split(strings.TrimPrefix(rp.Path, c.Version+"/"), "/") {
+				switch {
+				case isPathVar(seg):
+					rp.item = true
+				case kinds[seg] != "":
+					rp.resource, rp.item, subresources = seg, false, nil
+				case rp.resource != "":
+					subresources = append(subresources, seg)
+				}
+			}
+			rp.subresource = strings.Join(subresources, "/")
+			paths = append(paths, rp)
+		}
+	}
+	return paths
+}
+
+// APIResources returns the kinds served by the routes and their operations
+func (c *config) APIResources() []types.APIResource {
+	kinds := c.resourceKinds()
+	var resources []types.APIResource
+	index := map[string]int{}
+	for _, rp := range c.routePaths() {
+		if rp.resource == "" {
+			continue
+		}
+		i, ok := index[rp.resource]
+		if !ok {
+			i = len(resources)
+			index[rp.resource] = i
+			resources = append(resources, types.APIResource{Name: rp.resource, Kind: kinds[rp.resource]})
+		}
+		res := &resources[i]
+		if rp.subresource != "" {
+			res.Subresources = appendMissing(res.Subresources, rp.subresource)
+			continue
+		}
+		if !rp.item {
+			res.Paths = append(res.Paths, rp.Path)
+		}
+		for _, method := range rp.Methods {
+			res.Verbs = appendMissing(res.Verbs, verbOf(method, rp.item))
+		}
+	}
+	return resources
+}
+
+// OpenAPI returns the OpenAPI document of the routes, the objects of the
+// registered kinds are described by their schemas.
+func (c *config) OpenAPI() *types.OpenAPI {
+	doc := &types.OpenAPI{
+		OpenAPI: "3.0.0",
+		Info: types.OpenAPIInfo{
+			Title:   "kubeplay",
+			Version: types.GroupVersion(types.HubVersion),
+		},
+		Paths:      map[string]map[string]types.Operation{},
+		Components: types.OpenAPIComponents{Schemas: map[string]*types.JSONSchema{}},
+	}
+	for _, obj := range c.RegisteredAPITypes {
+		doc.Components.Schemas[obj.GetObjectKind()] = types.SchemaOf(obj)
+	}
+	kinds := c.resourceKinds()
+	for _, rp := range c.routePaths() {
+		if doc.Paths[rp.Path] == nil {
+			doc.Paths[rp.Path] = map[string]types.Operation{}
+		}
+		for _, method := range rp.Methods {
+			op := types.Operation{
+				OperationID: operationID(rp.Path, method),
+				Responses:   map[string]types.Response{"200": {Description: "OK"}},
+			}
+			for _, seg := range strings.Split(rp.Path, "/") {
+				if isPathVar(seg) {
+					op.Parameters = append(op.Parameters, types.Parameter{
+						Name:     strings.Trim(seg, "{}"),
+						In:       "path",
+						Required: true,
+						Schema:   &types.JSONSchema{Type: "string"},
+					})
+				}
+			}
+			kind := kinds[rp.resource]
+			if kind != "" && rp.subresource == "" {
+				op.Tags = []string{rp.resource}
+				switch verbOf(method, rp.item) {
+				case "list":
+					op.Parameters = append(op.Parameters, listParameters()...)
+					doc.Components.Schemas[kind+"List"] = listSchema(kind)
+					op.Responses["200"] = jsonResponse(kind + "List")
+				case "create", "update":
+					op.RequestBody = &types.RequestBody{
+						Required: true,
+						Content:  map[string]types.MediaType{"application/json": {Schema: schemaRef(kind)}},
+					}
+					op.Responses["200"] = jsonResponse(kind)
+				case "get":
+					op.Responses["200"] = jsonResponse(kind)
+				}
+			}
+			doc.Paths[rp.Path][strings.ToLower(method)] = op
+		}
+	}
+	return doc
+}
+
+// resourceKinds maps the plural names of the registered kinds to the kinds, e.g.: policies
+func (c *config) resourceKinds() map[string]string {
+	kinds := map[string]string{}
+	for _, obj := range c.RegisteredAPITypes {
+		name := strings.ToLower(obj.GetObjectKind())
+		if strings.HasSuffix(name, "y") {
+			name = strings.TrimSuffix(name, "y") + "ie"
+		}
+		kinds[name+"s"] = obj.GetObjectKind()
+	}
+	return kinds
+}
+
+func verbOf(method string, item bool) string {
+	switch method {
+	case "GET":
+		if item {
+			return "get"
+		}
+		return "list"
+	case "POST":
+		return "create"
+	case "PUT":
+		return "update"
+	case "PATCH":
+		return "patch"
+	case "DELETE":
+		return "delete"
+	}
+	return strings.ToLower(method)
+}
+
+// operationID returns a unique name of an operation, e.g.: getEventsGamesByName
+func operationID(path, method string) string {
+	id := strings.ToLower(method)
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if i < 2 || isPathVar(seg) {
+			// the version
+			continue
+		}
+		for _, word := range strings.FieldsFunc(seg, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			id += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	if isPathVar(segments[len(segments)-1]) {
+		id += "ByName"
+	}
+	return id
+}
+
+func listParameters() []types.Parameter {
+	var params []types.Parameter
+	for _, name := range []string{selector.LabelSelectorParam, selector.FieldSelectorParam, "continue"} {
+		params = append(params, types.Parameter{Name: name, In: "query", Schema: &types.JSONSchema{Type: "string"}})
+	}
+	return append(params, types.Parameter{Name: "limit", In: "query", Schema: &types.JSONSchema{Type: "integer", Format: "int64"}})
+}
+
+func listSchema(kind string) *types.JSONSchema {
+	s := types.SchemaOf(struct {
+		types.TypeMeta `json:",inline"`
+		types.ListMeta `json:"metadata"`
+	}{})
+	s.Properties["items"] = &types.JSONSchema{Type: "array", Items: schemaRef(kind)}
+	return s
+}
+
+func jsonResponse(schema string) types.Response {
+	return types.Response{
+		Description: "OK",
+		Content:     map[string]types.MediaType{"application/json": {Schema: schemaRef(schema)}},
+	}
+}
+
+func schemaRef(name string) *types.JSONSchema {
+	return &types.JSONSchema{Ref: "#/components/schemas/" + name}
+}
+
+func isPathVar(seg string) bool {
+	return strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}")
+}
+
+func appendMissing(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
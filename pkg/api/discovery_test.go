@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+func findResource(resources []types.APIResource, name string) *types.APIResource {
+	for i := range resources {
+		if resources[i].Name == name {
+			return &resources[i]
+		}
+	}
+	return nil
+}
+
+func TestAPIResources(t *testing.T) {
+	resources := Config.APIResources()
+	for _, tc := range []struct {
+		name             string
+		kind             string
+		wantVerbs        []string
+		wantSubresources []string
+		wantPaths        []string
+	}{
+		{
+			name:      "events",
+			kind:      types.EventKind,
+			wantVerbs: []string{"create", "list", "get", "delete"},
+			wantPaths: []string{"/v1/events"},
+		},
+		{
+			name:             "games",
+			kind:             types.GameKind,
+			wantVerbs:        []string{"create", "list", "get", "delete"},
+			wantSubresources: []string{"start", "solve"},
+			wantPaths:        []string{"/v1/events/{parent}/games", "/v1/games"},
+		},
+		{
+			name:             "challenges",
+			kind:             types.ChallengeKind,
+			wantVerbs:        []string{"create", "list", "get", "delete", "update"},
+			wantSubresources: []string{"revisions", "bundle"},
+			wantPaths:        []string{"/v1/challenges"},
+		},
+		{
+			name:      "policies",
+			kind:      types.PolicyKind,
+			wantVerbs: []string{"create", "list", "get", "delete"},
+			wantPaths: []string{"/v1/policies"},
+		},
+	} {
+		res := findResource(resources, tc.name)
+		if res == nil {
+			t.Errorf("expected the resource %s", tc.name)
+			continue
+		}
+		if res.Kind != tc.kind {
+			t.Errorf("%s: expected the kind %s, got %s", tc.name, tc.kind, res.Kind)
+		}
+		if !reflect.DeepEqual(res.Verbs, tc.wantVerbs) {
+			t.Errorf("%s: expected the verbs %v, got %v", tc.name, tc.wantVerbs, res.Verbs)
+		}
+		if !reflect.DeepEqual(res.Subresources, tc.wantSubresources) {
+			t.Errorf("%s: expected the subresources %v, got %v", tc.name, tc.wantSubresources, res.Subresources)
+		}
+		if !reflect.DeepEqual(res.Paths, tc.wantPaths) {
+			t.Errorf("%s: expected the paths %v, got %v", tc.name, tc.wantPaths, res.Paths)
+		}
+	}
+	// routes without a kind aren't resources, e.g.: /v1/login
+	if findResource(resources, "login") != nil || findResource(resources, "export") != nil {
+		t.Error("expected only the registered kinds to be resources")
+	}
+}
+
+func TestDiscoveryHandler(t *testing.T) {
+	h := Config.VersionHandler(http.HandlerFunc(Config.discoveryHandler))
+	for path, wantVersion := range map[string]string{
+		"/v1":      "v1",
+		"/v1beta1": "v1beta1",
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected the status 200, got %d: %s", path, w.Code, w.Body)
+		}
+		var list types.APIResourceList
+		if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+			t.Fatal(err)
+		}
+		if list.Kind != types.APIResourceListKind || list.GroupVersion != types.GroupVersion(wantVersion) {
+			t.Errorf("%s: expected the resources of %s, got %s %s", path, wantVersion, list.Kind, list.GroupVersion)
+		}
+		games := findResource(list.Resources, "games")
+		if games == nil || games.Paths[0] != "/"+wantVersion+"/events/{parent}/games" {
+			t.Errorf("%s: expected the paths of the version, got %+v", path, games)
+		}
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	doc := Config.OpenAPI()
+	ids := map[string]string{}
+	for path, ops := range doc.Paths {
+		for method, op := range ops {
+			if other, ok := ids[op.OperationID]; ok {
+				t.Errorf("%s %s: the operation id %s is used by %s", method, path, op.OperationID, other)
+			}
+			ids[op.OperationID] = method + " " + path
+			// every variable of the path is a parameter
+			for _, seg := range strings.Split(path, "/") {
+				if !isPathVar(seg) {
+					continue
+				}
+				found := false
+				for _, p := range op.Parameters {
+					found = found || (p.In == "path" && p.Required && "{"+p.Name+"}" == seg)
+				}
+				if !found {
+					t.Errorf("%s %s: missing the path parameter %s", method, path, seg)
+				}
+			}
+			// the references are described by the components
+			var refs []*types.JSONSchema
+			if op.RequestBody != nil {
+				refs = append(refs, op.RequestBody.Content["application/json"].Schema)
+			}
+			for _, resp := range op.Responses {
+				for _, mt := range resp.Content {
+					refs = append(refs, mt.Schema)
+				}
+			}
+			for _, ref := range refs {
+				name := strings.TrimPrefix(ref.Ref, "#/components/schemas/")
+				if doc.Components.Schemas[name] == nil {
+					t.Errorf("%s %s: unknown schema %s", method, path, ref.Ref)
+				}
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		path        string
+		method      string
+		wantID      string
+		wantRequest string
+		wantSchema  string
+	}{
+		{path: "/v1/events/{parent}/games", method: "get", wantID: "getEventsGames", wantSchema: "GameList"},
+		{path: "/v1/events/{parent}/games/{resourceName}", method: "get", wantID: "getEventsGamesByName", wantSchema: "Game"},
+		{path: "/v1/challenges", method: "post", wantID: "postChallenges", wantRequest: "Challenge", wantSchema: "Challenge"},
+		{path: "/v1/challenges/{resourceName}", method: "put", wantID: "putChallengesByName", wantRequest: "Challenge", wantSchema: "Challenge"},
+		{path: "/v1/events/{parent}/games/{resourceName}/solve", method: "post", wantID: "postEventsGamesSolve"},
+		{path: "/v1/.well-known/jwks.json", method: "get", wantID: "getWellKnownJwksJson"},
+	} {
+		op, ok := doc.Paths[tc.path][tc.method]
+		if !ok {
+			t.Errorf("expected the operation %s %s", tc.method, tc.path)
+			continue
+		}
+		if op.OperationID != tc.wantID {
+			t.Errorf("%s %s: expected the operation id %s, got %s", tc.method, tc.path, tc.wantID, op.OperationID)
+		}
+		if tc.wantRequest != "" && (op.RequestBody == nil || !strings.HasSuffix(op.RequestBody.Content["application/json"].Schema.Ref, "/"+tc.wantRequest)) {
+			t.Errorf("%s %s: expected the request body %s, got %+v", tc.method, tc.path, tc.wantRequest, op.RequestBody)
+		}
+		if tc.wantSchema != "" && !strings.HasSuffix(op.Responses["200"].Content["application/json"].Schema.Ref, "/"+tc.wantSchema) {
+			t.Errorf("%s %s: expected the response %s, got %+v", tc.method, tc.path, tc.wantSchema, op.Responses["200"])
+		}
+	}
+	if _, ok := doc.Paths["/v1/events/{resourceName}"]["put"]; ok {
+		t.Error("expected no updates of events")
+	}
+	list := doc.Components.Schemas["GameList"]
+	if list == nil || list.Properties["items"] == nil || list.Properties["items"].Items.Ref != "#/components/schemas/Game" {
+		t.Errorf("expected the items of the list schema, got %+v", list)
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+
+	apiauth "github.com/kubeplay/gameserver/pkg/api/auth"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+var AccessReview = accessReview{}
+
+type accessReview struct{}
+
+func (c *accessReview) HandlerSelf() HandlerFn {
+	return selfSubjectAccessReviewHandler
+}
+
+func (c *accessReview) HandlerSubject() HandlerFn {
+	return subjectAccessReviewHandler
+}
+
+func (c *accessReview) Middlewares() []mux.MiddlewareFunc {
+	return []mux.MiddlewareFunc{}
+}
+
+// selfSubjectAccessReviewHandler evaluates the policies for the caller
+func selfSubjectAccessReviewHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		review, ok := context.Get(r, "payload").(*types.SelfSubjectAccessReview)
+		if !ok {
+			http.Error(w, "unknown type found", http.StatusBadRequest)
+			return
+		}
+		pl, ok := context.Get(r, "player").(*types.PlayerClaims)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		review.Spec.Subject = pl.Username()
+		status, err := reviewAccess(review.Spec, pl.Roles...)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		review.Status = *status
+		NewResponse(w).Status(201).WriteJSON(review)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+// subjectAccessReviewHandler evaluates the policies for an arbitrary subject,
+// only callers allowed to create subject access reviews (hosts) could use it.
+func subjectAccessReviewHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		review, ok := context.Get(r, "payload").(*types.SubjectAccessReview)
+		if !ok {
+			http.Error(w, "unknown type found", http.StatusBadRequest)
+			return
+		}
+		pl, ok := context.Get(r, "player").(*types.PlayerClaims)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		allowed, err := Authorize(pl, r.URL.Path, r.Method)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !allowed {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if review.Spec.Subject == "" {
+			http.Error(w, "missing the subject", http.StatusBadRequest)
+			return
+		}
+		status, err := reviewAccess(review.Spec)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		review.Status = *status
+		NewResponse(w).Status(201).WriteJSON(review)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+// Authorize evaluates if the player is allowed to perform the verb in the path
+func Authorize(pl *types.PlayerClaims, path, verb string) (bool, error) {
+	return apiauth.Review(policiesFile, pl.Username(), path, verb, pl.Roles...)
+}
+
+func reviewAccess(spec types.AccessReviewSpec, roles ...string) (*types.AccessReviewStatus, error) {
+	if spec.Verb == "" || spec.Path == "" {
+		return nil, fmt.Errorf("missing the verb or the path")
+	}
+	allowed, err := apiauth.Review(policiesFile, spec.Subject, spec.Path, spec.Verb, roles...)
+	if err != nil {
+		return nil, err
+	}
+	status := &types.AccessReviewStatus{Allowed: allowed}
+	if allowed {
+		status.Reason = fmt.Sprintf("%q is allowed by a policy rule", spec.Subject)
+	} else {
+		status.Reason = fmt.Sprintf("no policy rule allows %q to %s %s", spec.Subject, spec.Verb, spec.Path)
+	}
+	return status, nil
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/context"
+
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// saveRoleBinding grants the role to the subject in the event, every event when it's empty
+func saveRoleBinding(t *testing.T, name, role, subject, event string) {
+	rb := &types.RoleBinding{
+		TypeMeta: types.TypeMeta{Kind: types.RoleBindingKind},
+		Metadata: types.Metadata{Name: name},
+		Role:     role,
+		Subjects: []string{subject},
+		Event:    event,
+	}
+	_, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.RoleBindingKind).
+		Resources(strings.ToLower(types.RoleBindingKind), name).
+		SaveObject(rb)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serveReview calls the handler with the payload and the player set by the middlewares
+func serveReview(t *testing.T, handler HandlerFn, path string, payload types.Object, pl *types.PlayerClaims) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", path, nil)
+	context.Set(r, "payload", payload)
+	if pl != nil {
+		context.Set(r, "player", pl)
+	}
+	defer context.Clear(r)
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestSelfSubjectAccessReview(t *testing.T) {
+	defer setupDatabase(t)()
+	saveRoleBinding(t, "alice-meetup", "host", "local|alice", "meetup")
+	if err := SyncPolicies(); err != nil {
+		t.Fatal(err)
+	}
+	alice := &types.PlayerClaims{Login: "alice", Provider: types.LocalProvider}
+	for _, tc := range []struct {
+		name        string
+		spec        types.AccessReviewSpec
+		pl          *types.PlayerClaims
+		wantStatus  int
+		wantAllowed bool
+	}{
+		{
+			name:        "guest rule",
+			spec:        types.AccessReviewSpec{Verb: "GET", Path: "/v1/events"},
+			pl:          alice,
+			wantStatus:  http.StatusCreated,
+			wantAllowed: true,
+		},
+		{
+			name:        "binding of the event",
+			spec:        types.AccessReviewSpec{Verb: "POST", Path: "/v1/events/meetup/games/g1/start"},
+			pl:          alice,
+			wantStatus:  http.StatusCreated,
+			wantAllowed: true,
+		},
+		{
+			name:       "another event",
+			spec:       types.AccessReviewSpec{Verb: "POST", Path: "/v1/events/other/games/g1/start"},
+			pl:         alice,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "the subject is the caller",
+			spec: types.AccessReviewSpec{Subject: "local|alice", Verb: "POST", Path: "/v1/events/meetup/games/g1/start"},
+			pl:   &types.PlayerClaims{Login: "bob", Provider: types.LocalProvider},
+			// bob isn't bound to a role of the event
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "missing verb",
+			spec:       types.AccessReviewSpec{Path: "/v1/events"},
+			pl:         alice,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "without a player",
+			spec:       types.AccessReviewSpec{Verb: "GET", Path: "/v1/events"},
+			wantStatus: http.StatusUnauthorized,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serveReview(t, selfSubjectAccessReviewHandler, "/v1/selfsubjectaccessreviews",
+				&types.SelfSubjectAccessReview{Spec: tc.spec}, tc.pl)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected the status %d, got %d: %s", tc.wantStatus, w.Code, w.Body)
+			}
+			if w.Code != http.StatusCreated {
+				return
+			}
+			review := &types.SelfSubjectAccessReview{}
+			if err := json.NewDecoder(w.Body).Decode(review); err != nil {
+				t.Fatal(err)
+			}
+			if review.Status.Allowed != tc.wantAllowed || review.Status.Reason == "" {
+				t.Errorf("expected allowed=%v with a reason, got %+v", tc.wantAllowed, review.Status)
+			}
+			if review.Spec.Subject != tc.pl.Username() {
+				t.Errorf("expected the subject of the caller, got %q", review.Spec.Subject)
+			}
+		})
+	}
+}
+
+func TestSubjectAccessReview(t *testing.T) {
+	defer setupDatabase(t)()
+	saveRoleBinding(t, "admin", "host", "local|admin", "")
+	saveRoleBinding(t, "alice-meetup", "host", "local|alice", "meetup")
+	if err := SyncPolicies(); err != nil {
+		t.Fatal(err)
+	}
+	admin := &types.PlayerClaims{Login: "admin", Provider: types.LocalProvider}
+	for _, tc := range []struct {
+		name        string
+		spec        types.AccessReviewSpec
+		pl          *types.PlayerClaims
+		wantStatus  int
+		wantAllowed bool
+	}{
+		{
+			name:        "a subject of another player",
+			spec:        types.AccessReviewSpec{Subject: "local|alice", Verb: "DELETE", Path: "/v1/events/meetup/games/g1"},
+			pl:          admin,
+			wantStatus:  http.StatusCreated,
+			wantAllowed: true,
+		},
+		{
+			name:       "denied in another event",
+			spec:       types.AccessReviewSpec{Subject: "local|alice", Verb: "DELETE", Path: "/v1/events/other/games/g1"},
+			pl:         admin,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "missing subject",
+			spec:       types.AccessReviewSpec{Verb: "GET", Path: "/v1/events"},
+			pl:         admin,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "guests couldn't review other subjects",
+			spec:       types.AccessReviewSpec{Subject: "local|admin", Verb: "GET", Path: "/v1/events"},
+			pl:         &types.PlayerClaims{Login: "bob", Provider: types.LocalProvider},
+			wantStatus: http.StatusForbidden,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := serveReview(t, subjectAccessReviewHandler, "/v1/subjectaccessreviews",
+				&types.SubjectAccessReview{Spec: tc.spec}, tc.pl)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected the status %d, got %d: %s", tc.wantStatus, w.Code, w.Body)
+			}
+			if w.Code != http.StatusCreated {
+				return
+			}
+			review := &types.SubjectAccessReview{}
+			if err := json.NewDecoder(w.Body).Decode(review); err != nil {
+				t.Fatal(err)
+			}
+			if review.Status.Allowed != tc.wantAllowed {
+				t.Errorf("expected allowed=%v, got %+v", tc.wantAllowed, review.Status)
+			}
+		})
+	}
+}
@@ -3,10 +3,11 @@ package handlers
 import (
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
-	"time"
 
+	apiauth "github.com/kubeplay/gameserver/pkg/api/auth"
 	"github.com/kubeplay/gameserver/pkg/types"
 	"github.com/sirupsen/logrus"
 
@@ -14,7 +15,30 @@ import (
 	"github.com/gorilla/mux"
 )
 
-var Auth = auth{}
+var (
+	Auth = auth{}
+
+	githubMembership   apiauth.GitHubMembership
+	githubRoleMappings []apiauth.RoleMapping
+)
+
+// SetGitHubRoleMappings grants roles to the members of GitHub organizations and teams at login
+func SetGitHubRoleMappings(m apiauth.GitHubMembership, mappings []apiauth.RoleMapping) {
+	githubMembership = m
+	githubRoleMappings = mappings
+}
+
+// resolveGitHubRoles evaluates the role mappings, failures revoke the granted roles
+func resolveGitHubRoles(profile *types.PlayerClaims) {
+	if githubMembership == nil || profile.Provider != types.GitHubProvider {
+		return
+	}
+	roles, err := apiauth.ResolveRoles(githubMembership, githubRoleMappings, profile.Login)
+	if err != nil {
+		logrus.WithField("login", profile.Login).Warnf("failed resolving github memberships: %v", err)
+	}
+	profile.Roles = roles
+}
 
 type auth struct{}
 
@@ -22,6 +46,10 @@ func (c *auth) Handler() HandlerFn {
 	return authHandler
 }
 
+func (c *auth) HandlerJWKS() HandlerFn {
+	return jwksHandler
+}
+
 func (c *auth) Middlewares() []mux.MiddlewareFunc {
 	return []mux.MiddlewareFunc{authMiddleware}
 }
@@ -48,44 +76,77 @@ func authHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "GET":
 		basicAuth := context.Get(r, "github-basic-auth")
-		req, err := http.NewRequest("GET", "https://api.github.com/user", nil)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		parts := strings.SplitN(basicAuth.(string), ":", 2)
+		if len(parts) != 2 {
+			http.Error(w, "Malformed Authorization Header", http.StatusUnauthorized)
 			return
 		}
-		parts := strings.Split(basicAuth.(string), ":")
-		req.SetBasicAuth(parts[0], parts[1])
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		var profile *types.PlayerClaims
+		var err error
+		switch provider := r.URL.Query().Get("provider"); provider {
+		case "", types.GitHubProvider:
+			profile, err = authenticateGitHubUser(parts[0], parts[1])
+		case types.LocalProvider:
+			profile, err = authenticateLocalUser(parts[0], parts[1])
+		default:
+			msg := fmt.Sprintf("unknown identity provider %q", provider)
+			http.Error(w, msg, http.StatusBadRequest)
 			return
 		}
-		if resp.StatusCode != 200 {
-			logrus.WithField("status", resp.StatusCode).Infof("failed authenticating to github")
+		if err != nil {
+			logrus.WithField("login", parts[0]).Infof("failed authenticating user: %v", err)
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		profile := &types.PlayerClaims{}
-		if err := json.NewDecoder(resp.Body).Decode(profile); err != nil {
+		if err := NewSession(profile); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
+		if err := json.NewEncoder(w).Encode(profile); err != nil {
+			logrus.Warnf("failed encoding response %v", err)
 		}
-		if err := GenerateNewJwtToken(
-			jwtSecret,
-			profile,
-			time.Now().UTC().Add(time.Hour*12),
-		); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+// jwksHandler publishes the public keys allowing third parties to verify player tokens
+func jwksHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		if signingKeys == nil {
+			http.Error(w, "signing keys not configured", http.StatusServiceUnavailable)
 			return
 		}
-		if err := json.NewEncoder(w).Encode(profile); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(signingKeys.JWKS()); err != nil {
 			logrus.Warnf("failed encoding response %v", err)
 		}
 	default:
 		http.Error(w, "Not Implemented", http.StatusNotImplemented)
 	}
 }
+
+// authenticateGitHubUser validates the credentials fetching the user profile from GitHub
+func authenticateGitHubUser(username, password string) (*types.PlayerClaims, error) {
+	req, err := http.NewRequest("GET", "https://api.github.com/user", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(username, password)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("failed authenticating to github, status %d", resp.StatusCode)
+	}
+	profile := &types.PlayerClaims{}
+	if err := json.NewDecoder(resp.Body).Decode(profile); err != nil {
+		return nil, err
+	}
+	profile.Provider = types.GitHubProvider
+	resolveGitHubRoles(profile)
+	return profile, nil
+}
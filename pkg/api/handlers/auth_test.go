@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	apiauth "github.com/kubeplay/gameserver/pkg/api/auth"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+type unavailableMembership struct{}
+
+func (unavailableMembership) IsMember(login, group string) (bool, error) {
+	return false, fmt.Errorf("github is unavailable")
+}
+
+func TestResolveGitHubRoles(t *testing.T) {
+	defer SetGitHubRoleMappings(nil, nil)
+	mappings := []apiauth.RoleMapping{{Group: "kubeplay/hosts", Role: apiauth.HostRole}}
+	fake := apiauth.FakeGitHubMembership{"alice": {"kubeplay/hosts"}}
+	for _, tc := range []struct {
+		name       string
+		membership apiauth.GitHubMembership
+		profile    types.PlayerClaims
+		want       []string
+	}{
+		{
+			name:       "member of the team",
+			membership: fake,
+			profile:    types.PlayerClaims{Login: "alice", Provider: types.GitHubProvider},
+			want:       []string{apiauth.HostRole},
+		},
+		{
+			name:       "not a member",
+			membership: fake,
+			profile:    types.PlayerClaims{Login: "bob", Provider: types.GitHubProvider, Roles: []string{"stale"}},
+			want:       nil,
+		},
+		{
+			name:       "other providers keep their roles",
+			membership: fake,
+			profile:    types.PlayerClaims{Login: "alice", Provider: types.LocalProvider, Roles: []string{"judge"}},
+			want:       []string{"judge"},
+		},
+		{
+			name:       "failures revoke the roles",
+			membership: unavailableMembership{},
+			profile:    types.PlayerClaims{Login: "alice", Provider: types.GitHubProvider, Roles: []string{apiauth.HostRole}},
+			want:       nil,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			SetGitHubRoleMappings(tc.membership, mappings)
+			resolveGitHubRoles(&tc.profile)
+			if !reflect.DeepEqual(tc.profile.Roles, tc.want) {
+				t.Errorf("expected the roles %v, got %v", tc.want, tc.profile.Roles)
+			}
+		})
+	}
+}
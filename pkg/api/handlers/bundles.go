@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kubeplay/gameserver/pkg/audit"
+	"github.com/kubeplay/gameserver/pkg/bundle"
+	"github.com/kubeplay/gameserver/pkg/provisioner"
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// maxBundleSize limits the size of the archives pushed
+const maxBundleSize = 32 << 20
+
+// assetResource is where the files of the bundles are stored by their digest
+const assetResource = "asset"
+
+func (c *challenge) HandlerBundle() HandlerFn {
+	return challengeBundleHandler
+}
+
+func (c *challenge) HandlerAsset() HandlerFn {
+	return assetHandler
+}
+
+func challengeBundleHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	switch r.Method {
+	case "PUT":
+		if ct := r.Header.Get("Content-Type"); ct != bundle.MediaType {
+			msg := fmt.Sprintf("unexpected content type %q, expected %q", ct, bundle.MediaType)
+			http.Error(w, msg, http.StatusUnsupportedMediaType)
+			return
+		}
+		data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBundleSize))
+		if err != nil {
+			msg := fmt.Sprintf("failed reading bundle, the limit is %dMB: %v", maxBundleSize>>20, err)
+			http.Error(w, msg, http.StatusRequestEntityTooLarge)
+			return
+		}
+		b, err := bundle.Read(bytes.NewReader(data))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c := b.Challenge
+		if c.Name != params["resourceName"] {
+			msg := fmt.Sprintf("the bundle is of the challenge %q, expected %q", c.Name, params["resourceName"])
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+		if c.Provisioner != nil {
+			if _, err := provisioner.Get(c.Provisioner.Name); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		c.Bundle = &types.ChallengeBundle{
+			Digest:   bundle.Digest(data),
+			PushedAt: time.Now().UTC().Format(time.RFC3339),
+		}
+		for name, content := range b.Files {
+			digest := bundle.Digest(content)
+			err := store.New(dbConfig.file, dbConfig.bucket).
+				Resources(assetResource).
+				PutBlob(digest, content)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			c.Bundle.Files = append(c.Bundle.Files, types.BundleFile{
+				Path:   name,
+				Digest: digest,
+				Size:   int64(len(content)),
+			})
+		}
+		sort.Slice(c.Bundle.Files, func(i, j int) bool {
+			return c.Bundle.Files[i].Path < c.Bundle.Files[j].Path
+		})
+		c.AssetsURL = path.Join("/v1/challenges", c.Name, "bundle")
+		audit.Annotate(r, "digest", c.Bundle.Digest)
+
+		// pushing a bundle creates the challenge or replaces it
+		old, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.ChallengeKind).
+			Resources(strings.ToLower(types.ChallengeKind)).
+			Get(c.Name)
+		if err != nil {
+			resp, err := createChallenge(c)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			NewResponse(w).Status(201).WriteJSON(resp)
+			return
+		}
+		// the same archive keeps the revision
+		if prev := old.(*types.Challenge).Bundle; prev != nil && prev.Digest == c.Bundle.Digest {
+			c.Bundle.PushedAt = prev.PushedAt
+		}
+		resp, err := updateChallenge(old.(*types.Challenge), c)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		NewResponse(w).WriteJSON(resp)
+	case "GET":
+		obj, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.ChallengeKind).
+			Resources(strings.ToLower(types.ChallengeKind)).
+			Get(params["resourceName"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		c := obj.(*types.Challenge)
+		if c.Bundle == nil {
+			msg := fmt.Sprintf("the challenge %q doesn't have a bundle", c.Name)
+			http.Error(w, msg, http.StatusNotFound)
+			return
+		}
+		// players must never see the keys or the manifests of the provisioner
+		NewResponse(w).WriteJSON(&types.Challenge{
+			TypeMeta:  c.TypeMeta,
+			Metadata:  c.Metadata,
+			AssetsURL: c.AssetsURL,
+			Bundle:    bundle.Public(c.Bundle),
+		})
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+func assetHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	switch r.Method {
+	case "GET":
+		digest := params["resourceName"]
+		if !bundle.ValidDigest(digest) {
+			http.Error(w, fmt.Sprintf("invalid digest %q", digest), http.StatusBadRequest)
+			return
+		}
+		data, err := store.New(dbConfig.file, dbConfig.bucket).
+			Resources(assetResource).
+			GetBlob(digest)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		// the content of a digest never changes
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+		w.Header().Set("ETag", fmt.Sprintf("%q", digest))
+		if _, err := w.Write(data); err != nil {
+			logrus.WithError(err).WithField("digest", digest).Warn("Failed writing asset")
+		}
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+// bundleManifests returns the manifests of the provisioner of a challenge, the
+// paths are relative to the provisioner directory of the bundle.
+func bundleManifests(c *types.Challenge) (map[string][]byte, error) {
+	if c.Bundle == nil {
+		return nil, nil
+	}
+	manifests := map[string][]byte{}
+	prefix := bundle.ProvisionerDir + "/"
+	for _, f := range c.Bundle.Files {
+		if !strings.HasPrefix(f.Path, prefix) {
+			continue
+		}
+		data, err := store.New(dbConfig.file, dbConfig.bucket).
+			Resources(assetResource).
+			GetBlob(f.Digest)
+		if err != nil {
+			return nil, err
+		}
+		manifests[strings.TrimPrefix(f.Path, prefix)] = data
+	}
+	return manifests, nil
+}
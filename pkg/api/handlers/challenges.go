@@ -7,6 +7,7 @@ import (
 
 	"github.com/gorilla/context"
 	"github.com/gorilla/mux"
+	"github.com/kubeplay/gameserver/pkg/provisioner"
 	"github.com/kubeplay/gameserver/pkg/store"
 	"github.com/kubeplay/gameserver/pkg/types"
 	"github.com/sirupsen/logrus"
@@ -56,12 +57,37 @@ func challengeMiddleware(next http.Handler) http.Handler {
 func challengeHandler(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	switch r.Method {
-	// TODO: don't delete if have references to events/games
 	case "DELETE":
-		err := store.New(dbConfig.file, dbConfig.bucket).
+		policy, err := deletionPolicy(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s := store.New(dbConfig.file, dbConfig.bucket).
 			Kind(types.ChallengeKind).
-			Resources(strings.ToLower(types.ChallengeKind)).
-			Delete(params["resourceName"])
+			Resources(strings.ToLower(types.ChallengeKind))
+		if _, err := s.Get(params["resourceName"]); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if status, err := deleteDependents(policy, types.ChallengeKind, params["resourceName"]); err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		// the revisions are kept while orphaned games are solved by them
+		pinned, err := challengePinned(params["resourceName"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s = store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.ChallengeKind).
+			Resources(strings.ToLower(types.ChallengeKind))
+		if pinned {
+			err = s.DeleteObject(params["resourceName"])
+		} else {
+			err = s.Delete(params["resourceName"])
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -73,7 +99,7 @@ func challengeHandler(w http.ResponseWriter, r *http.Request) {
 			Resources(strings.ToLower(types.ChallengeKind)).
 			Get(params["resourceName"])
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), storeErrorStatus(err))
 			return
 		}
 		NewResponse(w).WriteJSON(obj)
@@ -89,15 +115,21 @@ func challengeHandler(w http.ResponseWriter, r *http.Request) {
 			Resources(strings.ToLower(types.ChallengeKind)).
 			Get(params["resourceName"])
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), storeErrorStatus(err))
 			return
 		}
-		obj, err := store.New(dbConfig.file, dbConfig.bucket).
-			Kind(types.ChallengeKind).
-			Resources(
-				strings.ToLower(types.ChallengeKind),
-				params["resourceName"],
-			).Update(old, new)
+		// the bundle is only replaced by pushing a new one
+		if new.Bundle == nil {
+			new.Bundle = old.(*types.Challenge).Bundle
+			new.AssetsURL = old.(*types.Challenge).AssetsURL
+		}
+		if new.Provisioner != nil {
+			if _, err := provisioner.Get(new.Provisioner.Name); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		obj, err := updateChallenge(old.(*types.Challenge), new)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -117,26 +149,34 @@ func challengeListHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "unknown type found", http.StatusBadRequest)
 			return
 		}
-		resp, err := store.New(dbConfig.file, dbConfig.bucket).
-			Kind(types.ChallengeKind).
-			Resources(strings.ToLower(types.ChallengeKind), c.Name).
-			SaveObject(c)
+		if c.Provisioner != nil {
+			if _, err := provisioner.Get(c.Provisioner.Name); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		resp, err := createChallenge(c)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 		NewResponse(w).Status(201).WriteJSON(resp)
 	case "GET":
-		itemList, err := store.New(dbConfig.file, dbConfig.bucket).
+		opts, err := listOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		page, err := store.New(dbConfig.file, dbConfig.bucket).
 			Kind(types.ChallengeKind).
 			Resources(strings.ToLower(types.ChallengeKind)).
-			List(regexp.MustCompile(`^\/challenge`))
+			ListPage(regexp.MustCompile(`^\/challenge\/[^/]+$`), opts)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		items := types.ChallengeList{}
-		for _, obj := range itemList {
+		items := types.ChallengeList{ListMeta: newListMeta(page)}
+		for _, obj := range page.Items {
 			c := obj.(*types.Challenge)
 			items.Items = append(items.Items, *c)
 		}
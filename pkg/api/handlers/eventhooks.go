@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kubeplay/gameserver/pkg/hooks"
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// maxHookDeliveries is how many deliveries are kept in the status of a hook
+const maxHookDeliveries = 20
+
+// hookStatusLock serializes the updates of the deliveries of the hooks
+var hookStatusLock sync.Mutex
+
+var EventHook = eventHook{}
+
+type eventHook struct{}
+
+func (c *eventHook) HandlerList() HandlerFn {
+	return eventHookListHandler
+}
+
+func (c *eventHook) Handler() HandlerFn {
+	return eventHookHandler
+}
+
+func (c *eventHook) Middlewares() []mux.MiddlewareFunc {
+	return []mux.MiddlewareFunc{}
+}
+
+func eventHookHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	switch r.Method {
+	case "DELETE":
+		err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.EventHookKind).
+			Resources(strings.ToLower(types.EventHookKind)).
+			Delete(params["resourceName"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(204)
+	case "GET":
+		obj, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.EventHookKind).
+			Resources(strings.ToLower(types.EventHookKind)).
+			Get(params["resourceName"])
+		if err != nil {
+			http.Error(w, err.Error(), storeErrorStatus(err))
+			return
+		}
+		NewResponse(w).WriteJSON(obj)
+	case "PUT":
+		new, ok := context.Get(r, "payload").(*types.EventHook)
+		if !ok {
+			http.Error(w, "unknown type found", http.StatusBadRequest)
+			return
+		}
+		if err := validateEventHook(new); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		hookStatusLock.Lock()
+		defer hookStatusLock.Unlock()
+		obj, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.EventHookKind).
+			Resources(strings.ToLower(types.EventHookKind)).
+			Get(params["resourceName"])
+		if err != nil {
+			http.Error(w, err.Error(), storeErrorStatus(err))
+			return
+		}
+		old := obj.(*types.EventHook)
+		// the secret is write-only, keep it when it's omitted
+		if new.Secret == "" {
+			new.Secret = old.Secret
+		}
+		new.Status = old.Status
+		obj, err = store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.EventHookKind).
+			Resources(
+				strings.ToLower(types.EventHookKind),
+				params["resourceName"],
+			).Update(old, new)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		NewResponse(w).WriteJSON(obj)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+func eventHookListHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		h, ok := context.Get(r, "payload").(*types.EventHook)
+		if !ok {
+			http.Error(w, "unknown type found", http.StatusBadRequest)
+			return
+		}
+		if err := validateEventHook(h); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.Status = types.EventHookStatus{}
+		resp, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.EventHookKind).
+			Resources(strings.ToLower(types.EventHookKind), h.Name).
+			SaveObject(h)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		NewResponse(w).Status(201).WriteJSON(resp)
+	case "GET":
+		sel, err := listSelector(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		items, err := listEventHooks()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		itemList := types.EventHookList{}
+		for _, h := range items {
+			if !sel.Matches(&h) {
+				continue
+			}
+			itemList.Items = append(itemList.Items, h)
+		}
+		itemList.Kind = "List"
+		NewResponse(w).WriteJSON(&itemList)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+func validateEventHook(h *types.EventHook) error {
+	u, err := url.Parse(h.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid url %q, expected an http(s) URL", h.URL)
+	}
+	for _, t := range h.Triggers {
+		switch t {
+		case types.HookGameCreated, types.HookGameStarted, types.HookKeySolved, types.HookGameCompleted, types.HookGameExpired:
+		default:
+			return fmt.Errorf("unknown trigger %q", t)
+		}
+	}
+	_, err = hooks.Render(h.Template, &hooks.Payload{})
+	return err
+}
+
+func listEventHooks() ([]types.EventHook, error) {
+	items, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.EventHookKind).
+		Resources(strings.ToLower(types.EventHookKind)).
+		List(regexp.MustCompile(`^\/eventhook\/`))
+	if err != nil {
+		return nil, err
+	}
+	var hookList []types.EventHook
+	for _, obj := range items {
+		hookList = append(hookList, *obj.(*types.EventHook))
+	}
+	return hookList, nil
+}
+
+// notifyHooks delivers the trigger to the matching hooks in the background,
+// the requests of the players never wait for the deliveries.
+func notifyHooks(trigger types.HookTrigger, event string, gm *types.Game, key string) {
+	hookList, err := listEventHooks()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed listing event hooks")
+		return
+	}
+	for _, h := range hookList {
+		if !hooks.Matches(&h, trigger, event) {
+			continue
+		}
+		p := hooks.NewPayload(store.NewUUID(), trigger, event, gm, key)
+		go func(h types.EventHook) {
+			d := hooks.Deliver(&h, p)
+			if !d.Delivered {
+				logrus.WithFields(logrus.Fields{
+					"hook":     h.Name,
+					"trigger":  trigger,
+					"attempts": d.Attempts,
+				}).Warnf("Failed delivering event hook: %s", d.Error)
+			}
+			if err := recordHookDelivery(h.Name, d); err != nil {
+				logrus.WithError(err).WithField("hook", h.Name).Warn("Failed recording event hook delivery")
+			}
+		}(h)
+	}
+}
+
+func recordHookDelivery(name string, d types.HookDelivery) error {
+	hookStatusLock.Lock()
+	defer hookStatusLock.Unlock()
+	s := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.EventHookKind).
+		Resources(strings.ToLower(types.EventHookKind))
+	obj, err := s.Get(name)
+	if err != nil {
+		return err
+	}
+	h := obj.(*types.EventHook)
+	h.Status.Deliveries = append(h.Status.Deliveries, d)
+	if n := len(h.Status.Deliveries); n > maxHookDeliveries {
+		h.Status.Deliveries = h.Status.Deliveries[n-maxHookDeliveries:]
+	}
+	_, err = s.Update(h, h)
+	return err
+}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/kubeplay/gameserver/pkg/store"
 
@@ -46,12 +48,30 @@ func eventHandler(w http.ResponseWriter, r *http.Request) {
 			Resources(strings.ToLower(types.EventKind)).
 			Get(params["resourceName"])
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), storeErrorStatus(err))
 			return
 		}
 		NewResponse(w).WriteJSON(obj)
 	case "DELETE":
-		err := store.New(dbConfig.file, dbConfig.bucket).
+		policy, err := deletionPolicy(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, err = store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.EventKind).
+			Resources(strings.ToLower(types.EventKind)).
+			Get(params["resourceName"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		// the games are deleted one by one to destroy their environments
+		if status, err := deleteDependents(policy, types.EventKind, params["resourceName"]); err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		err = store.New(dbConfig.file, dbConfig.bucket).
 			Kind(types.EventKind).
 			Resources(strings.ToLower(types.EventKind)).
 			Delete(params["resourceName"])
@@ -73,6 +93,13 @@ func eventListHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "unknown type found", http.StatusBadRequest)
 			return
 		}
+		if ev.GameTimeout != "" {
+			if d, err := time.ParseDuration(ev.GameTimeout); err != nil || d <= 0 {
+				msg := fmt.Sprintf("invalid gameTimeout %q, expected a positive duration, e.g.: 2h", ev.GameTimeout)
+				http.Error(w, msg, http.StatusBadRequest)
+				return
+			}
+		}
 		resp, err := store.New(dbConfig.file, dbConfig.bucket).
 			Resources(strings.ToLower(types.EventKind), ev.Name).
 			SaveObject(ev)
@@ -82,16 +109,21 @@ func eventListHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		NewResponse(w).Status(201).WriteJSON(resp)
 	case "GET":
-		items, err := store.New(dbConfig.file, dbConfig.bucket).
+		opts, err := listOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		page, err := store.New(dbConfig.file, dbConfig.bucket).
 			Kind(types.EventKind).
 			Resources(strings.ToLower(types.EventKind)).
-			List(regexp.MustCompile(`\/event\/[a-z0-9-]+$`))
+			ListPage(regexp.MustCompile(`\/event\/[a-z0-9-]+$`), opts)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		itemList := types.EventList{}
-		for _, obj := range items {
+		itemList := types.EventList{ListMeta: newListMeta(page)}
+		for _, obj := range page.Items {
 			ev := obj.(*types.Event)
 			itemList.Items = append(itemList.Items, *ev)
 		}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// gameDeadline returns when a running game expires, games of events without
+// a timeout never expire.
+func gameDeadline(ev *types.Event, gm *types.Game) (time.Time, bool) {
+	if ev.GameTimeout == "" || gm.Status.Phase != types.GameRunning {
+		return time.Time{}, false
+	}
+	timeout, err := time.ParseDuration(ev.GameTimeout)
+	if err != nil {
+		return time.Time{}, false
+	}
+	startTime, err := time.Parse(time.RFC3339, gm.Status.StartTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return startTime.Add(timeout), true
+}
+
+// errGameNotExpired skips the update of a game which changed before expiring it
+var errGameNotExpired = errors.New("the game isn't expired")
+
+// isGameExpired verifies if the game ran longer than the timeout of the event
+func isGameExpired(ev *types.Event, gm *types.Game, now time.Time) bool {
+	deadline, ok := gameDeadline(ev, gm)
+	return ok && !now.Before(deadline)
+}
+
+// ExpireGames moves the running games past the timeout of their events to the
+// expired phase and notifies the hooks. It returns the number of expired games.
+func ExpireGames(now time.Time) (int, error) {
+	page, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.GameKind).
+		Resources(strings.ToLower(types.EventKind)).
+		ListPage(gameKeyRe, store.ListOptions{
+			Index:      store.GamePhaseIndex,
+			IndexValue: string(types.GameRunning),
+		})
+	if err != nil {
+		return 0, err
+	}
+	events := map[string]*types.Event{}
+	expired := 0
+	for i, obj := range page.Items {
+		eventName := gameKeyRe.FindStringSubmatch(page.Keys[i])[1]
+		ev, ok := events[eventName]
+		if !ok {
+			obj, err := store.New(dbConfig.file, dbConfig.bucket).
+				Kind(types.EventKind).
+				Resources(strings.ToLower(types.EventKind)).
+				Get(eventName)
+			if err != nil && !store.IsNotFound(err) {
+				return expired, err
+			}
+			// games of a removed event are kept as they are
+			if err == nil {
+				ev = obj.(*types.Event)
+			}
+			events[eventName] = ev
+		}
+		gm := obj.(*types.Game)
+		if ev == nil || !isGameExpired(ev, gm, now) {
+			continue
+		}
+		// the game is checked again in the update, it could be solved or
+		// deleted after it was listed
+		s := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.GameKind).
+			Resources(
+				strings.ToLower(types.EventKind),
+				eventName,
+				strings.ToLower(types.GameKind),
+			)
+		updated, err := s.UpdateFunc(gm.Name, func(obj types.Object) error {
+			current := obj.(*types.Game)
+			if current.Status.Phase != types.GameRunning || !isGameExpired(ev, current, now) {
+				return errGameNotExpired
+			}
+			current.Status.Phase = types.GameExpired
+			current.Status.EndTime = now.UTC().Format(time.RFC3339)
+			return nil
+		})
+		if err == errGameNotExpired || store.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		gm = updated.(*types.Game)
+		logrus.WithFields(logrus.Fields{
+			"event":  eventName,
+			"game":   gm.Name,
+			"player": gm.Player,
+		}).Info("Game expired")
+		notifyHooks(types.HookGameExpired, eventName, gm, "")
+		expired++
+	}
+	return expired, nil
+}
+
+// ExpireGamesEvery expires the games now and then on every interval, it
+// returns when the stop channel is closed.
+func ExpireGamesEvery(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := ExpireGames(time.Now()); err != nil {
+			logrus.WithError(err).Warn("Failed expiring games")
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
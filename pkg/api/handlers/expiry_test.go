@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+func saveEvent(t *testing.T, name, gameTimeout string) {
+	ev := &types.Event{
+		TypeMeta:    types.TypeMeta{Kind: types.EventKind},
+		Metadata:    types.Metadata{Name: name},
+		GameTimeout: gameTimeout,
+	}
+	_, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.EventKind).
+		Resources(strings.ToLower(types.EventKind), name).
+		SaveObject(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func saveRunningGame(t *testing.T, event, name string, startTime time.Time) {
+	gm := &types.Game{
+		TypeMeta:  types.TypeMeta{Kind: types.GameKind},
+		Metadata:  types.Metadata{Name: name},
+		Challenge: "foo",
+		Player:    "github|alice",
+		Status: types.GameStatus{
+			Phase:     types.GameRunning,
+			StartTime: startTime.UTC().Format(time.RFC3339),
+		},
+	}
+	_, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.GameKind).
+		Resources(strings.ToLower(types.EventKind), event, strings.ToLower(types.GameKind), name).
+		SaveObject(gm)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func gamePhase(t *testing.T, event, name string) types.GamePhase {
+	obj, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.GameKind).
+		Resources(strings.ToLower(types.EventKind), event, strings.ToLower(types.GameKind)).
+		Get(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj.(*types.Game).Status.Phase
+}
+
+func TestExpireGames(t *testing.T) {
+	defer setupDatabase(t)()
+	now := time.Now()
+	saveEvent(t, "meetup", "1h")
+	saveEvent(t, "workshop", "")
+	saveRunningGame(t, "meetup", "late", now.Add(-2*time.Hour))
+	saveRunningGame(t, "meetup", "on-time", now.Add(-30*time.Minute))
+	saveRunningGame(t, "workshop", "late", now.Add(-48*time.Hour))
+	// games of removed events are kept
+	saveRunningGame(t, "removed", "late", now.Add(-48*time.Hour))
+
+	expired, err := ExpireGames(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expired != 1 {
+		t.Errorf("expected 1 expired game, got %d", expired)
+	}
+	for _, tc := range []struct {
+		event string
+		game  string
+		want  types.GamePhase
+	}{
+		{event: "meetup", game: "late", want: types.GameExpired},
+		{event: "meetup", game: "on-time", want: types.GameRunning},
+		{event: "workshop", game: "late", want: types.GameRunning},
+		{event: "removed", game: "late", want: types.GameRunning},
+	} {
+		if got := gamePhase(t, tc.event, tc.game); got != tc.want {
+			t.Errorf("expected the game %s/%s to be %s, got %s", tc.event, tc.game, tc.want, got)
+		}
+	}
+	// expired games aren't running anymore
+	if expired, err := ExpireGames(now.Add(time.Hour)); err != nil || expired != 1 {
+		t.Errorf("expected only the game on time to expire later, got %d: %v", expired, err)
+	}
+}
+
+func TestExpireGamesEveryStops(t *testing.T) {
+	defer setupDatabase(t)()
+	saveEvent(t, "meetup", "1h")
+	saveRunningGame(t, "meetup", "late", time.Now().Add(-2*time.Hour))
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		ExpireGamesEvery(time.Hour, stop)
+		close(done)
+	}()
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the job to return after stopping it")
+	}
+	// the first run isn't skipped
+	if got := gamePhase(t, "meetup", "late"); got != types.GameExpired {
+		t.Errorf("expected the game to be expired, got %s", got)
+	}
+}
+
+func TestIsGameExpired(t *testing.T) {
+	start := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	gm := &types.Game{Status: types.GameStatus{Phase: types.GameRunning, StartTime: start.Format(time.RFC3339)}}
+	for _, tc := range []struct {
+		name    string
+		timeout string
+		phase   types.GamePhase
+		now     time.Time
+		want    bool
+	}{
+		{name: "before the timeout", timeout: "1h", now: start.Add(59 * time.Minute)},
+		{name: "at the timeout", timeout: "1h", now: start.Add(time.Hour), want: true},
+		{name: "without timeout", now: start.Add(time.Hour)},
+		{name: "completed games", timeout: "1h", phase: types.GameCompleted, now: start.Add(time.Hour)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := *gm
+			if tc.phase != "" {
+				g.Status.Phase = tc.phase
+			}
+			if got := isGameExpired(&types.Event{GameTimeout: tc.timeout}, &g, tc.now); got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
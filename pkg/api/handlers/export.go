@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kubeplay/gameserver/pkg/selector"
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+	"github.com/kubeplay/gameserver/pkg/utils"
+)
+
+var Export = export{}
+
+type export struct{}
+
+func (e *export) HandlerExport() HandlerFn {
+	return exportHandler
+}
+
+func (e *export) HandlerImport() HandlerFn {
+	return importHandler
+}
+
+func (e *export) Middlewares() []mux.MiddlewareFunc {
+	return []mux.MiddlewareFunc{}
+}
+
+// challengeExportRe matches the challenges and their revisions in the order of their keys
+var challengeExportRe = regexp.MustCompile(`^\/challenge\/[^/]+(\/revision\/[0-9]+)?$`)
+
+// exportHandler dumps the objects of every exported kind as multi-document YAML,
+// the secrets (passwords and hook secrets) and the sessions aren't exported.
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "# kubeplay export %s\n", time.Now().UTC().Format(time.RFC3339))
+		for _, kind := range types.ExportKinds {
+			objs, err := exportObjects(kind)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			for _, obj := range objs {
+				data, err := utils.ObjectToYaml(obj)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				buf.WriteString("---\n")
+				buf.Write(data)
+			}
+		}
+		w.Header().Set("Content-Type", types.ExportMediaType)
+		w.Write(buf.Bytes())
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+// importHandler stores the objects of an export as they are, keeping their
+// status (e.g.: the keys solved by the players). The objects are imported in
+// the order of their kinds and the existing ones are skipped.
+func importHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		if ct := r.Header.Get("Content-Type"); ct != types.ExportMediaType {
+			msg := fmt.Sprintf("unsupported content type %q, expected %q", ct, types.ExportMediaType)
+			http.Error(w, msg, http.StatusUnsupportedMediaType)
+			return
+		}
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed reading body: %v", err), http.StatusBadRequest)
+			return
+		}
+		objs, err := decodeExport(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result := &types.ImportResult{TypeMeta: types.TypeMeta{Kind: "ImportResult"}}
+		syncPolicies := false
+		for _, obj := range objs {
+			keys := importKeys(obj)
+			if u, ok := obj.(*types.User); ok && u.Password != "" {
+				if err := hashUserPassword(u); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			created, err := store.New(dbConfig.file, dbConfig.bucket).
+				Kind(obj.GetObjectKind()).
+				Resources(keys...).
+				ImportObject(obj)
+			if err != nil {
+				msg := fmt.Sprintf("failed importing %s, %d object(s) were created: %v", strings.Join(keys, "/"), len(result.Created), err)
+				http.Error(w, msg, http.StatusInternalServerError)
+				return
+			}
+			if !created {
+				result.Skipped = append(result.Skipped, strings.Join(keys, "/"))
+				continue
+			}
+			result.Created = append(result.Created, strings.Join(keys, "/"))
+			switch obj.GetObjectKind() {
+			case types.RoleKind, types.RoleBindingKind, types.PolicyKind:
+				syncPolicies = true
+			}
+		}
+		if syncPolicies {
+			if err := SyncPolicies(); err != nil {
+				logrus.WithError(err).Warn("Failed syncing imported policies")
+			}
+		}
+		logrus.WithFields(logrus.Fields{
+			"created": len(result.Created),
+			"skipped": len(result.Skipped),
+		}).Info("Imported objects")
+		NewResponse(w).WriteJSON(result)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+// exportObjects lists the objects of a kind, the games have their event and
+// the revisions of the challenges have the revision annotation.
+func exportObjects(kind string) ([]types.Object, error) {
+	resource := strings.ToLower(kind)
+	re := regexp.MustCompile(fmt.Sprintf(`^\/%s\/[^/]+$`, resource))
+	switch kind {
+	case types.GameKind:
+		resource, re = strings.ToLower(types.EventKind), gameKeyRe
+	case types.ChallengeKind:
+		re = challengeExportRe
+	}
+	page, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(kind).
+		Resources(resource).
+		ListPage(re, store.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i, obj := range page.Items {
+		removeSecrets(obj)
+		switch o := obj.(type) {
+		case *types.Game:
+			o.Event = gameKeyRe.FindStringSubmatch(page.Keys[i])[1]
+		case *types.Challenge:
+			if challengeRevisionRe.MatchString(page.Keys[i]) {
+				if o.Annotations == nil {
+					o.Annotations = map[string]string{}
+				}
+				o.Annotations[types.RevisionAnnotation] = strconv.FormatInt(o.Generation, 10)
+			}
+		}
+	}
+	return page.Items, nil
+}
+
+// decodeExport decodes the documents of an export sorted by the order of
+// their kinds, the games must belong to an existing or an imported event.
+func decodeExport(data []byte) ([]types.Object, error) {
+	docs, err := utils.YamlDocumentsToJson(data)
+	if err != nil {
+		return nil, err
+	}
+	var objs []types.Object
+	events := map[string]bool{}
+	for i, doc := range docs {
+		meta := &types.TypeMeta{}
+		if err := json.Unmarshal(doc, meta); err != nil {
+			return nil, fmt.Errorf("document %d: %v", i+1, err)
+		}
+		if exportOrder(meta.Kind) < 0 {
+			return nil, fmt.Errorf("document %d: kind %q couldn't be imported", i+1, meta.Kind)
+		}
+		// documents of the served versions are converted to the hub, the
+		// exports of previous versions like the stored objects
+		if v := types.LookupVersion(meta.APIVersion); v != nil && v.Name != types.HubVersion {
+			if doc, err = types.ConvertToHub(meta.APIVersion, meta.Kind, doc); err != nil {
+				return nil, fmt.Errorf("document %d: %v", i+1, err)
+			}
+		}
+		doc, _, err := types.ConvertToStorageVersion(meta.Kind, doc)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %v", i+1, err)
+		}
+		obj, err := types.Decode(meta, doc)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %v", i+1, err)
+		}
+		m := obj.GetObjectMeta()
+		if m.Name == "" || strings.Contains(m.Name, "/") {
+			return nil, fmt.Errorf("document %d: invalid name %q", i+1, m.Name)
+		}
+		if err := selector.ValidateLabels(m.Labels); err != nil {
+			return nil, fmt.Errorf("document %d: %v", i+1, err)
+		}
+		switch o := obj.(type) {
+		case *types.Event:
+			events[o.Name] = true
+		case *types.Challenge:
+			if rev, ok := o.Annotations[types.RevisionAnnotation]; ok && rev != strconv.FormatInt(o.Generation, 10) {
+				return nil, fmt.Errorf("document %d: the revision %q of the challenge %q doesn't match its generation", i+1, rev, o.Name)
+			}
+		}
+		objs = append(objs, obj)
+	}
+	for _, obj := range objs {
+		gm, ok := obj.(*types.Game)
+		if !ok || events[gm.Event] {
+			continue
+		}
+		if gm.Event == "" || strings.Contains(gm.Event, "/") {
+			return nil, fmt.Errorf("game %q: invalid event %q", gm.Name, gm.Event)
+		}
+		_, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.EventKind).
+			Resources(strings.ToLower(types.EventKind)).
+			Get(gm.Event)
+		if err != nil {
+			return nil, fmt.Errorf("game %q: the event %q doesn't exist", gm.Name, gm.Event)
+		}
+		events[gm.Event] = true
+	}
+	sort.SliceStable(objs, func(i, j int) bool {
+		return exportOrder(objs[i].GetObjectKind()) < exportOrder(objs[j].GetObjectKind())
+	})
+	return objs, nil
+}
+
+// importKeys returns where an imported object is stored, e.g.: event/meetup/game/foo.
+// The revision annotation is removed from the revisions of the challenges.
+func importKeys(obj types.Object) []string {
+	meta := obj.GetObjectMeta()
+	switch o := obj.(type) {
+	case *types.Game:
+		return []string{strings.ToLower(types.EventKind), o.Event, strings.ToLower(types.GameKind), o.Name}
+	case *types.Challenge:
+		if rev, ok := o.Annotations[types.RevisionAnnotation]; ok {
+			delete(o.Annotations, types.RevisionAnnotation)
+			if len(o.Annotations) == 0 {
+				o.Annotations = nil
+			}
+			return []string{strings.ToLower(types.ChallengeKind), o.Name, revisionResource, rev}
+		}
+	}
+	return []string{strings.ToLower(obj.GetObjectKind()), meta.Name}
+}
+
+func exportOrder(kind string) int {
+	for i, k := range types.ExportKinds {
+		if k == kind {
+			return i
+		}
+	}
+	return -1
+}
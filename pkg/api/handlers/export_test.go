@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+func saveObject(t *testing.T, obj types.Object, keys ...string) {
+	_, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(obj.GetObjectKind()).
+		Resources(keys...).
+		SaveObject(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func serveImport(t *testing.T, contentType string, data []byte) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", "/v1/import", bytes.NewReader(data))
+	r.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	importHandler(w, r)
+	return w
+}
+
+func TestExportAndImport(t *testing.T) {
+	teardown := setupOwners(t)
+	if _, err := updateChallenge(newChallenge("foo", "v1"), newChallenge("foo", "v2")); err != nil {
+		t.Fatal(err)
+	}
+	saveObject(t, &types.User{
+		TypeMeta:     types.TypeMeta{Kind: types.UserKind},
+		Metadata:     types.Metadata{Name: "alice"},
+		PasswordHash: "$2a$10$secret",
+	}, "user", "alice")
+	saveObject(t, &types.EventHook{
+		TypeMeta: types.TypeMeta{Kind: types.EventHookKind},
+		Metadata: types.Metadata{Name: "slack"},
+		URL:      "https://hooks.example.com",
+		Secret:   "secret",
+	}, "eventhook", "slack")
+	saveRoleBinding(t, "alice-meetup", "host", "local|alice", "meetup")
+
+	w := httptest.NewRecorder()
+	exportHandler(w, httptest.NewRequest("GET", "/v1/export", nil))
+	teardown()
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != types.ExportMediaType {
+		t.Fatalf("expected an export, got %d %s: %s", w.Code, w.Header().Get("Content-Type"), w.Body)
+	}
+	export := w.Body.Bytes()
+	if bytes.Contains(export, []byte("secret")) {
+		t.Errorf("expected the secrets not to be exported, got %s", export)
+	}
+	if !bytes.Contains(export, []byte(types.RevisionAnnotation)) {
+		t.Errorf("expected the revisions of the challenges in the export, got %s", export)
+	}
+
+	// the export is imported in an empty database
+	defer setupDatabase(t)()
+	if err := store.New(dbConfig.file, dbConfig.bucket).Init(); err != nil {
+		t.Fatal(err)
+	}
+	w = serveImport(t, types.ExportMediaType, export)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status 200, got %d: %s", w.Code, w.Body)
+	}
+	var result types.ImportResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"challenge/foo",
+		"challenge/foo/revision/1",
+		"challenge/foo/revision/2",
+		"event/meetup",
+		"event/meetup/game/g1",
+		"event/meetup/game/g2",
+		"event/meetup/game/legacy",
+		"rolebinding/alice-meetup",
+		"eventhook/slack",
+		"user/alice",
+	}
+	if !reflect.DeepEqual(result.Created, want) || len(result.Skipped) != 0 {
+		t.Errorf("expected the objects in the order of their kinds %v, got %v skipped %v", want, result.Created, result.Skipped)
+	}
+	for generation, key := range map[int64]string{1: "v1", 2: "v2"} {
+		c, err := getChallengeRevision("foo", generation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Keys["k1"].Value != key || c.Annotations[types.RevisionAnnotation] != "" {
+			t.Errorf("revision %d: expected the key %s without the annotation, got %+v", generation, key, c)
+		}
+	}
+	if gm := getGame(t, "g1"); gm == nil || !gm.IsOwnedBy(types.ChallengeKind, "foo") {
+		t.Errorf("expected the game with its owners, got %+v", gm)
+	}
+	// the imported bindings are synced
+	allowed, err := Authorize(&types.PlayerClaims{Login: "alice", Provider: types.LocalProvider}, "/v1/events/meetup", "DELETE")
+	if err != nil || !allowed {
+		t.Errorf("expected the imported role binding to be synced: %v", err)
+	}
+
+	// existing objects aren't replaced
+	w = serveImport(t, types.ExportMediaType, export)
+	result = types.ImportResult{}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Created) != 0 || !reflect.DeepEqual(result.Skipped, want) {
+		t.Errorf("expected every object to be skipped, got %v created %v", result.Skipped, result.Created)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	defer setupDatabase(t)()
+	if err := store.New(dbConfig.file, dbConfig.bucket).Init(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		data        string
+		wantStatus  int
+		wantErr     string
+	}{
+		{
+			name:        "content type",
+			contentType: "application/json",
+			data:        "{}",
+			wantStatus:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:       "kinds not exported",
+			data:       "kind: Session\nmetadata: {name: s1}\n",
+			wantStatus: http.StatusBadRequest,
+			wantErr:    `document 1: kind "Session" couldn't be imported`,
+		},
+		{
+			name:       "invalid name",
+			data:       "kind: Event\nmetadata: {name: a/b}\n---\nkind: Event\nmetadata: {name: meetup}\n",
+			wantStatus: http.StatusBadRequest,
+			wantErr:    `document 1: invalid name "a/b"`,
+		},
+		{
+			name:       "game without event",
+			data:       "kind: Game\nmetadata: {name: g1}\nevent: missing\nchallenge: foo\n",
+			wantStatus: http.StatusBadRequest,
+			wantErr:    `the event "missing" doesn't exist`,
+		},
+		{
+			name:       "revision of another generation",
+			data:       "kind: Challenge\nmetadata: {name: foo, generation: 2, annotations: {kubeplay.io/revision: '1'}}\n",
+			wantStatus: http.StatusBadRequest,
+			wantErr:    "doesn't match its generation",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = types.ExportMediaType
+			}
+			w := serveImport(t, contentType, []byte(tc.data))
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected the status %d, got %d: %s", tc.wantStatus, w.Code, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tc.wantErr) {
+				t.Errorf("expected an error containing %q, got %s", tc.wantErr, w.Body)
+			}
+		})
+	}
+	// invalid exports don't import any object
+	objs, err := exportObjects(types.EventKind)
+	if err != nil || len(objs) != 0 {
+		t.Errorf("expected no objects, got %d: %v", len(objs), err)
+	}
+}
@@ -13,14 +13,23 @@ import (
 
 	"github.com/gorilla/context"
 	"github.com/gorilla/mux"
+	"github.com/kubeplay/gameserver/pkg/audit"
+	"github.com/kubeplay/gameserver/pkg/metrics"
 	"github.com/kubeplay/gameserver/pkg/store"
 	"github.com/kubeplay/gameserver/pkg/types"
 )
 
+// gameKeyRe matches the keys of the games of every event, e.g.: /event/<event>/game/<name>
+var gameKeyRe = regexp.MustCompile(`^\/event\/([^/]+)\/game\/[^/]+$`)
+
 func (c *event) HandlerGameList() HandlerFn {
 	return gameListHandler
 }
 
+func (c *event) HandlerAllGamesList() HandlerFn {
+	return allGamesListHandler
+}
+
 func (c *event) HandlerGame() HandlerFn {
 	return gameHandler
 }
@@ -45,14 +54,36 @@ func gameHandler(w http.ResponseWriter, r *http.Request) {
 				strings.ToLower(types.GameKind),
 			).Get(params["resourceName"])
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), storeErrorStatus(err))
 			return
 		}
+		// games created before the event was recorded
+		obj.(*types.Game).Event = params["parent"]
 		NewResponse(w).WriteJSON(obj)
+	case "DELETE":
+		s := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.GameKind).
+			Resources(
+				strings.ToLower(types.EventKind),
+				params["parent"],
+				strings.ToLower(types.GameKind),
+			)
+		obj, err := s.Get(params["resourceName"])
+		if err != nil {
+			http.Error(w, err.Error(), storeErrorStatus(err))
+			return
+		}
+		// keep the game when the environment couldn't be destroyed, deleting it again retries
+		if err := deleteGame(params["parent"], obj.(*types.Game)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(204)
 	default:
 		http.Error(w, "Not Implemented", http.StatusNotImplemented)
 	}
 }
+
 func gameStartHandler(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	switch r.Method {
@@ -66,7 +97,7 @@ func gameStartHandler(w http.ResponseWriter, r *http.Request) {
 			)
 		obj, err := s.Get(params["resourceName"])
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), storeErrorStatus(err))
 			return
 		}
 		gm := obj.(*types.Game)
@@ -74,13 +105,26 @@ func gameStartHandler(w http.ResponseWriter, r *http.Request) {
 			NewResponse(w).WriteJSON(obj)
 			return
 		}
+		if gm.Status.Phase == types.GameExpired {
+			http.Error(w, "the game expired", http.StatusConflict)
+			return
+		}
+		// games with a provisioner start when their environment is ready
+		if p := gm.Status.Provisioning; p != nil && p.Phase != types.ProvisioningReady {
+			msg := fmt.Sprintf("the environment of the game isn't ready, provisioning phase: %s", p.Phase)
+			http.Error(w, msg, http.StatusConflict)
+			return
+		}
 		gm.Status.StartTime = time.Now().UTC().Format(time.RFC3339)
 		gm.Status.Phase = types.GameRunning
+		audit.Annotate(r, "challenge", gm.Challenge)
+		audit.Annotate(r, "player", gm.Player)
 		obj, err = s.Update(gm, gm)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		notifyHooks(types.HookGameStarted, params["parent"], gm, "")
 		NewResponse(w).WriteJSON(obj)
 	default:
 		http.Error(w, "Not Implemented", http.StatusNotImplemented)
@@ -102,7 +146,7 @@ func gameSolveHandler(w http.ResponseWriter, r *http.Request) {
 			Resources(strings.ToLower(types.EventKind)).
 			Get(params["parent"])
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), storeErrorStatus(err))
 			return
 		}
 		// The event must be active to approve keys
@@ -119,7 +163,7 @@ func gameSolveHandler(w http.ResponseWriter, r *http.Request) {
 				strings.ToLower(types.GameKind),
 			).Get(params["resourceName"])
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), storeErrorStatus(err))
 			return
 		}
 		// The game must be running to approve keys
@@ -128,19 +172,26 @@ func gameSolveHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "The game isn't running", http.StatusBadRequest)
 			return
 		}
-		obj, err = store.New(dbConfig.file, dbConfig.bucket).
-			Kind(types.ChallengeKind).
-			Resources(strings.ToLower(types.ChallengeKind)).
-			Get(gm.Challenge)
+		// the games expire on an interval, the keys are refused right after the timeout
+		if isGameExpired(ev, gm, time.Now()) {
+			http.Error(w, "The game expired", http.StatusBadRequest)
+			return
+		}
+		// keys are validated against the revision the game was created with
+		chl, err := getChallengeRevision(gm.Challenge, gm.ChallengeGeneration)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		chl := obj.(*types.Challenge)
+		audit.Annotate(r, "challenge", chl.Name)
+		audit.Annotate(r, "player", gm.Player)
 		for keyName, key := range chl.Keys {
 			if ok := cli.SolveGameKey(gameKeyHash, gm.UID, keyName, key); ok {
 				for _, status := range gm.Status.Keys {
 					if status.KeyName == keyName {
+						metrics.SolveAttempts.WithLabelValues(params["parent"], metrics.SolveDuplicate).Inc()
+						audit.Annotate(r, "key", keyName)
+						audit.Annotate(r, "result", metrics.SolveDuplicate)
 						logrus.WithField("key", keyName).Warn("Key already validated, noop")
 						NewResponse(w).WriteJSON(gm)
 						return
@@ -154,6 +205,11 @@ func gameSolveHandler(w http.ResponseWriter, r *http.Request) {
 				}
 				gm.Status.Keys = append(gm.Status.Keys, gameStatus)
 				gm.Status.LastSolvedKey = gameStatus
+				metrics.SolveAttempts.WithLabelValues(params["parent"], metrics.SolveAccepted).Inc()
+				metrics.KeysSolved.WithLabelValues(chl.Name, keyName).Inc()
+				audit.Annotate(r, "key", keyName)
+				audit.Annotate(r, "result", metrics.SolveAccepted)
+				audit.Annotate(r, "approvedAt", gameStatus.ApprovedAt)
 				// All keys are validated, means the player completed the game!
 				if len(chl.Keys) == len(gm.Status.Keys) {
 					gm.Status.Phase = types.GameCompleted
@@ -167,10 +223,20 @@ func gameSolveHandler(w http.ResponseWriter, r *http.Request) {
 						strings.ToLower(types.GameKind),
 						gm.Name,
 					).Update(gm, gm)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				notifyHooks(types.HookKeySolved, params["parent"], gm, keyName)
+				if gm.Status.Phase == types.GameCompleted {
+					notifyHooks(types.HookGameCompleted, params["parent"], gm, "")
+				}
 				NewResponse(w).WriteJSON(gm)
 				return
 			}
 		}
+		metrics.SolveAttempts.WithLabelValues(params["parent"], metrics.SolveRejected).Inc()
+		audit.Annotate(r, "result", metrics.SolveRejected)
 		http.Error(w, "Key not validated", http.StatusForbidden)
 	default:
 		http.Error(w, "Not Implemented", http.StatusNotImplemented)
@@ -202,12 +268,12 @@ func gameListHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		c := obj.(*types.Challenge)
-		_, err = store.New(dbConfig.file, dbConfig.bucket).
+		ev, err := store.New(dbConfig.file, dbConfig.bucket).
 			Kind(types.EventKind).
 			Resources(strings.ToLower(types.EventKind)).
 			Get(params["parent"])
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), storeErrorStatus(err))
 			return
 		}
 		logrus.WithFields(logrus.Fields{
@@ -219,7 +285,16 @@ func gameListHandler(w http.ResponseWriter, r *http.Request) {
 			Phase:          types.GamePending,
 			RegisteredKeys: len(c.Keys),
 		}
+		gm.Event = params["parent"]
 		gm.Player = pl.Username()
+		gm.ChallengeGeneration = c.Generation
+		gm.OwnerReferences = []types.OwnerReference{
+			types.NewOwnerReference(ev),
+			types.NewOwnerReference(c),
+		}
+		if c.Provisioner != nil {
+			gm.Status.Provisioning = newProvisioningStatus(c.Provisioner.Name, types.ProvisioningInProgress, "")
+		}
 		resp, err := store.New(dbConfig.file, dbConfig.bucket).
 			Kind(types.GameKind).
 			Resources(
@@ -232,22 +307,33 @@ func gameListHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		notifyHooks(types.HookGameCreated, params["parent"], gm, "")
+		if c.Provisioner != nil {
+			saved := *resp.(*types.Game)
+			go provisionGame(params["parent"], &saved, c)
+		}
 		NewResponse(w).Status(201).WriteJSON(resp)
 	case "GET":
-		items, err := store.New(dbConfig.file, dbConfig.bucket).
+		opts, err := gameListOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		page, err := store.New(dbConfig.file, dbConfig.bucket).
 			Kind(types.GameKind).
 			Resources(
 				strings.ToLower(types.EventKind),
 				params["parent"],
 				strings.ToLower(types.GameKind),
-			).List(regexp.MustCompile(`^\/event\/[a-z0-9-]+\/game`))
+			).ListPage(regexp.MustCompile(`^\/event\/[a-z0-9-]+\/game`), opts)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		itemList := types.GameList{}
-		for _, obj := range items {
+		itemList := types.GameList{ListMeta: newListMeta(page)}
+		for _, obj := range page.Items {
 			gm := obj.(*types.Game)
+			gm.Event = params["parent"]
 			itemList.Items = append(itemList.Items, *gm)
 		}
 		itemList.Kind = "List"
@@ -258,6 +344,59 @@ func gameListHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// allGamesListHandler lists the games of every event, e.g.: the history of a player
+func allGamesListHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		opts, err := gameListOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		page, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.GameKind).
+			Resources(strings.ToLower(types.EventKind)).
+			ListPage(gameKeyRe, opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		itemList := types.GameList{ListMeta: newListMeta(page)}
+		for i, obj := range page.Items {
+			gm := obj.(*types.Game)
+			gm.Event = gameKeyRe.FindStringSubmatch(page.Keys[i])[1]
+			itemList.Items = append(itemList.Items, *gm)
+		}
+		itemList.Kind = "List"
+		NewResponse(w).WriteJSON(&itemList)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+// gameListOptions parses the options of a list of games, the games could be
+// filtered by their player, e.g.: ?player=github|user. The games of a player,
+// a challenge or a phase are read from the indexes of the store.
+func gameListOptions(r *http.Request) (store.ListOptions, error) {
+	opts, err := listOptions(r)
+	if err != nil {
+		return opts, err
+	}
+	if player := r.URL.Query().Get("player"); player != "" {
+		addMatch(&opts, func(obj types.Object) bool {
+			return obj.(*types.Game).Player == player
+		})
+		opts.Index, opts.IndexValue = store.GamePlayerIndex, player
+		return opts, nil
+	}
+	sel, err := listSelector(r)
+	if err != nil {
+		return opts, err
+	}
+	useIndex(&opts, types.GameKind, sel)
+	return opts, nil
+}
+
 // func validateGameKey(gameKeyHash, gameUID, keyName string, key types.Key) bool {
 // 	hash := hmac.New(sha256.New, []byte(key.Value))
 // 	hash.Write([]byte(gameUID))
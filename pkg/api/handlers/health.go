@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	apiauth "github.com/kubeplay/gameserver/pkg/api/auth"
+	"github.com/kubeplay/gameserver/pkg/store"
+)
+
+const readinessCheckTimeout = time.Second
+
+var Health = health{}
+
+type health struct {
+	// ready is set when the server finished starting and unset when it's shutting down
+	ready int32
+}
+
+func (h *health) HandlerLiveness() HandlerFn {
+	return livenessHandler
+}
+
+func (h *health) HandlerReadiness() HandlerFn {
+	return readinessHandler
+}
+
+// SetReady marks if the server is able to receive traffic
+func SetReady(ready bool) {
+	var v int32
+	if ready {
+		v = 1
+	}
+	atomic.StoreInt32(&Health.ready, v)
+}
+
+// livenessHandler answers as long as the process is able to serve requests
+func livenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+}
+
+// readinessHandler verifies the dependencies of the server, it fails when any
+// of the checks fail or the server is shutting down.
+func readinessHandler(w http.ResponseWriter, r *http.Request) {
+	checks := []struct {
+		name  string
+		check func() error
+	}{
+		{"started", func() error {
+			if atomic.LoadInt32(&Health.ready) == 0 {
+				return fmt.Errorf("the server is starting or shutting down")
+			}
+			return nil
+		}},
+		{"database", func() error {
+			return store.New(dbConfig.file, dbConfig.bucket).Ping(readinessCheckTimeout)
+		}},
+		{"policies", func() error {
+			_, err := apiauth.NewEnforcer(policiesFile)
+			return err
+		}},
+	}
+	var out bytes.Buffer
+	statusCode := http.StatusOK
+	for _, c := range checks {
+		if err := c.check(); err != nil {
+			statusCode = http.StatusServiceUnavailable
+			fmt.Fprintf(&out, "[-]%s failed: %v\n", c.name, err)
+			continue
+		}
+		fmt.Fprintf(&out, "[+]%s ok\n", c.name)
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(statusCode)
+	w.Write(out.Bytes())
+}
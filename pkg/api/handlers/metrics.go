@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	gamesDesc = prometheus.NewDesc(
+		"kubeplay_games",
+		"Number of games by event and phase.",
+		[]string{"event", "phase"}, nil,
+	)
+	activePlayersDesc = prometheus.NewDesc(
+		"kubeplay_active_players",
+		"Number of players with running games by event.",
+		[]string{"event"}, nil,
+	)
+)
+
+// gameCollector reads the games from the store on every scrape, the
+// values are always consistent with the database.
+type gameCollector struct{}
+
+// NewGameCollector returns a collector of the games and active players by event
+func NewGameCollector() prometheus.Collector {
+	return &gameCollector{}
+}
+
+func (c *gameCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- gamesDesc
+	ch <- activePlayersDesc
+}
+
+func (c *gameCollector) Collect(ch chan<- prometheus.Metric) {
+	events, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.EventKind).
+		Resources(strings.ToLower(types.EventKind)).
+		List(regexp.MustCompile(`\/event\/[a-z0-9-]+$`))
+	if err != nil {
+		logrus.WithError(err).Warn("Failed listing events for metrics")
+		return
+	}
+	// the games of each phase are counted by their keys in the phase index
+	phases := map[types.GamePhase]map[string]int{}
+	for _, phase := range []types.GamePhase{types.GamePending, types.GameRunning, types.GameCompleted, types.GameExpired} {
+		keys, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.GameKind).
+			Resources(strings.ToLower(types.EventKind)).
+			ListKeys(gameKeyRe, store.ListOptions{Index: store.GamePhaseIndex, IndexValue: string(phase)})
+		if err != nil {
+			logrus.WithError(err).WithField("phase", phase).Warn("Failed listing games for metrics")
+			return
+		}
+		phases[phase] = map[string]int{}
+		for _, key := range keys {
+			phases[phase][gameKeyRe.FindStringSubmatch(key)[1]]++
+		}
+	}
+	// only the running games are read for their players
+	running, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.GameKind).
+		Resources(strings.ToLower(types.EventKind)).
+		ListPage(gameKeyRe, store.ListOptions{Index: store.GamePhaseIndex, IndexValue: string(types.GameRunning)})
+	if err != nil {
+		logrus.WithError(err).Warn("Failed listing running games for metrics")
+		return
+	}
+	players := map[string]map[string]bool{}
+	for i, obj := range running.Items {
+		event := gameKeyRe.FindStringSubmatch(running.Keys[i])[1]
+		if players[event] == nil {
+			players[event] = map[string]bool{}
+		}
+		players[event][obj.(*types.Game).Player] = true
+	}
+	for _, obj := range events {
+		ev := obj.(*types.Event)
+		for phase, counts := range phases {
+			ch <- prometheus.MustNewConstMetric(gamesDesc, prometheus.GaugeValue, float64(counts[ev.Name]), ev.Name, string(phase))
+		}
+		ch <- prometheus.MustNewConstMetric(activePlayersDesc, prometheus.GaugeValue, float64(len(players[ev.Name])), ev.Name)
+	}
+}
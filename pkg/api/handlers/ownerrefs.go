@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// maxDependentsMessage limits the dependents listed when a deletion is blocked
+const maxDependentsMessage = 10
+
+// eventGame is a game and the event it belongs to
+type eventGame struct {
+	Event string
+	Game  *types.Game
+}
+
+func (g eventGame) String() string {
+	return g.Event + "/" + g.Game.Name
+}
+
+// deletionPolicy reads the policy of a delete request, dependents block the deletion by default
+func deletionPolicy(r *http.Request) (types.DeletionPolicy, error) {
+	switch p := types.DeletionPolicy(r.URL.Query().Get("cascade")); p {
+	case "":
+		return types.DeletionBlock, nil
+	case types.DeletionBlock, types.DeletionCascade, types.DeletionOrphan:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown deletion policy %q, expected one of: block, cascade or orphan", p)
+	}
+}
+
+// gamesOwnedBy returns the games which depend on an event or a challenge,
+// games created before the owner references are matched by their fields.
+func gamesOwnedBy(kind, name string) ([]eventGame, error) {
+	if kind == types.EventKind {
+		return eventGames(name)
+	}
+	all, err := challengeGames(name)
+	if err != nil {
+		return nil, err
+	}
+	var games []eventGame
+	for _, g := range all {
+		// games created without references are matched by their challenge
+		legacy := len(g.Game.OwnerReferences) == 0
+		if g.Game.IsOwnedBy(kind, name) || legacy {
+			games = append(games, g)
+		}
+	}
+	return games, nil
+}
+
+// challengePinned returns true if a game, orphaned or not, is still solved by
+// a revision of the challenge.
+func challengePinned(name string) (bool, error) {
+	keys, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.GameKind).
+		Resources(strings.ToLower(types.EventKind)).
+		ListKeys(gameKeyRe, store.ListOptions{Index: store.GameChallengeIndex, IndexValue: name})
+	return len(keys) > 0, err
+}
+
+// challengeGames returns the games of a challenge of every event by the index
+func challengeGames(name string) ([]eventGame, error) {
+	page, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.GameKind).
+		Resources(strings.ToLower(types.EventKind)).
+		ListPage(gameKeyRe, store.ListOptions{Index: store.GameChallengeIndex, IndexValue: name})
+	if err != nil {
+		return nil, err
+	}
+	var games []eventGame
+	for i, obj := range page.Items {
+		event := gameKeyRe.FindStringSubmatch(page.Keys[i])[1]
+		games = append(games, eventGame{Event: event, Game: obj.(*types.Game)})
+	}
+	return games, nil
+}
+
+// eventGames returns the games of an event
+func eventGames(event string) ([]eventGame, error) {
+	items, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.GameKind).
+		Resources(
+			strings.ToLower(types.EventKind),
+			event,
+			strings.ToLower(types.GameKind),
+		).List(gameKeyRe)
+	if err != nil {
+		return nil, err
+	}
+	var games []eventGame
+	for _, obj := range items {
+		games = append(games, eventGame{Event: event, Game: obj.(*types.Game)})
+	}
+	return games, nil
+}
+
+// deleteDependents applies the policy to the games of an owner, it fails with a
+// conflict when the policy is block and there are dependents.
+func deleteDependents(policy types.DeletionPolicy, kind, name string) (int, error) {
+	games, err := gamesOwnedBy(kind, name)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if len(games) == 0 {
+		return 0, nil
+	}
+	switch policy {
+	case types.DeletionBlock:
+		var names []string
+		for i, g := range games {
+			if i == maxDependentsMessage {
+				names = append(names, "...")
+				break
+			}
+			names = append(names, g.String())
+		}
+		return http.StatusConflict, fmt.Errorf("%s %q is used by %d game(s): %s, delete them first or set the policy cascade or orphan",
+			strings.ToLower(kind), name, len(games), strings.Join(names, ", "))
+	case types.DeletionCascade:
+		for _, g := range games {
+			if err := deleteGame(g.Event, g.Game); err != nil {
+				return http.StatusInternalServerError, fmt.Errorf("failed deleting game %s: %v", g, err)
+			}
+		}
+	case types.DeletionOrphan:
+		if kind == types.EventKind {
+			return http.StatusBadRequest, fmt.Errorf("games are stored in their event, they couldn't be orphaned")
+		}
+		// orphaned games are still solved by the revision they pinned
+		for _, g := range games {
+			g.Game.RemoveOwnerReference(kind, name)
+			_, err := store.New(dbConfig.file, dbConfig.bucket).
+				Kind(types.GameKind).
+				Resources(
+					strings.ToLower(types.EventKind),
+					g.Event,
+					strings.ToLower(types.GameKind),
+					g.Game.Name,
+				).Update(g.Game, g.Game)
+			if err != nil {
+				return http.StatusInternalServerError, fmt.Errorf("failed orphaning game %s: %v", g, err)
+			}
+		}
+	}
+	return 0, nil
+}
+
+// deleteGame destroys the environment of the player and removes the game
+func deleteGame(event string, gm *types.Game) error {
+	if err := deprovisionGame(event, gm); err != nil {
+		return fmt.Errorf("failed deprovisioning game: %v", err)
+	}
+	return store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.GameKind).
+		Resources(
+			strings.ToLower(types.EventKind),
+			event,
+			strings.ToLower(types.GameKind),
+		).Delete(gm.Name)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// setupOwners saves the challenge foo and the event meetup with two games owned
+// by both and a game created before the owner references existed.
+func setupOwners(t *testing.T) func() {
+	teardown := setupDatabase(t)
+	if err := store.New(dbConfig.file, dbConfig.bucket).Init(); err != nil {
+		t.Fatal(err)
+	}
+	chl, err := createChallenge(newChallenge("foo", "v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveEvent(t, "meetup", "")
+	ev, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.EventKind).
+		Resources(strings.ToLower(types.EventKind)).
+		Get("meetup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"g1", "g2", "legacy"} {
+		gm := &types.Game{
+			TypeMeta:            types.TypeMeta{Kind: types.GameKind},
+			Metadata:            types.Metadata{Name: name},
+			Challenge:           "foo",
+			ChallengeGeneration: 1,
+			Player:              "github|alice",
+		}
+		if name != "legacy" {
+			gm.OwnerReferences = []types.OwnerReference{types.NewOwnerReference(ev), types.NewOwnerReference(chl)}
+		}
+		_, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.GameKind).
+			Resources(strings.ToLower(types.EventKind), "meetup", strings.ToLower(types.GameKind), name).
+			SaveObject(gm)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return teardown
+}
+
+func getGame(t *testing.T, name string) *types.Game {
+	obj, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.GameKind).
+		Resources(strings.ToLower(types.EventKind), "meetup", strings.ToLower(types.GameKind)).
+		Get(name)
+	if store.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj.(*types.Game)
+}
+
+func TestDeleteChallengePolicies(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		query       string
+		wantStatus  int
+		wantGames   bool
+		wantDeleted bool
+	}{
+		{name: "block by default", wantStatus: http.StatusConflict, wantGames: true},
+		{name: "block", query: "?cascade=block", wantStatus: http.StatusConflict, wantGames: true},
+		{name: "unknown policy", query: "?cascade=all", wantStatus: http.StatusBadRequest, wantGames: true},
+		{name: "cascade", query: "?cascade=cascade", wantStatus: http.StatusNoContent, wantDeleted: true},
+		{name: "orphan", query: "?cascade=orphan", wantStatus: http.StatusNoContent, wantGames: true, wantDeleted: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer setupOwners(t)()
+			w := serveRoute(t, "DELETE", "/v1/challenges/{resourceName}", "/v1/challenges/foo"+tc.query, challengeHandler, nil)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected the status %d, got %d: %s", tc.wantStatus, w.Code, w.Body)
+			}
+			if w.Code == http.StatusConflict && !strings.Contains(w.Body.String(), "is used by 3 game(s)") {
+				t.Errorf("expected the dependents in the message, got %s", w.Body)
+			}
+			_, err := store.New(dbConfig.file, dbConfig.bucket).
+				Kind(types.ChallengeKind).
+				Resources(strings.ToLower(types.ChallengeKind)).
+				Get("foo")
+			if deleted := store.IsNotFound(err); deleted != tc.wantDeleted {
+				t.Errorf("expected the challenge to be deleted: %v, got %v", tc.wantDeleted, err)
+			}
+			for _, name := range []string{"g1", "g2", "legacy"} {
+				gm := getGame(t, name)
+				if (gm != nil) != tc.wantGames {
+					t.Fatalf("%s: expected the game to be kept: %v", name, tc.wantGames)
+				}
+				if gm != nil && tc.query == "?cascade=orphan" {
+					if gm.IsOwnedBy(types.ChallengeKind, "foo") {
+						t.Errorf("%s: expected the reference to the challenge to be removed", name)
+					}
+					if name != "legacy" && !gm.IsOwnedBy(types.EventKind, "meetup") {
+						t.Errorf("%s: expected the reference to the event to be kept", name)
+					}
+				}
+			}
+			// the orphaned games are still solved by the revision they pinned
+			_, err = getChallengeRevision("foo", 1)
+			if pinned := err == nil; pinned != tc.wantGames {
+				t.Errorf("expected the revisions to be kept only for the remaining games, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDeleteEventPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantGames  bool
+	}{
+		{name: "block by default", wantStatus: http.StatusConflict, wantGames: true},
+		{name: "games couldn't be orphaned", query: "?cascade=orphan", wantStatus: http.StatusBadRequest, wantGames: true},
+		{name: "cascade", query: "?cascade=cascade", wantStatus: http.StatusNoContent},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defer setupOwners(t)()
+			w := serveRoute(t, "DELETE", "/v1/events/{resourceName}", "/v1/events/meetup"+tc.query, eventHandler, nil)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected the status %d, got %d: %s", tc.wantStatus, w.Code, w.Body)
+			}
+			for _, name := range []string{"g1", "g2", "legacy"} {
+				if gm := getGame(t, name); (gm != nil) != tc.wantGames {
+					t.Errorf("%s: expected the game to be kept: %v", name, tc.wantGames)
+				}
+			}
+			// the challenge doesn't depend on the event
+			if _, err := getChallengeRevision("foo", 1); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	defer setupOwners(t)()
+	w := serveRoute(t, "DELETE", "/v1/events/{resourceName}", "/v1/events/missing", eventHandler, nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected the status 404 for a missing event, got %d", w.Code)
+	}
+}
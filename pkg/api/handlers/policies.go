@@ -46,6 +46,10 @@ func policyHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if err := SyncPolicies(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		w.WriteHeader(204)
 	case "GET":
 		obj, err := store.New(dbConfig.file, dbConfig.bucket).
@@ -53,7 +57,7 @@ func policyHandler(w http.ResponseWriter, r *http.Request) {
 			Resources(strings.ToLower(types.PolicyKind)).
 			Get(params["resourceName"])
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			http.Error(w, err.Error(), storeErrorStatus(err))
 			return
 		}
 		NewResponse(w).WriteJSON(obj)
@@ -79,18 +83,27 @@ func policyListHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if err := SyncPolicies(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		NewResponse(w).Status(201).WriteJSON(resp)
 	case "GET":
-		itemList, err := store.New(dbConfig.file, dbConfig.bucket).
+		opts, err := listOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		page, err := store.New(dbConfig.file, dbConfig.bucket).
 			Kind(types.PolicyKind).
 			Resources(strings.ToLower(types.PolicyKind)).
-			List(regexp.MustCompile(`^\/policy`))
+			ListPage(regexp.MustCompile(`^\/policy`), opts)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		items := types.PolicyList{}
-		for _, obj := range itemList {
+		items := types.PolicyList{ListMeta: newListMeta(page)}
+		for _, obj := range page.Items {
 			c := obj.(*types.Policy)
 			items.Items = append(items.Items, *c)
 		}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kubeplay/gameserver/pkg/provisioner"
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// provisionTimeout limits how long the environment of a player takes to be ready
+const provisionTimeout = 5 * time.Minute
+
+func newProvisioningStatus(name string, phase types.ProvisioningPhase, message string) *types.ProvisioningStatus {
+	return &types.ProvisioningStatus{
+		Provisioner: name,
+		Phase:       phase,
+		Message:     message,
+		UpdatedAt:   time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// provisionGame creates the environment of the player in the background, the
+// game starts automatically when the environment is ready.
+func provisionGame(event string, gm *types.Game, chl *types.Challenge) {
+	spec := chl.Provisioner
+	log := logrus.WithFields(logrus.Fields{
+		"event":       event,
+		"game":        gm.Name,
+		"provisioner": spec.Name,
+	})
+	var outputs map[string]string
+	req, err := newProvisionerRequest(event, gm, chl)
+	if err == nil {
+		var p provisioner.Provisioner
+		if p, err = provisioner.Get(spec.Name); err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), provisionTimeout)
+			defer cancel()
+			outputs, err = p.Provision(ctx, req)
+		}
+	}
+	status := newProvisioningStatus(spec.Name, types.ProvisioningReady, "")
+	status.Outputs = outputs
+	if err != nil {
+		log.WithError(err).Warn("Failed provisioning game")
+		status = newProvisioningStatus(spec.Name, types.ProvisioningFailed, err.Error())
+	}
+	s := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.GameKind).
+		Resources(
+			strings.ToLower(types.EventKind),
+			event,
+			strings.ToLower(types.GameKind),
+		)
+	// the game may have changed while provisioning, it is read and saved in one transaction
+	started := false
+	obj, err := s.UpdateFunc(gm.Name, func(obj types.Object) error {
+		current := obj.(*types.Game)
+		current.Status.Provisioning = status
+		started = false
+		if status.Phase == types.ProvisioningReady && current.Status.Phase == types.GamePending {
+			current.Status.Phase = types.GameRunning
+			current.Status.StartTime = time.Now().UTC().Format(time.RFC3339)
+			started = true
+		}
+		return nil
+	})
+	if store.IsNotFound(err) {
+		log.WithError(err).Warn("Failed updating the provisioning status, the game was removed")
+		return
+	}
+	if err != nil {
+		log.WithError(err).Warn("Failed updating the provisioning status")
+		return
+	}
+	if started {
+		log.Info("Game environment ready, the game started")
+		notifyHooks(types.HookGameStarted, event, obj.(*types.Game), "")
+	}
+}
+
+// deprovisionGame destroys the environment of the player, games of challenges
+// without a provisioner are noop.
+func deprovisionGame(event string, gm *types.Game) error {
+	chl, err := getChallengeRevision(gm.Challenge, gm.ChallengeGeneration)
+	if err != nil {
+		return err
+	}
+	if chl.Provisioner == nil {
+		return nil
+	}
+	p, err := provisioner.Get(chl.Provisioner.Name)
+	if err != nil {
+		return err
+	}
+	req, err := newProvisionerRequest(event, gm, chl)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), provisionTimeout)
+	defer cancel()
+	return p.Deprovision(ctx, req)
+}
+
+func newProvisionerRequest(event string, gm *types.Game, chl *types.Challenge) (*provisioner.Request, error) {
+	manifests, err := bundleManifests(chl)
+	if err != nil {
+		return nil, fmt.Errorf("failed loading the manifests of the bundle: %v", err)
+	}
+	return &provisioner.Request{
+		Event:     event,
+		Game:      gm,
+		Challenge: chl,
+		Params:    chl.Provisioner.Params,
+		Manifests: manifests,
+	}, nil
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// revisionResource is where the immutable revisions of a challenge are stored,
+// e.g.: /challenge/<name>/revision/<generation>
+const revisionResource = "revision"
+
+var challengeRevisionRe = regexp.MustCompile(`^\/challenge\/[^/]+\/revision\/[0-9]+$`)
+
+func (c *challenge) HandlerRevisionList() HandlerFn {
+	return challengeRevisionListHandler
+}
+
+func (c *challenge) HandlerRevision() HandlerFn {
+	return challengeRevisionHandler
+}
+
+func challengeRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	switch r.Method {
+	case "GET":
+		generation, err := strconv.ParseInt(params["resourceName"], 10, 64)
+		if err != nil || generation < 1 {
+			http.Error(w, "the revision must be a generation number greater than zero", http.StatusBadRequest)
+			return
+		}
+		c, err := getChallengeRevision(params["parent"], generation)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		NewResponse(w).WriteJSON(c)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+func challengeRevisionListHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	switch r.Method {
+	case "GET":
+		sel, err := listSelector(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		itemList, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.ChallengeKind).
+			Resources(
+				strings.ToLower(types.ChallengeKind),
+				params["parent"],
+				revisionResource,
+			).List(challengeRevisionRe)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		items := types.ChallengeList{}
+		for _, obj := range itemList {
+			if !sel.Matches(obj) {
+				continue
+			}
+			items.Items = append(items.Items, *obj.(*types.Challenge))
+		}
+		sort.Slice(items.Items, func(i, j int) bool {
+			return items.Items[i].Generation < items.Items[j].Generation
+		})
+		items.Kind = "List"
+		NewResponse(w).WriteJSON(&items)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+// createChallenge saves a new challenge with its first revision, revisions of a
+// deleted challenge with the same name are kept for their orphaned games.
+func createChallenge(c *types.Challenge) (types.Object, error) {
+	revisions, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.ChallengeKind).
+		Resources(strings.ToLower(types.ChallengeKind), c.Name, revisionResource).
+		List(challengeRevisionRe)
+	if err != nil {
+		return nil, err
+	}
+	c.Generation = 1
+	for _, obj := range revisions {
+		if g := obj.(*types.Challenge).Generation; g >= c.Generation {
+			c.Generation = g + 1
+		}
+	}
+	resp, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.ChallengeKind).
+		Resources(strings.ToLower(types.ChallengeKind), c.Name).
+		SaveObject(c)
+	if err != nil {
+		return nil, err
+	}
+	return resp, saveChallengeRevision(resp.(*types.Challenge))
+}
+
+// updateChallenge replaces a challenge creating a new revision, the games keep
+// the revision they were created with. Updates without changes are noop and
+// changing only the labels keeps the revision.
+func updateChallenge(old, new *types.Challenge) (types.Object, error) {
+	if challengeSpecEqual(old, new) {
+		if reflect.DeepEqual(old.Labels, new.Labels) {
+			return old, nil
+		}
+		new.Generation = old.Generation
+		return store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.ChallengeKind).
+			Resources(strings.ToLower(types.ChallengeKind), old.Name).
+			Update(old, new)
+	}
+	// challenges created without revisions keep their content as the first one
+	if old.Generation == 0 {
+		old.Generation = 1
+		if err := saveChallengeRevision(old); err != nil {
+			return nil, err
+		}
+	}
+	new.Generation = old.Generation + 1
+	resp, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.ChallengeKind).
+		Resources(strings.ToLower(types.ChallengeKind), old.Name).
+		Update(old, new)
+	if err != nil {
+		return nil, err
+	}
+	return resp, saveChallengeRevision(resp.(*types.Challenge))
+}
+
+func challengeSpecEqual(old, new *types.Challenge) bool {
+	return reflect.DeepEqual(old.Keys, new.Keys) &&
+		old.AssetsURL == new.AssetsURL &&
+		reflect.DeepEqual(old.Provisioner, new.Provisioner) &&
+		reflect.DeepEqual(old.Bundle, new.Bundle)
+}
+
+func saveChallengeRevision(c *types.Challenge) error {
+	rev := *c
+	// the creation time of a revision is when it was recorded
+	rev.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	_, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.ChallengeKind).
+		Resources(
+			strings.ToLower(types.ChallengeKind),
+			c.Name,
+			revisionResource,
+			strconv.FormatInt(c.Generation, 10),
+		).Update(&rev, &rev)
+	return err
+}
+
+// getChallengeRevision returns a revision of a challenge, games created before
+// the revisions existed (generation 0) use the first revision or the current challenge.
+func getChallengeRevision(name string, generation int64) (*types.Challenge, error) {
+	g := generation
+	if g == 0 {
+		g = 1
+	}
+	obj, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.ChallengeKind).
+		Resources(strings.ToLower(types.ChallengeKind), name, revisionResource).
+		Get(strconv.FormatInt(g, 10))
+	if err != nil && generation == 0 {
+		obj, err = store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.ChallengeKind).
+			Resources(strings.ToLower(types.ChallengeKind)).
+			Get(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*types.Challenge), nil
+}
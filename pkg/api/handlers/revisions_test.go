@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// serveRoute calls the handler through a router with the route template, the
+// payload is set as the decoder middleware does.
+func serveRoute(t *testing.T, method, template, path string, handler HandlerFn, payload types.Object) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.HandleFunc(template, func(w http.ResponseWriter, r *http.Request) {
+		if payload != nil {
+			context.Set(r, "payload", payload)
+		}
+		defer context.Clear(r)
+		handler(w, r)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func newChallenge(name, key string) *types.Challenge {
+	return &types.Challenge{
+		TypeMeta: types.TypeMeta{Kind: types.ChallengeKind},
+		Metadata: types.Metadata{Name: name},
+		Keys:     map[string]types.Key{"k1": {Value: key, Weight: 1}},
+	}
+}
+
+func putChallenge(t *testing.T, c *types.Challenge) *types.Challenge {
+	w := serveRoute(t, "PUT", "/v1/challenges/{resourceName}", "/v1/challenges/"+c.Name, challengeHandler, c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the status 200, got %d: %s", w.Code, w.Body)
+	}
+	var resp types.Challenge
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return &resp
+}
+
+func TestChallengeRevisions(t *testing.T) {
+	defer setupDatabase(t)()
+	if err := store.New(dbConfig.file, dbConfig.bucket).Init(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createChallenge(newChallenge("foo", "v1")); err != nil {
+		t.Fatal(err)
+	}
+	// updates without changes don't create revisions
+	if c := putChallenge(t, newChallenge("foo", "v1")); c.Generation != 1 {
+		t.Errorf("expected the generation 1 without changes, got %d", c.Generation)
+	}
+	labeled := newChallenge("foo", "v1")
+	labeled.Labels = map[string]string{"track": "k8s"}
+	if c := putChallenge(t, labeled); c.Generation != 1 || c.Labels["track"] != "k8s" {
+		t.Errorf("expected the labels to keep the generation 1, got %d %v", c.Generation, c.Labels)
+	}
+	if c := putChallenge(t, newChallenge("foo", "v2")); c.Generation != 2 {
+		t.Errorf("expected the generation 2 after changing the keys, got %d", c.Generation)
+	}
+
+	// games keep the revision they were created with
+	for generation, want := range map[int64]string{1: "v1", 2: "v2"} {
+		c, err := getChallengeRevision("foo", generation)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Keys["k1"].Value != want || c.Generation != generation {
+			t.Errorf("revision %d: expected the key %s, got %+v", generation, want, c.Keys)
+		}
+	}
+	if _, err := getChallengeRevision("foo", 3); err == nil {
+		t.Error("expected an error for a missing revision")
+	}
+
+	w := serveRoute(t, "GET", "/v1/challenges/{parent}/revisions", "/v1/challenges/foo/revisions", challengeRevisionListHandler, nil)
+	var list types.ChallengeList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("%v: %s", err, w.Body)
+	}
+	if len(list.Items) != 2 || list.Items[0].Generation != 1 || list.Items[1].Generation != 2 {
+		t.Errorf("expected the revisions sorted by generation, got %+v", list.Items)
+	}
+
+	for path, wantStatus := range map[string]int{
+		"/v1/challenges/foo/revisions/2": http.StatusOK,
+		"/v1/challenges/foo/revisions/3": http.StatusNotFound,
+		"/v1/challenges/foo/revisions/0": http.StatusBadRequest,
+		"/v1/challenges/foo/revisions/x": http.StatusBadRequest,
+	} {
+		w := serveRoute(t, "GET", "/v1/challenges/{parent}/revisions/{resourceName}", path, challengeRevisionHandler, nil)
+		if w.Code != wantStatus {
+			t.Errorf("%s: expected the status %d, got %d: %s", path, wantStatus, w.Code, w.Body)
+		}
+	}
+}
+
+func TestChallengeRevisionsWithoutGeneration(t *testing.T) {
+	defer setupDatabase(t)()
+	// challenges created before the revisions existed
+	old := newChallenge("foo", "v1")
+	_, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.ChallengeKind).
+		Resources(strings.ToLower(types.ChallengeKind), old.Name).
+		SaveObject(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := getChallengeRevision("foo", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Keys["k1"].Value != "v1" {
+		t.Errorf("expected the current challenge without revisions, got %+v", c)
+	}
+	// the first update keeps the old content as the first revision
+	if c := putChallenge(t, newChallenge("foo", "v2")); c.Generation != 2 {
+		t.Errorf("expected the generation 2, got %d", c.Generation)
+	}
+	c, err = getChallengeRevision("foo", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Keys["k1"].Value != "v1" || c.Generation != 1 {
+		t.Errorf("expected the games without generation to use the first revision, got %+v", c)
+	}
+}
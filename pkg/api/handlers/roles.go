@@ -0,0 +1,363 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	apiauth "github.com/kubeplay/gameserver/pkg/api/auth"
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+var Role = role{}
+
+type role struct{}
+
+func (c *role) HandlerList() HandlerFn {
+	return roleListHandler
+}
+
+func (c *role) Handler() HandlerFn {
+	return roleHandler
+}
+
+func (c *role) HandlerBindingList() HandlerFn {
+	return roleBindingListHandler
+}
+
+func (c *role) HandlerBinding() HandlerFn {
+	return roleBindingHandler
+}
+
+func (c *role) Middlewares() []mux.MiddlewareFunc {
+	return []mux.MiddlewareFunc{}
+}
+
+func roleHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	switch r.Method {
+	case "DELETE":
+		err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.RoleKind).
+			Resources(strings.ToLower(types.RoleKind)).
+			Delete(params["resourceName"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := SyncPolicies(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(204)
+	case "GET":
+		obj, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.RoleKind).
+			Resources(strings.ToLower(types.RoleKind)).
+			Get(params["resourceName"])
+		if err != nil {
+			http.Error(w, err.Error(), storeErrorStatus(err))
+			return
+		}
+		NewResponse(w).WriteJSON(obj)
+	case "PUT":
+		new, ok := context.Get(r, "payload").(*types.Role)
+		if !ok {
+			http.Error(w, "unknown type found", http.StatusBadRequest)
+			return
+		}
+		old, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.RoleKind).
+			Resources(strings.ToLower(types.RoleKind)).
+			Get(params["resourceName"])
+		if err != nil {
+			http.Error(w, err.Error(), storeErrorStatus(err))
+			return
+		}
+		obj, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.RoleKind).
+			Resources(
+				strings.ToLower(types.RoleKind),
+				params["resourceName"],
+			).Update(old, new)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := SyncPolicies(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		NewResponse(w).WriteJSON(obj)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+func roleListHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		ro, ok := context.Get(r, "payload").(*types.Role)
+		if !ok {
+			http.Error(w, "unknown type found", http.StatusBadRequest)
+			return
+		}
+		if _, builtin := apiauth.BuiltinRoles[ro.Name]; builtin {
+			http.Error(w, "it's not allowed to override a built-in role", http.StatusBadRequest)
+			return
+		}
+		resp, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.RoleKind).
+			Resources(strings.ToLower(types.RoleKind), ro.Name).
+			SaveObject(ro)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := SyncPolicies(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		NewResponse(w).Status(201).WriteJSON(resp)
+	case "GET":
+		sel, err := listSelector(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		roles, err := listRoles()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		items := types.RoleList{}
+		var all []types.Role
+		for name, rules := range apiauth.BuiltinRoles {
+			all = append(all, types.Role{
+				TypeMeta: types.TypeMeta{Kind: types.RoleKind},
+				Metadata: types.Metadata{Name: name},
+				Rules:    rules,
+			})
+		}
+		all = append(all, roles...)
+		for i := range all {
+			if sel.Matches(&all[i]) {
+				items.Items = append(items.Items, all[i])
+			}
+		}
+		items.Kind = "List"
+		NewResponse(w).WriteJSON(&items)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+func roleBindingHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	switch r.Method {
+	case "DELETE":
+		err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.RoleBindingKind).
+			Resources(strings.ToLower(types.RoleBindingKind)).
+			Delete(params["resourceName"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := SyncPolicies(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(204)
+	case "GET":
+		obj, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.RoleBindingKind).
+			Resources(strings.ToLower(types.RoleBindingKind)).
+			Get(params["resourceName"])
+		if err != nil {
+			http.Error(w, err.Error(), storeErrorStatus(err))
+			return
+		}
+		NewResponse(w).WriteJSON(obj)
+	case "PUT":
+		new, ok := context.Get(r, "payload").(*types.RoleBinding)
+		if !ok {
+			http.Error(w, "unknown type found", http.StatusBadRequest)
+			return
+		}
+		old, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.RoleBindingKind).
+			Resources(strings.ToLower(types.RoleBindingKind)).
+			Get(params["resourceName"])
+		if err != nil {
+			http.Error(w, err.Error(), storeErrorStatus(err))
+			return
+		}
+		obj, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.RoleBindingKind).
+			Resources(
+				strings.ToLower(types.RoleBindingKind),
+				params["resourceName"],
+			).Update(old, new)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := SyncPolicies(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		NewResponse(w).WriteJSON(obj)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+func roleBindingListHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		rb, ok := context.Get(r, "payload").(*types.RoleBinding)
+		if !ok {
+			http.Error(w, "unknown type found", http.StatusBadRequest)
+			return
+		}
+		if rb.Role == "" || len(rb.Subjects) == 0 {
+			http.Error(w, "missing the role or the subjects", http.StatusBadRequest)
+			return
+		}
+		resp, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.RoleBindingKind).
+			Resources(strings.ToLower(types.RoleBindingKind), rb.Name).
+			SaveObject(rb)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := SyncPolicies(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		NewResponse(w).Status(201).WriteJSON(resp)
+	case "GET":
+		sel, err := listSelector(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		bindings, err := listRoleBindings()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		items := types.RoleBindingList{}
+		for i := range bindings {
+			if sel.Matches(&bindings[i]) {
+				items.Items = append(items.Items, bindings[i])
+			}
+		}
+		items.Kind = "List"
+		NewResponse(w).WriteJSON(&items)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+func listRoles() ([]types.Role, error) {
+	itemList, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.RoleKind).
+		Resources(strings.ToLower(types.RoleKind)).
+		List(regexp.MustCompile(`^\/role\/`))
+	if err != nil {
+		return nil, err
+	}
+	var roles []types.Role
+	for _, obj := range itemList {
+		roles = append(roles, *obj.(*types.Role))
+	}
+	return roles, nil
+}
+
+func listRoleBindings() ([]types.RoleBinding, error) {
+	itemList, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.RoleBindingKind).
+		Resources(strings.ToLower(types.RoleBindingKind)).
+		List(regexp.MustCompile(`^\/rolebinding\/`))
+	if err != nil {
+		return nil, err
+	}
+	var bindings []types.RoleBinding
+	for _, obj := range itemList {
+		bindings = append(bindings, *obj.(*types.RoleBinding))
+	}
+	return bindings, nil
+}
+
+func listPolicies() ([]types.Policy, error) {
+	itemList, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.PolicyKind).
+		Resources(strings.ToLower(types.PolicyKind)).
+		List(regexp.MustCompile(`^\/policy\/`))
+	if err != nil {
+		return nil, err
+	}
+	var policies []types.Policy
+	for _, obj := range itemList {
+		policies = append(policies, *obj.(*types.Policy))
+	}
+	return policies, nil
+}
+
+// SyncPolicies rewrites the policy file from the roles,
+// role bindings and policies found in the store.
+func SyncPolicies() error {
+	if err := store.New(dbConfig.file, dbConfig.bucket).Init(); err != nil {
+		return err
+	}
+	roles, err := listRoles()
+	if err != nil {
+		return err
+	}
+	bindings, err := listRoleBindings()
+	if err != nil {
+		return err
+	}
+	policies, err := listPolicies()
+	if err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"roles":    len(roles),
+		"bindings": len(bindings),
+		"policies": len(policies),
+	}).Info("Syncing policies")
+	return apiauth.SyncPolicies(policiesFile, roles, bindings, policies)
+}
+
+// BootstrapRoleBinding grants a role to a subject in every event if the binding doesn't exist
+func BootstrapRoleBinding(name, subject, role string) error {
+	if err := store.New(dbConfig.file, dbConfig.bucket).Init(); err != nil {
+		return err
+	}
+	s := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.RoleBindingKind).
+		Resources(strings.ToLower(types.RoleBindingKind))
+	if _, err := s.Get(name); err == nil {
+		return nil
+	}
+	rb := &types.RoleBinding{
+		TypeMeta: types.TypeMeta{Kind: types.RoleBindingKind},
+		Metadata: types.Metadata{Name: name},
+		Role:     role,
+		Subjects: []string{subject},
+	}
+	_, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.RoleBindingKind).
+		Resources(strings.ToLower(types.RoleBindingKind), name).
+		SaveObject(rb)
+	return err
+}
@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 7 * 24 * time.Hour
+)
+
+var Session = session{}
+
+type session struct{}
+
+func (c *session) HandlerList() HandlerFn {
+	return sessionListHandler
+}
+
+func (c *session) Handler() HandlerFn {
+	return sessionHandler
+}
+
+func (c *session) HandlerRefresh() HandlerFn {
+	return refreshHandler
+}
+
+func (c *session) HandlerLogout() HandlerFn {
+	return logoutHandler
+}
+
+func (c *session) Middlewares() []mux.MiddlewareFunc {
+	return []mux.MiddlewareFunc{}
+}
+
+func sessionHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	switch r.Method {
+	case "GET":
+		obj, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.SessionKind).
+			Resources(strings.ToLower(types.SessionKind)).
+			Get(params["resourceName"])
+		if err != nil {
+			http.Error(w, err.Error(), storeErrorStatus(err))
+			return
+		}
+		NewResponse(w).WriteJSON(obj)
+	case "DELETE":
+		if _, err := revokeSession(params["resourceName"]); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(204)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+func sessionListHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		opts, err := listOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if subject := r.URL.Query().Get("subject"); subject != "" {
+			addMatch(&opts, func(obj types.Object) bool {
+				return obj.(*types.Session).Subject == subject
+			})
+		}
+		page, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.SessionKind).
+			Resources(strings.ToLower(types.SessionKind)).
+			ListPage(regexp.MustCompile(`^\/session\/`), opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		items := types.SessionList{ListMeta: newListMeta(page)}
+		for _, obj := range page.Items {
+			items.Items = append(items.Items, *obj.(*types.Session))
+		}
+		items.Kind = "List"
+		NewResponse(w).WriteJSON(&items)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+// refreshHandler issues a new access token exchanging a valid refresh token,
+// the refresh token is rotated on every call.
+func refreshHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		refreshToken := r.Header.Get(types.RefreshTokenHeaderName)
+		parts := strings.SplitN(refreshToken, ".", 2)
+		if len(parts) != 2 {
+			msg := fmt.Sprintf("%q header not set or malformed", types.RefreshTokenHeaderName)
+			http.Error(w, msg, http.StatusUnauthorized)
+			return
+		}
+		s := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.SessionKind).
+			Resources(strings.ToLower(types.SessionKind))
+		obj, err := s.Get(parts[0])
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		sess := obj.(*types.Session)
+		hash := hashRefreshToken(refreshToken)
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(sess.RefreshTokenHash)) != 1 {
+			logrus.WithField("session", sess.Name).Warn("invalid refresh token")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		expiresAt, _ := time.Parse(time.RFC3339, sess.ExpiresAt)
+		if time.Now().UTC().After(expiresAt) {
+			http.Error(w, "The session is expired", http.StatusUnauthorized)
+			return
+		}
+		profile := sess.Claims
+		// memberships could change during the session
+		resolveGitHubRoles(&profile)
+		if err := issueSessionTokens(sess, &profile); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := s.Update(sess, sess); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(&profile); err != nil {
+			logrus.Warnf("failed encoding response %v", err)
+		}
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+// logoutHandler revokes the session of the caller and its current access token
+func logoutHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		pl, ok := context.Get(r, "player").(*types.PlayerClaims)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		sess, err := revokeSession(pl.SessionID)
+		if err != nil {
+			logrus.WithField("session", pl.SessionID).Warnf("failed revoking session: %v", err)
+		}
+		if sess == nil || sess.AccessTokenID != pl.Id {
+			if err := revokeToken(pl.Id, time.Unix(pl.ExpiresAt, 0)); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		w.WriteHeader(204)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+// NewSession stores a new server-side session for the player and
+// issues its access and refresh tokens.
+func NewSession(profile *types.PlayerClaims) error {
+	sess := &types.Session{
+		TypeMeta: types.TypeMeta{Kind: types.SessionKind},
+		Metadata: types.Metadata{Name: store.NewUUID()},
+		Subject:  profile.Username(),
+		ExpiresAt: time.Now().UTC().
+			Add(refreshTokenTTL).
+			Format(time.RFC3339),
+	}
+	if err := issueSessionTokens(sess, profile); err != nil {
+		return err
+	}
+	_, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.SessionKind).
+		Resources(strings.ToLower(types.SessionKind), sess.Name).
+		SaveObject(sess)
+	return err
+}
+
+// issueSessionTokens generates a new access token and rotates the refresh token of the session
+func issueSessionTokens(sess *types.Session, profile *types.PlayerClaims) error {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed generating refresh token: %v", err)
+	}
+	refreshToken := fmt.Sprintf("%s.%s", sess.Name, hex.EncodeToString(secret))
+	profile.SessionID = sess.Name
+	profile.AccessToken = ""
+	profile.RefreshToken = ""
+	if err := GenerateNewJwtToken(
+		profile,
+		time.Now().UTC().Add(accessTokenTTL),
+	); err != nil {
+		return err
+	}
+	sess.Claims = *profile
+	sess.Claims.AccessToken = ""
+	sess.AccessTokenID = profile.Id
+	sess.RefreshTokenHash = hashRefreshToken(refreshToken)
+	profile.RefreshToken = refreshToken
+	return nil
+}
+
+// revokeSession removes the session, denying its last issued access token
+func revokeSession(sessionID string) (*types.Session, error) {
+	if sessionID == "" {
+		return nil, fmt.Errorf("missing session id")
+	}
+	s := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.SessionKind).
+		Resources(strings.ToLower(types.SessionKind))
+	obj, err := s.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	sess := obj.(*types.Session)
+	expiresAt := time.Unix(sess.Claims.ExpiresAt, 0)
+	if err := revokeToken(sess.AccessTokenID, expiresAt); err != nil {
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"session": sess.Name,
+		"subject": sess.Subject,
+	}).Info("Session revoked")
+	return sess, store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.SessionKind).
+		Resources(strings.ToLower(types.SessionKind)).
+		Delete(sessionID)
+}
+
+// revokeSubjectSessions revokes every session of the subject, e.g.: a deleted account
+func revokeSubjectSessions(subject string) error {
+	objs, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.SessionKind).
+		Resources(strings.ToLower(types.SessionKind)).
+		List(regexp.MustCompile(`^\/session\/`))
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		if sess := obj.(*types.Session); sess.Subject == subject {
+			if _, err := revokeSession(sess.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// IsSessionRevoked verifies if the session of an access token was removed, the access
+// tokens issued before the last refresh aren't in the denylist.
+func IsSessionRevoked(sessionID string) bool {
+	if sessionID == "" {
+		return false
+	}
+	_, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.SessionKind).
+		Resources(strings.ToLower(types.SessionKind)).
+		Get(sessionID)
+	return err != nil
+}
+
+// revokeToken adds the JWT ID to the denylist
+func revokeToken(tokenID string, expiresAt time.Time) error {
+	if tokenID == "" || IsTokenRevoked(tokenID) {
+		return nil
+	}
+	t := &types.RevokedToken{
+		TypeMeta:  types.TypeMeta{Kind: types.RevokedTokenKind},
+		Metadata:  types.Metadata{Name: tokenID},
+		ExpiresAt: expiresAt.UTC().Format(time.RFC3339),
+	}
+	_, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.RevokedTokenKind).
+		Resources(strings.ToLower(types.RevokedTokenKind), tokenID).
+		SaveObject(t)
+	return err
+}
+
+// IsTokenRevoked verifies if the JWT ID is in the denylist
+func IsTokenRevoked(tokenID string) bool {
+	if tokenID == "" {
+		return false
+	}
+	_, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.RevokedTokenKind).
+		Resources(strings.ToLower(types.RevokedTokenKind)).
+		Get(tokenID)
+	return err == nil
+}
+
+// PruneExpiredTokens removes the expired sessions and the revoked tokens which already expired,
+// a revoked token stops mattering once it's expired. It returns the number of removed objects.
+func PruneExpiredTokens(now time.Time) (int, error) {
+	pruned := 0
+	for kind, expiresAt := range map[string]func(types.Object) string{
+		types.SessionKind:      func(obj types.Object) string { return obj.(*types.Session).ExpiresAt },
+		types.RevokedTokenKind: func(obj types.Object) string { return obj.(*types.RevokedToken).ExpiresAt },
+	} {
+		resource := strings.ToLower(kind)
+		objs, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(kind).
+			Resources(resource).
+			List(regexp.MustCompile(fmt.Sprintf(`^\/%s\/`, resource)))
+		if err != nil {
+			return pruned, err
+		}
+		for _, obj := range objs {
+			t, err := time.Parse(time.RFC3339, expiresAt(obj))
+			if err != nil || t.After(now) {
+				continue
+			}
+			err = store.New(dbConfig.file, dbConfig.bucket).
+				Kind(kind).
+				Resources(resource).
+				Delete(obj.GetObjectMeta().Name)
+			if err != nil {
+				return pruned, err
+			}
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+// PruneExpiredTokensEvery prunes the expired tokens now and then on every
+// interval, it returns when the stop channel is closed.
+func PruneExpiredTokensEvery(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		pruned, err := PruneExpiredTokens(time.Now())
+		if err != nil {
+			logrus.WithError(err).Warn("Failed pruning expired tokens")
+		} else if pruned > 0 {
+			logrus.WithField("pruned", pruned).Info("Pruned expired sessions and revoked tokens")
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func hashRefreshToken(refreshToken string) string {
+	hash := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(hash[:])
+}
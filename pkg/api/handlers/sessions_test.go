@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/context"
+
+	apiauth "github.com/kubeplay/gameserver/pkg/api/auth"
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// setupDatabase points the handlers to a database and a policies file in a
+// temporary directory, the returned func removes them.
+func setupDatabase(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "kubeplay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbConfig.file = filepath.Join(dir, "kubeplay.db")
+	dbConfig.bucket = "kubeplay"
+	policiesFile = filepath.Join(dir, "policies.csv")
+	return func() { os.RemoveAll(dir) }
+}
+
+func saveSession(t *testing.T, name string, expiresAt time.Time) {
+	sess := &types.Session{
+		TypeMeta:  types.TypeMeta{Kind: types.SessionKind},
+		Metadata:  types.Metadata{Name: name},
+		Subject:   "local|alice",
+		ExpiresAt: expiresAt.UTC().Format(time.RFC3339),
+	}
+	_, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.SessionKind).
+		Resources(strings.ToLower(types.SessionKind), name).
+		SaveObject(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sessionExists(name string) bool {
+	_, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.SessionKind).
+		Resources(strings.ToLower(types.SessionKind)).
+		Get(name)
+	return err == nil
+}
+
+func TestPruneExpiredTokens(t *testing.T) {
+	defer setupDatabase(t)()
+	now := time.Now()
+	saveSession(t, "expired", now.Add(-time.Hour))
+	saveSession(t, "active", now.Add(time.Hour))
+	if err := revokeToken("expired-jti", now.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := revokeToken("active-jti", now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	pruned, err := PruneExpiredTokens(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 2 {
+		t.Errorf("expected 2 pruned objects, got %d", pruned)
+	}
+	if sessionExists("expired") || !sessionExists("active") {
+		t.Errorf("expected only the active session to be kept")
+	}
+	if IsTokenRevoked("expired-jti") {
+		t.Errorf("expected the expired revoked token to be pruned")
+	}
+	if !IsTokenRevoked("active-jti") {
+		t.Errorf("expected the revoked token to be denied until it expires")
+	}
+
+	pruned, err = PruneExpiredTokens(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 0 {
+		t.Errorf("expected nothing to prune, got %d", pruned)
+	}
+}
+
+func TestRevokeSubjectSessions(t *testing.T) {
+	defer setupDatabase(t)()
+	expiresAt := time.Now().Add(time.Hour)
+	for name, subject := range map[string]string{"a1": "local|alice", "a2": "local|alice", "b1": "local|bob"} {
+		sess := &types.Session{
+			TypeMeta:      types.TypeMeta{Kind: types.SessionKind},
+			Metadata:      types.Metadata{Name: name},
+			Subject:       subject,
+			AccessTokenID: name + "-jti",
+			ExpiresAt:     expiresAt.UTC().Format(time.RFC3339),
+		}
+		sess.Claims.ExpiresAt = expiresAt.Unix()
+		_, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.SessionKind).
+			Resources(strings.ToLower(types.SessionKind), name).
+			SaveObject(sess)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := revokeSubjectSessions("local|alice"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		session string
+		revoked bool
+	}{
+		{"a1", true},
+		{"a2", true},
+		{"b1", false},
+	} {
+		if got := IsSessionRevoked(tc.session); got != tc.revoked {
+			t.Errorf("session %s: expected revoked=%v, got %v", tc.session, tc.revoked, got)
+		}
+		if got := IsTokenRevoked(tc.session + "-jti"); got != tc.revoked {
+			t.Errorf("access token of %s: expected revoked=%v, got %v", tc.session, tc.revoked, got)
+		}
+	}
+}
+
+// setupSigningKeys signs the tokens of the handlers with a new ECDSA key
+func setupSigningKeys(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "kubeplay-keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "signing.pem")
+	if err := ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ks, err := apiauth.LoadKeySet(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetSigningKeys(ks)
+}
+
+func refresh(t *testing.T, refreshToken string) (*httptest.ResponseRecorder, *types.PlayerClaims) {
+	r := httptest.NewRequest("POST", "/v1/auth/refresh", nil)
+	r.Header.Set(types.RefreshTokenHeaderName, refreshToken)
+	w := httptest.NewRecorder()
+	refreshHandler(w, r)
+	if w.Code != http.StatusOK {
+		return w, nil
+	}
+	profile := &types.PlayerClaims{}
+	if err := json.NewDecoder(w.Body).Decode(profile); err != nil {
+		t.Fatal(err)
+	}
+	return w, profile
+}
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	defer setupDatabase(t)()
+	setupSigningKeys(t)
+	profile := &types.PlayerClaims{Login: "alice", Provider: types.LocalProvider}
+	if err := NewSession(profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile.SessionID == "" || profile.RefreshToken == "" || profile.Id == "" {
+		t.Fatalf("expected the session tokens, got %+v", profile)
+	}
+
+	w, refreshed := refresh(t, profile.RefreshToken)
+	if refreshed == nil {
+		t.Fatalf("expected the tokens to be refreshed, got %d: %s", w.Code, w.Body)
+	}
+	if refreshed.SessionID != profile.SessionID {
+		t.Errorf("expected the same session, got %s", refreshed.SessionID)
+	}
+	if refreshed.RefreshToken == profile.RefreshToken || refreshed.Id == profile.Id {
+		t.Error("expected new refresh and access tokens")
+	}
+	if refreshed.Subject != "local|alice" {
+		t.Errorf("expected the subject of the session, got %q", refreshed.Subject)
+	}
+	// the refresh tokens are used once
+	if w, _ := refresh(t, profile.RefreshToken); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected the rotated refresh token to be refused, got %d", w.Code)
+	}
+	for _, token := range []string{"", "malformed", "missing." + strings.Repeat("0", 64)} {
+		if w, _ := refresh(t, token); w.Code != http.StatusUnauthorized {
+			t.Errorf("%q: expected the refresh token to be refused, got %d", token, w.Code)
+		}
+	}
+	// the secret of the session is never returned
+	obj, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.SessionKind).
+		Resources(strings.ToLower(types.SessionKind)).
+		Get(profile.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess := obj.(*types.Session); sess.AccessTokenID != refreshed.Id || strings.Contains(sess.RefreshTokenHash, refreshed.RefreshToken) {
+		t.Errorf("expected the last access token and the hash of the refresh token, got %+v", sess)
+	}
+}
+
+func TestRefreshExpiredSession(t *testing.T) {
+	defer setupDatabase(t)()
+	setupSigningKeys(t)
+	profile := &types.PlayerClaims{Login: "alice", Provider: types.LocalProvider}
+	if err := NewSession(profile); err != nil {
+		t.Fatal(err)
+	}
+	s := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.SessionKind).
+		Resources(strings.ToLower(types.SessionKind))
+	_, err := s.UpdateFunc(profile.SessionID, func(obj types.Object) error {
+		obj.(*types.Session).ExpiresAt = time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, _ := refresh(t, profile.RefreshToken); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected the expired session to be refused, got %d", w.Code)
+	}
+}
+
+func TestLogoutRevokesTheSession(t *testing.T) {
+	defer setupDatabase(t)()
+	setupSigningKeys(t)
+	profile := &types.PlayerClaims{Login: "alice", Provider: types.LocalProvider}
+	if err := NewSession(profile); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("POST", "/v1/auth/logout", nil)
+	context.Set(r, "player", profile)
+	defer context.Clear(r)
+	w := httptest.NewRecorder()
+	logoutHandler(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body)
+	}
+	if !IsSessionRevoked(profile.SessionID) || !IsTokenRevoked(profile.Id) {
+		t.Error("expected the session and its access token to be revoked")
+	}
+	if w, _ := refresh(t, profile.RefreshToken); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected the refresh token of the session to be refused, got %d", w.Code)
+	}
+
+	// without a player
+	w = httptest.NewRecorder()
+	logoutHandler(w, httptest.NewRequest("POST", "/v1/auth/logout", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a player, got %d", w.Code)
+	}
+}
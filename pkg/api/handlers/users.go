@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+var User = user{}
+
+type user struct{}
+
+func (c *user) HandlerList() HandlerFn {
+	return userListHandler
+}
+
+func (c *user) Handler() HandlerFn {
+	return userHandler
+}
+
+func (c *user) Middlewares() []mux.MiddlewareFunc {
+	return []mux.MiddlewareFunc{}
+}
+
+func userHandler(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	switch r.Method {
+	case "DELETE":
+		err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.UserKind).
+			Resources(strings.ToLower(types.UserKind)).
+			Delete(params["resourceName"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// the sessions of the account couldn't be refreshed and their access tokens are denied
+		subject := types.Subject(types.LocalProvider, params["resourceName"])
+		if err := revokeSubjectSessions(subject); err != nil {
+			msg := fmt.Sprintf("the user was deleted, but revoking its sessions failed: %v", err)
+			http.Error(w, msg, http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(204)
+	case "GET":
+		obj, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.UserKind).
+			Resources(strings.ToLower(types.UserKind)).
+			Get(params["resourceName"])
+		if err != nil {
+			http.Error(w, err.Error(), storeErrorStatus(err))
+			return
+		}
+		NewResponse(w).WriteJSON(obj)
+	case "PUT":
+		req := context.Get(r, "payload")
+		new, ok := req.(*types.User)
+		if !ok {
+			http.Error(w, "unknown type found", http.StatusBadRequest)
+			return
+		}
+		obj, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.UserKind).
+			Resources(strings.ToLower(types.UserKind)).
+			Get(params["resourceName"])
+		if err != nil {
+			http.Error(w, err.Error(), storeErrorStatus(err))
+			return
+		}
+		old := obj.(*types.User)
+		// Keep the current password unless a new one is provided
+		new.PasswordHash = old.PasswordHash
+		if new.Password != "" {
+			if err := hashUserPassword(new); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		obj, err = store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.UserKind).
+			Resources(
+				strings.ToLower(types.UserKind),
+				params["resourceName"],
+			).Update(old, new)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		NewResponse(w).WriteJSON(new)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+func userListHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		req := context.Get(r, "payload")
+		u, ok := req.(*types.User)
+		if !ok {
+			http.Error(w, "unknown type found", http.StatusBadRequest)
+			return
+		}
+		if u.Password == "" {
+			http.Error(w, "missing user password", http.StatusBadRequest)
+			return
+		}
+		if err := hashUserPassword(u); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.UserKind).
+			Resources(strings.ToLower(types.UserKind), u.Name).
+			SaveObject(u)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		NewResponse(w).Status(201).WriteJSON(u)
+	case "GET":
+		opts, err := listOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		page, err := store.New(dbConfig.file, dbConfig.bucket).
+			Kind(types.UserKind).
+			Resources(strings.ToLower(types.UserKind)).
+			ListPage(regexp.MustCompile(`^\/user`), opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		items := types.UserList{ListMeta: newListMeta(page)}
+		for _, obj := range page.Items {
+			items.Items = append(items.Items, *obj.(*types.User))
+		}
+		items.Kind = "List"
+		NewResponse(w).WriteJSON(&items)
+	default:
+		http.Error(w, "Not Implemented", http.StatusNotImplemented)
+	}
+}
+
+// hashUserPassword replaces the plain text password of the user by its bcrypt hash
+func hashUserPassword(u *types.User) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed hashing password: %v", err)
+	}
+	u.PasswordHash = string(hash)
+	u.Password = ""
+	return nil
+}
+
+// authenticateLocalUser validates the credentials against a local user account
+func authenticateLocalUser(username, password string) (*types.PlayerClaims, error) {
+	obj, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.UserKind).
+		Resources(strings.ToLower(types.UserKind)).
+		Get(username)
+	if err != nil {
+		return nil, err
+	}
+	u := obj.(*types.User)
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+		return nil, err
+	}
+	return &types.PlayerClaims{
+		Name:     u.DisplayName,
+		Login:    u.Name,
+		Email:    u.Email,
+		Provider: types.LocalProvider,
+	}, nil
+}
@@ -4,18 +4,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
+	"strconv"
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
+	apiauth "github.com/kubeplay/gameserver/pkg/api/auth"
+	"github.com/kubeplay/gameserver/pkg/selector"
+	"github.com/kubeplay/gameserver/pkg/store"
 	"github.com/kubeplay/gameserver/pkg/types"
 )
 
 type HandlerFn func(w http.ResponseWriter, r *http.Request)
 
 var (
-	jwtSecret = []byte(os.Getenv("JWT_SECRET"))
-	dbConfig  = struct {
+	signingKeys  *apiauth.KeySet
+	policiesFile = apiauth.DefaultPoliciesFile
+	dbConfig     = struct {
 		file   string
 		bucket string
 	}{
@@ -24,6 +28,17 @@ var (
 	}
 )
 
+// SetDatabase configures the bbolt file and the bucket where objects are stored
+func SetDatabase(file, bucket string) {
+	dbConfig.file = file
+	dbConfig.bucket = bucket
+}
+
+// SetPoliciesFile configures where the casbin policies are persisted
+func SetPoliciesFile(path string) {
+	policiesFile = path
+}
+
 func NewResponse(w http.ResponseWriter) *HttpResponse {
 	return &HttpResponse{
 		statusCode: 200,
@@ -48,7 +63,9 @@ func (r *HttpResponse) WriteRawJSON(rawObj []byte) error {
 	return err
 }
 
+// WriteJSON encodes the object in the response, its secrets are removed before
 func (r *HttpResponse) WriteJSON(obj types.Object) error {
+	removeSecrets(obj)
 	r.response.Header().Set("Content-Type", "application/json")
 	data, err := json.Marshal(obj)
 	if err != nil {
@@ -59,28 +76,131 @@ func (r *HttpResponse) WriteJSON(obj types.Object) error {
 	return err
 }
 
+// listSelector parses the label and field selectors of a list request, the
+// secrets aren't returned by the API and couldn't be selected.
+func listSelector(r *http.Request) (*selector.Selector, error) {
+	q := r.URL.Query()
+	sel, err := selector.Parse(q.Get(selector.LabelSelectorParam), q.Get(selector.FieldSelectorParam))
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range secretFields {
+		if sel.SelectsField(field) {
+			return nil, fmt.Errorf("invalid field selector, the field %q couldn't be selected", field)
+		}
+	}
+	return sel, nil
+}
+
+// listOptions parses the selectors and the page of a list request
+func listOptions(r *http.Request) (store.ListOptions, error) {
+	opts := store.ListOptions{Continue: r.URL.Query().Get("continue")}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		n, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("invalid limit %q, expected a positive number", limit)
+		}
+		opts.Limit = n
+	}
+	sel, err := listSelector(r)
+	if err != nil {
+		return opts, err
+	}
+	if !sel.Empty() {
+		opts.Match = sel.Matches
+	}
+	return opts, nil
+}
+
+// addMatch narrows the objects matched by the list options
+func addMatch(opts *store.ListOptions, match func(obj types.Object) bool) {
+	prev := opts.Match
+	opts.Match = func(obj types.Object) bool {
+		return match(obj) && (prev == nil || prev(obj))
+	}
+}
+
+// useIndex scans the first index of the kind which serves a field of the
+// selector, the selector still matches the objects read from the index.
+func useIndex(opts *store.ListOptions, kind string, sel *selector.Selector) {
+	for _, idx := range store.Indexes[kind] {
+		if value, ok := sel.ExactMatch(idx.Field); ok {
+			opts.Index, opts.IndexValue = idx.Name, value
+			return
+		}
+	}
+}
+
+func newListMeta(page *store.Page) types.ListMeta {
+	return types.ListMeta{Continue: page.Continue, RemainingItemCount: page.RemainingItemCount}
+}
+
+// secretFields are the JSON fields cleared by removeSecrets
+var secretFields = []string{"passwordHash", "refreshTokenHash", "secret"}
+
+// removeSecrets clears the fields which are never returned by the API
+func removeSecrets(obj types.Object) {
+	switch o := obj.(type) {
+	case *types.User:
+		o.PasswordHash = ""
+	case *types.UserList:
+		for i := range o.Items {
+			removeSecrets(&o.Items[i])
+		}
+	case *types.Session:
+		o.RefreshTokenHash = ""
+	case *types.SessionList:
+		for i := range o.Items {
+			removeSecrets(&o.Items[i])
+		}
+	case *types.EventHook:
+		o.Secret = ""
+	case *types.EventHookList:
+		for i := range o.Items {
+			removeSecrets(&o.Items[i])
+		}
+	}
+}
+
+// SetSigningKeys configures the keys used to sign and verify player tokens
+func SetSigningKeys(ks *apiauth.KeySet) {
+	signingKeys = ks
+}
+
 // GenerateNewJwtToken creates a new user token to allow machine-to-machine interaction
-func GenerateNewJwtToken(key []byte, p *types.PlayerClaims, exp time.Time) error {
-	token := jwt.New(jwt.SigningMethodHS256)
+func GenerateNewJwtToken(p *types.PlayerClaims, exp time.Time) error {
+	if signingKeys == nil {
+		return fmt.Errorf("signing keys not configured")
+	}
+	key := signingKeys.Signer()
 	p.ExpiresAt = exp.UTC().Unix()
 	p.IssuedAt = time.Now().UTC().Unix()
-	token.Claims = p
+	p.Subject = p.Username()
+	p.Id = store.NewUUID()
+	token := jwt.NewWithClaims(key.Method, p)
+	token.Header["kid"] = key.ID
 	// Sign and get the complete encoded token as a string
 	var err error
-	p.AccessToken, err = token.SignedString(key)
+	p.AccessToken, err = token.SignedString(key.Private)
 	return err
 }
 
-// DecodeUserToken decodes a jwtToken (HS256 and RS256)
-func DecodeUserToken(jwtTokenString, jwtSecret string) (*types.PlayerClaims, error) {
+// DecodeUserToken decodes a jwtToken (RS256 and ES256) verifying it with the key found by its "kid"
+func DecodeUserToken(jwtTokenString string) (*types.PlayerClaims, error) {
+	if signingKeys == nil {
+		return nil, fmt.Errorf("signing keys not configured")
+	}
 	player := &types.PlayerClaims{}
 	token, err := jwt.ParseWithClaims(jwtTokenString, player, func(token *jwt.Token) (interface{}, error) {
-		switch t := token.Method.(type) {
-		case *jwt.SigningMethodHMAC:
-			return []byte(jwtSecret), nil
-		default:
-			return nil, fmt.Errorf("unknown sign method [%v]", t)
+		kid, _ := token.Header["kid"].(string)
+		key := signingKeys.Lookup(kid)
+		if key == nil {
+			return nil, fmt.Errorf("unknown signing key [%v]", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected sign method [%v]", token.Method.Alg())
 		}
+		return key.Public(), nil
 	})
 	if err == nil && token.Valid {
 		return player, nil
@@ -98,3 +218,12 @@ func DecodeUserToken(jwtTokenString, jwtSecret string) (*types.PlayerClaims, err
 		return nil, fmt.Errorf("unknown error, failed decoding token [%v]", err)
 	}
 }
+
+// storeErrorStatus returns the status of a failed store operation, objects which
+// don't exist are not found and the remaining errors are bad requests.
+func storeErrorStatus(err error) int {
+	if store.IsNotFound(err) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+func TestListRemovesSecrets(t *testing.T) {
+	defer setupDatabase(t)()
+	u := &types.User{
+		TypeMeta:     types.TypeMeta{Kind: types.UserKind},
+		Metadata:     types.Metadata{Name: "alice"},
+		PasswordHash: "$2a$10$secret",
+	}
+	_, err := store.New(dbConfig.file, dbConfig.bucket).
+		Kind(types.UserKind).
+		Resources(strings.ToLower(types.UserKind), u.Name).
+		SaveObject(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{name: "without selectors", wantStatus: http.StatusOK},
+		{name: "with a selector", query: "?fieldSelector=metadata.name%3Dalice", wantStatus: http.StatusOK},
+		{name: "selecting a secret", query: "?fieldSelector=passwordHash%3D%242a", wantStatus: http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			userListHandler(w, httptest.NewRequest("GET", "/v1/users"+tc.query, nil))
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected the status %d, got %d: %s", tc.wantStatus, w.Code, w.Body)
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			if !strings.Contains(w.Body.String(), `"alice"`) {
+				t.Errorf("expected the user in the list, got %s", w.Body)
+			}
+			if strings.Contains(w.Body.String(), "secret") {
+				t.Errorf("expected the password hash to be removed, got %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestWriteJSONRemovesSecrets(t *testing.T) {
+	for _, obj := range []types.Object{
+		&types.User{PasswordHash: "secret"},
+		&types.UserList{Items: []types.User{{PasswordHash: "secret"}}},
+		&types.Session{RefreshTokenHash: "secret"},
+		&types.SessionList{Items: []types.Session{{RefreshTokenHash: "secret"}}},
+		&types.EventHook{Secret: "secret"},
+		&types.EventHookList{Items: []types.EventHook{{Secret: "secret"}}},
+	} {
+		w := httptest.NewRecorder()
+		if err := NewResponse(w).WriteJSON(obj); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("expected the secrets of %T to be removed, got %s", obj, w.Body)
+		}
+	}
+}
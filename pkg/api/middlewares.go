@@ -1,72 +1,206 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/context"
-	"github.com/kubeplay/gameserver/pkg/api/auth"
+	"github.com/gorilla/mux"
+	apiauth "github.com/kubeplay/gameserver/pkg/api/auth"
 	"github.com/kubeplay/gameserver/pkg/api/handlers"
+	"github.com/kubeplay/gameserver/pkg/audit"
+	"github.com/kubeplay/gameserver/pkg/bundle"
+	"github.com/kubeplay/gameserver/pkg/metrics"
+	"github.com/kubeplay/gameserver/pkg/selector"
+	"github.com/kubeplay/gameserver/pkg/store"
 	"github.com/kubeplay/gameserver/pkg/types"
 	"github.com/sirupsen/logrus"
 )
 
-func authenticationMiddleware(next http.Handler) http.Handler {
+// statusRecorder keeps the status code written by the handlers
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// metricsMiddleware measures the requests by the route template, using the
+// template instead of the path prevents a metric for every game or event.
+func metricsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		logrus.WithField("method", r.Method).Info("AUTHENTICATION MIDDLEWARE")
-		if strings.HasSuffix(r.RequestURI, "/v1/login") {
+		route := routeTemplate(r)
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		code := strconv.Itoa(rec.statusCode)
+		metrics.RequestsTotal.WithLabelValues(r.Method, route, code).Inc()
+		metrics.RequestDuration.WithLabelValues(r.Method, route, code).Observe(time.Since(start).Seconds())
+	})
+}
+
+func routeTemplate(r *http.Request) string {
+	if cr := mux.CurrentRoute(r); cr != nil {
+		if tpl, err := cr.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return "unknown"
+}
+
+// auditMiddleware records the logins and the mutating requests (including starting and
+// solving games) with the subject, the outcome and the body allowed by the audit policy.
+func auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a := Config.Auditor
+		switch {
+		case r.Method == "POST", r.Method == "PUT", r.Method == "PATCH", r.Method == "DELETE":
+		case r.URL.Path == "/v1/login":
+			// login attempts aren't mutating requests, but they're recorded as well
+		default:
 			next.ServeHTTP(w, r)
 			return
 		}
-		parts := strings.Split(r.Header.Get("Authorization"), " ")
-		if len(parts) != 2 || len(parts) == 2 && parts[0] != "Bearer" {
-			// fmt.Printf("PARTS %#v\n", r.Header.Get("Authorization"))
-			logrus.WithField("method", r.Method).Infof("Missing Authorization header - 403 / not implemented yet!")
-			// FORBIDDEN
-
-			// http.Error(w, "Forbidden", http.StatusForbidden)
+		level := audit.LevelNone
+		if a != nil {
+			level = a.Policy.LevelFor(r.Method, r.URL.Path)
+		}
+		if level == audit.LevelNone {
 			next.ServeHTTP(w, r)
 			return
 		}
-		switch t := parts[1]; {
-		case strings.HasPrefix(t, "gamekey:"):
-			logrus.WithField("key", t).Infof("Game Key Token")
-			// remove the header "gamekey:"
-			context.Set(r, "gamekey", t[8:])
-		case strings.HasPrefix(t, "token-"):
-			// API Key Token
-			logrus.Infof("API Based token, not implemented yet")
-		default:
-			pl, err := handlers.DecodeUserToken(parts[1], os.Getenv("JWT_SECRET"))
+		e := &audit.Event{
+			ID:         store.NewUUID(),
+			Timestamp:  audit.Now(),
+			Level:      level,
+			RemoteAddr: r.RemoteAddr,
+			UserAgent:  r.UserAgent(),
+			Verb:       r.Method,
+			Path:       r.URL.Path,
+			Route:      routeTemplate(r),
+		}
+		if domain := apiauth.Domain(r.URL.Path); domain != apiauth.AllDomains {
+			e.Event = domain
+		}
+		if level == audit.LevelRequest && r.Body != nil {
+			body, err := ioutil.ReadAll(r.Body)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusForbidden)
+				http.Error(w, fmt.Sprintf("failed reading body: %v", err), http.StatusBadRequest)
 				return
 			}
-			context.Set(r, "player", pl)
-			e, err := auth.NewEnforcer(UserPoliciesFile)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusForbidden)
-				return
+			// the decoder middleware reads the body again
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			e.RequestBody = audit.RedactBody(body)
+		}
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		e.LatencyMs = float64(time.Since(start)) / float64(time.Millisecond)
+		e.StatusCode = rec.statusCode
+		e.Outcome = audit.Outcome(rec.statusCode)
+		e.Annotations = audit.Annotations(r)
+		if pl, ok := context.Get(r, "player").(*types.PlayerClaims); ok {
+			e.Subject = pl.Username()
+			e.Roles = pl.Roles
+		} else if login, _, ok := r.BasicAuth(); ok {
+			// login attempts
+			provider := r.URL.Query().Get("provider")
+			if provider == "" {
+				provider = types.GitHubProvider
 			}
-			if e.Enforce(pl.Username(), r.URL.Path, r.Method) == true {
-				fmt.Printf("%q CAN %q %q\n", pl.Username(), r.Method, r.URL.Path)
-			} else {
-				fmt.Printf("%q CANNOT %q %q\n", pl.Username(), r.Method, r.URL.Path)
+			e.Subject = types.Subject(provider, login)
+		}
+		a.Log(e)
+	})
+}
+
+func authenticationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logrus.WithField("method", r.Method).Debug("AUTHENTICATION MIDDLEWARE")
+		switch r.URL.Path {
+		case "/v1/login", "/v1/refresh", "/v1/.well-known/jwks.json", "/healthz", "/readyz", "/metrics", "/v1", "/openapi/v1":
+			next.ServeHTTP(w, r)
+			return
+		}
+		parts := strings.Split(r.Header.Get("Authorization"), " ")
+		if len(parts) != 2 || parts[0] != "Bearer" || parts[1] == "" {
+			// Client certificates are verified by the TLS handshake
+			if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+				pl, err := types.ClientCertificateClaims(r.TLS.VerifiedChains[0][0])
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+				context.Set(r, "player", pl)
+				if authorize(w, r, pl) {
+					next.ServeHTTP(w, r)
+				}
+				return
 			}
+			logrus.WithField("method", r.Method).Debug("Missing Authorization header")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		pl, err := handlers.DecodeUserToken(parts[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if handlers.IsTokenRevoked(pl.Id) || handlers.IsSessionRevoked(pl.SessionID) {
+			http.Error(w, "the token was revoked", http.StatusUnauthorized)
+			return
+		}
+		context.Set(r, "player", pl)
+		if authorize(w, r, pl) {
+			next.ServeHTTP(w, r)
 		}
-		next.ServeHTTP(w, r)
 	})
 }
 
+// authorize evaluates the policies for the player, the request is denied when
+// they don't allow it or when they couldn't be evaluated.
+func authorize(w http.ResponseWriter, r *http.Request, pl *types.PlayerClaims) bool {
+	allowed, err := handlers.Authorize(pl, r.URL.Path, r.Method)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed evaluating policies")
+		http.Error(w, "failed evaluating policies", http.StatusInternalServerError)
+		return false
+	}
+	logrus.WithFields(logrus.Fields{
+		"subject": pl.Username(),
+		"method":  r.Method,
+		"path":    r.URL.Path,
+		"roles":   pl.Roles,
+		"allowed": allowed,
+	}).Debug("Policy decision")
+	if !allowed {
+		msg := fmt.Sprintf("%q is not allowed to %s %s", pl.Username(), r.Method, r.URL.Path)
+		http.Error(w, msg, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
 func decoderMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		logrus.WithField("method", r.Method).Info("GLOBAL MIDDLEWARE")
+		logrus.WithField("method", r.Method).Debug("GLOBAL MIDDLEWARE")
 		switch r.Method {
 		case "POST", "PUT", "PATCH":
+			// archives and exports are decoded by their handlers
+			if ct := r.Header.Get("Content-Type"); ct == bundle.MediaType || ct == types.ExportMediaType {
+				next.ServeHTTP(w, r)
+				return
+			}
 			payload, err := ioutil.ReadAll(r.Body)
 			if err != nil {
 				msg := fmt.Sprintf("failed reading body: %v", err)
@@ -83,12 +217,27 @@ func decoderMiddleware(next http.Handler) http.Handler {
 				http.Error(w, msg, http.StatusBadRequest)
 				return
 			}
+			// objects without apiVersion have the version of the path
+			if typeMeta.APIVersion == "" {
+				typeMeta.APIVersion = RequestVersion(r)
+			}
+			payload, err = types.ConvertToHub(typeMeta.APIVersion, typeMeta.Kind, payload)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 			obj, err := types.Decode(typeMeta, payload)
 			if err != nil {
 				msg := fmt.Sprintf("failed decoding object %v: %v", typeMeta.Kind, err)
 				http.Error(w, msg, http.StatusBadRequest)
 				return
 			}
+			if obj != nil && obj.GetObjectMeta() != nil {
+				if err := selector.ValidateLabels(obj.GetObjectMeta().Labels); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
 			context.Set(r, "payload", obj)
 			next.ServeHTTP(w, r)
 		default:
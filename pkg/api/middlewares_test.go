@@ -0,0 +1,188 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/context"
+	apiauth "github.com/kubeplay/gameserver/pkg/api/auth"
+	"github.com/kubeplay/gameserver/pkg/api/handlers"
+	"github.com/kubeplay/gameserver/pkg/audit"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// setupAuth configures the database, the policies and the signing keys of
+// the handlers in a temporary directory and grants the host role to local|admin.
+func setupAuth(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "kubeplay-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "signing.pem")
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ks, err := apiauth.LoadKeySet(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlers.SetSigningKeys(ks)
+	handlers.SetDatabase(filepath.Join(dir, "kubeplay.db"), "kubeplay")
+	handlers.SetPoliciesFile(filepath.Join(dir, "policies.csv"))
+	if err := handlers.BootstrapRoleBinding("bootstrap", "local|admin", apiauth.HostRole); err != nil {
+		t.Fatal(err)
+	}
+	if err := handlers.SyncPolicies(); err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+// newToken starts a session for the login and returns its access token
+func newToken(t *testing.T, login string) string {
+	pl := &types.PlayerClaims{Login: login, Provider: types.LocalProvider}
+	if err := handlers.NewSession(pl); err != nil {
+		t.Fatal(err)
+	}
+	return pl.AccessToken
+}
+
+func TestAuthenticationMiddleware(t *testing.T) {
+	dir, teardown := setupAuth(t)
+	defer teardown()
+
+	adminToken := newToken(t, "admin")
+	guestToken := newToken(t, "guest")
+	// the access token outlives its session
+	revoked := &types.PlayerClaims{Login: "admin", Provider: types.LocalProvider, SessionID: "missing"}
+	if err := handlers.GenerateNewJwtToken(revoked, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		method     string
+		path       string
+		header     string
+		policies   string
+		wantStatus int
+	}{
+		{name: "exempt path", method: "POST", path: "/v1/login", wantStatus: http.StatusOK},
+		{name: "discovery", method: "GET", path: "/v1", wantStatus: http.StatusOK},
+		{name: "missing header", method: "GET", path: "/v1/events", wantStatus: http.StatusUnauthorized},
+		{name: "not a bearer token", method: "GET", path: "/v1/events", header: "Basic " + adminToken, wantStatus: http.StatusUnauthorized},
+		{name: "invalid token", method: "GET", path: "/v1/events", header: "Bearer invalid", wantStatus: http.StatusUnauthorized},
+		{name: "revoked session", method: "GET", path: "/v1/events", header: "Bearer " + revoked.AccessToken, wantStatus: http.StatusUnauthorized},
+		{name: "guest allowed", method: "GET", path: "/v1/events", header: "Bearer " + guestToken, wantStatus: http.StatusOK},
+		{name: "guest forbidden", method: "POST", path: "/v1/events", header: "Bearer " + guestToken, wantStatus: http.StatusForbidden},
+		{name: "host allowed", method: "POST", path: "/v1/events", header: "Bearer " + adminToken, wantStatus: http.StatusOK},
+		{
+			name:       "policies couldn't be evaluated",
+			method:     "GET",
+			path:       "/v1/events",
+			header:     "Bearer " + adminToken,
+			policies:   filepath.Join(dir, "missing.csv"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.policies != "" {
+				handlers.SetPoliciesFile(tc.policies)
+				defer handlers.SetPoliciesFile(filepath.Join(dir, "policies.csv"))
+			}
+			called := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			})
+			r := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.header != "" {
+				r.Header.Set("Authorization", tc.header)
+			}
+			w := httptest.NewRecorder()
+			authenticationMiddleware(next).ServeHTTP(w, r)
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected the status %d, got %d: %s", tc.wantStatus, w.Code, w.Body)
+			}
+			if called != (tc.wantStatus == http.StatusOK) {
+				t.Errorf("expected the next handler to be called only when allowed, called: %v", called)
+			}
+		})
+	}
+}
+
+type memorySink struct {
+	events []*audit.Event
+}
+
+func (s *memorySink) Write(e *audit.Event) error {
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *memorySink) Close() error { return nil }
+
+func TestAuditMiddleware(t *testing.T) {
+	sink := &memorySink{}
+	Config.Auditor = audit.New(audit.DefaultPolicy(), sink)
+	defer func() { Config.Auditor = nil }()
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		context.Set(r, "player", &types.PlayerClaims{Login: "alice", Provider: types.LocalProvider, Roles: []string{"judge"}})
+		audit.Annotate(r, "key", "k1")
+		if r.URL.Path == "/v1/events/meetup/games/g1" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	})
+	for _, tc := range []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{method: "GET", path: "/v1/events/meetup/games"},
+		{method: "POST", path: "/v1/events/meetup/games/g1/solve", body: `{"kind":"Game","value":"secret"}`},
+		{method: "DELETE", path: "/v1/events/meetup/games/g1"},
+	} {
+		r := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+		auditMiddleware(next).ServeHTTP(httptest.NewRecorder(), r)
+		context.Clear(r)
+	}
+	// the reads aren't recorded
+	if len(sink.events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(sink.events))
+	}
+	solve, del := sink.events[0], sink.events[1]
+	if solve.Level != audit.LevelRequest || solve.Subject != "local|alice" || solve.Event != "meetup" {
+		t.Errorf("unexpected event %+v", solve)
+	}
+	if solve.Outcome != audit.OutcomeSuccess || solve.Annotations["key"] != "k1" || len(solve.Roles) != 1 {
+		t.Errorf("expected the outcome, the roles and the annotations of the request, got %+v", solve)
+	}
+	if !bytes.Contains(solve.RequestBody, []byte(`"kind":"Game"`)) || bytes.Contains(solve.RequestBody, []byte("secret")) {
+		t.Errorf("expected the body without secrets, got %s", solve.RequestBody)
+	}
+	if del.Level != audit.LevelMetadata || del.RequestBody != nil {
+		t.Errorf("expected only the metadata of the deletion, got %+v", del)
+	}
+	if del.StatusCode != http.StatusForbidden || del.Outcome != audit.OutcomeFailure {
+		t.Errorf("expected a failed deletion, got %d %s", del.StatusCode, del.Outcome)
+	}
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+type versionKey struct{}
+
+// RequestVersion returns the apiVersion of the path of a request, e.g.: kubeplay.io/v1beta1
+func RequestVersion(r *http.Request) string {
+	if v, ok := r.Context().Value(versionKey{}).(string); ok {
+		return v
+	}
+	return types.GroupVersion(types.HubVersion)
+}
+
+// VersionHandler serves every version with the routes of the hub (/v1), the
+// paths of the other versions are rewritten and their JSON responses are
+// converted from the hub. The bodies of the requests are converted by the decoder.
+func (c *config) VersionHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := servedVersion(r.URL.Path)
+		if name == "" || name == types.HubVersion {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r.URL.Path = c.Version + strings.TrimPrefix(r.URL.Path, "/"+name)
+		r.URL.RawPath = ""
+		r = r.WithContext(context.WithValue(r.Context(), versionKey{}, types.GroupVersion(name)))
+		vw := &versionWriter{ResponseWriter: w, version: name}
+		next.ServeHTTP(vw, r)
+		vw.finish()
+	})
+}
+
+// servedVersion returns the version of a path, e.g.: v1beta1 for /v1beta1/games
+func servedVersion(path string) string {
+	for _, v := range types.ServedVersions {
+		if prefix := "/" + v.Name; path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return v.Name
+		}
+	}
+	return ""
+}
+
+// versionWriter buffers the JSON responses to convert them, other responses
+// (e.g.: assets and errors) are written as they are.
+type versionWriter struct {
+	http.ResponseWriter
+	version     string
+	status      int
+	wroteHeader bool
+	buf         *bytes.Buffer
+}
+
+func (w *versionWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		w.buf = &bytes.Buffer{}
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *versionWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buf != nil {
+		return w.buf.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *versionWriter) finish() {
+	if w.buf == nil {
+		return
+	}
+	data, err := convertResponse(w.version, w.buf.Bytes())
+	if err != nil {
+		http.Error(w.ResponseWriter, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(data)
+}
+
+// convertResponse converts an object or the items of a list from the hub
+func convertResponse(version string, data []byte) ([]byte, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		// not an object, e.g.: the JSON Web Key Set
+		return data, nil
+	}
+	var objects []map[string]interface{}
+	if items, ok := fields["items"].([]interface{}); ok {
+		for _, item := range items {
+			if obj, ok := item.(map[string]interface{}); ok {
+				objects = append(objects, obj)
+			}
+		}
+	} else {
+		objects = append(objects, fields)
+	}
+	for _, obj := range objects {
+		if !isRegisteredKind(obj["kind"]) {
+			continue
+		}
+		if err := types.ConvertFromHub(version, obj); err != nil {
+			return nil, err
+		}
+	}
+	return append(mustMarshal(fields), '\n'), nil
+}
+
+func isRegisteredKind(kind interface{}) bool {
+	for _, obj := range types.RegisteredTypes {
+		if obj.GetObjectKind() == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func mustMarshal(v interface{}) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// ServedPaths returns the path prefixes of the served versions, e.g.: /v1beta1
+func ServedPaths() []string {
+	var paths []string
+	for _, v := range types.ServedVersions {
+		paths = append(paths, "/"+v.Name)
+	}
+	return paths
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+func TestVersionHandler(t *testing.T) {
+	var gotPath, gotVersion string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotVersion = r.URL.Path, RequestVersion(r)
+		switch r.URL.Path {
+		case "/v1/events/meetup/games":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"kind":"List","items":[{"kind":"Game","apiVersion":"kubeplay.io/v1","metadata":{"name":"g1"},"challenge":"foo","challengeGeneration":2}]}`)
+		case "/v1/events/meetup/games/g1":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"kind":"Game","apiVersion":"kubeplay.io/v1","metadata":{"name":"g1"},"challenge":"foo"}`)
+		case "/v1/.well-known/jwks.json":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `[]`)
+		case "/v1/assets/digest":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			fmt.Fprint(w, "asset")
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	})
+	h := Config.VersionHandler(next)
+
+	for _, tc := range []struct {
+		name        string
+		path        string
+		wantPath    string
+		wantVersion string
+		wantStatus  int
+		check       func(t *testing.T, body []byte)
+	}{
+		{
+			name:        "hub",
+			path:        "/v1/events/meetup/games/g1",
+			wantPath:    "/v1/events/meetup/games/g1",
+			wantVersion: "kubeplay.io/v1",
+			wantStatus:  http.StatusCreated,
+			check: func(t *testing.T, body []byte) {
+				var gm map[string]interface{}
+				json.Unmarshal(body, &gm)
+				if gm["challenge"] != "foo" || gm["challengeRef"] != nil {
+					t.Errorf("expected the hub to be served as it is, got %s", body)
+				}
+			},
+		},
+		{
+			name:        "object",
+			path:        "/v1beta1/events/meetup/games/g1",
+			wantPath:    "/v1/events/meetup/games/g1",
+			wantVersion: "kubeplay.io/v1beta1",
+			wantStatus:  http.StatusCreated,
+			check: func(t *testing.T, body []byte) {
+				var gm map[string]interface{}
+				json.Unmarshal(body, &gm)
+				ref, _ := gm["challengeRef"].(map[string]interface{})
+				if gm["apiVersion"] != "kubeplay.io/v1beta1" || gm["challenge"] != nil || ref["name"] != "foo" {
+					t.Errorf("expected a v1beta1 game, got %s", body)
+				}
+			},
+		},
+		{
+			name:        "items of lists",
+			path:        "/v1beta1/events/meetup/games",
+			wantPath:    "/v1/events/meetup/games",
+			wantVersion: "kubeplay.io/v1beta1",
+			wantStatus:  http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var list struct {
+					Kind  string                   `json:"kind"`
+					Items []map[string]interface{} `json:"items"`
+				}
+				json.Unmarshal(body, &list)
+				if list.Kind != "List" || len(list.Items) != 1 {
+					t.Fatalf("expected a list with a game, got %s", body)
+				}
+				ref, _ := list.Items[0]["challengeRef"].(map[string]interface{})
+				if list.Items[0]["apiVersion"] != "kubeplay.io/v1beta1" || ref["generation"] != float64(2) {
+					t.Errorf("expected the items converted to v1beta1, got %s", body)
+				}
+			},
+		},
+		{
+			name:        "JSON which isn't an object",
+			path:        "/v1beta1/.well-known/jwks.json",
+			wantPath:    "/v1/.well-known/jwks.json",
+			wantVersion: "kubeplay.io/v1beta1",
+			wantStatus:  http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				if string(body) != "[]" {
+					t.Errorf("expected the body as it is, got %s", body)
+				}
+			},
+		},
+		{
+			name:        "not JSON",
+			path:        "/v1beta1/assets/digest",
+			wantPath:    "/v1/assets/digest",
+			wantVersion: "kubeplay.io/v1beta1",
+			wantStatus:  http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				if string(body) != "asset" {
+					t.Errorf("expected the asset as it is, got %s", body)
+				}
+			},
+		},
+		{
+			name:        "errors",
+			path:        "/v1beta1/missing",
+			wantPath:    "/v1/missing",
+			wantVersion: "kubeplay.io/v1beta1",
+			wantStatus:  http.StatusNotFound,
+		},
+		{
+			name:        "not a served version",
+			path:        "/v1beta1x/events",
+			wantPath:    "/v1beta1x/events",
+			wantVersion: "kubeplay.io/v1",
+			wantStatus:  http.StatusNotFound,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+			if w.Code != tc.wantStatus {
+				t.Fatalf("expected the status %d, got %d: %s", tc.wantStatus, w.Code, w.Body)
+			}
+			if gotPath != tc.wantPath || gotVersion != tc.wantVersion {
+				t.Errorf("expected the path %s of %s, got %s of %s", tc.wantPath, tc.wantVersion, gotPath, gotVersion)
+			}
+			if tc.check != nil {
+				tc.check(t, w.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestServedVersion(t *testing.T) {
+	for path, want := range map[string]string{
+		"/v1":            types.HubVersion,
+		"/v1/events":     types.HubVersion,
+		"/v1beta1":       "v1beta1",
+		"/v1beta1/games": "v1beta1",
+		"/v1beta10":      "",
+		"/healthz":       "",
+	} {
+		if got := servedVersion(path); got != want {
+			t.Errorf("%s: expected the version %q, got %q", path, want, got)
+		}
+	}
+}
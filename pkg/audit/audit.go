@@ -0,0 +1,271 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/context"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Level defines how much of a request is recorded
+type Level string
+
+const (
+	// LevelNone doesn't record the request
+	LevelNone Level = "None"
+	// LevelMetadata records who did what, on which object and the outcome
+	LevelMetadata Level = "Metadata"
+	// LevelRequest records the metadata and the request body
+	LevelRequest Level = "Request"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+const annotationsContextKey = "audit"
+
+// redactedFields are never recorded, even with the Request level
+var redactedFields = map[string]bool{
+	"password":      true,
+	"passwordHash":  true,
+	"value":         true, // challenge keys
+	"access_token":  true,
+	"refresh_token": true,
+	"token":         true,
+	"secret":        true, // event hooks
+}
+
+// Event is a record of a mutating request
+type Event struct {
+	ID        string `json:"id"`
+	Timestamp string `json:"timestamp"`
+	Level     Level  `json:"level"`
+
+	// Subject is who performed the request, e.g.: github|user
+	Subject    string   `json:"subject,omitempty"`
+	Roles      []string `json:"roles,omitempty"`
+	RemoteAddr string   `json:"remoteAddr"`
+	UserAgent  string   `json:"userAgent,omitempty"`
+
+	Verb  string `json:"verb"`
+	Path  string `json:"path"`
+	Route string `json:"route,omitempty"`
+	// Event is the game event the object belongs to
+	Event string `json:"event,omitempty"`
+
+	StatusCode int     `json:"statusCode"`
+	Outcome    string  `json:"outcome"`
+	LatencyMs  float64 `json:"latencyMs"`
+
+	// Annotations are set by the handlers, e.g.: the key solved in a game
+	Annotations map[string]string `json:"annotations,omitempty"`
+	RequestBody json.RawMessage   `json:"requestBody,omitempty"`
+}
+
+// Sink persists audit events
+type Sink interface {
+	Write(e *Event) error
+	Close() error
+}
+
+// PolicyRule sets the level of the requests matching the path and the verbs
+type PolicyRule struct {
+	// Path is a regular expression matched against the request path
+	Path  string   `json:"path" yaml:"path"`
+	Verbs []string `json:"verbs,omitempty" yaml:"verbs,omitempty"`
+	Level Level    `json:"level" yaml:"level"`
+
+	re *regexp.Regexp
+}
+
+// Policy decides which requests are recorded and if their bodies are included,
+// the first matching rule wins.
+type Policy struct {
+	Level Level        `json:"level" yaml:"level"`
+	Rules []PolicyRule `json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// DefaultPolicy records the metadata of every mutating request and the body
+// of the requests which change the game state.
+func DefaultPolicy() *Policy {
+	p := &Policy{
+		Level: LevelMetadata,
+		Rules: []PolicyRule{
+			{Path: `^/v1/events/[^/]+/games(/[^/]+/(start|solve))?$`, Level: LevelRequest},
+		},
+	}
+	p.compile()
+	return p
+}
+
+// LoadPolicy reads a policy from a YAML file, e.g.:
+//
+//	level: Metadata
+//	rules:
+//	- path: ^/v1/events/
+//	  verbs: [POST]
+//	  level: Request
+func LoadPolicy(file string) (*Policy, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	p := &Policy{}
+	if err := yaml.UnmarshalStrict(data, p); err != nil {
+		return nil, fmt.Errorf("failed decoding audit policy: %v", err)
+	}
+	if p.Level == "" {
+		p.Level = LevelMetadata
+	}
+	return p, p.compile()
+}
+
+func (p *Policy) compile() error {
+	for i, rule := range p.Rules {
+		if err := validLevel(rule.Level); err != nil {
+			return fmt.Errorf("rule %d: %v", i, err)
+		}
+		re, err := regexp.Compile(rule.Path)
+		if err != nil {
+			return fmt.Errorf("rule %d: %v", i, err)
+		}
+		p.Rules[i].re = re
+	}
+	return validLevel(p.Level)
+}
+
+func validLevel(l Level) error {
+	switch l {
+	case LevelNone, LevelMetadata, LevelRequest:
+		return nil
+	}
+	return fmt.Errorf("unknown level %q, expected one of: None, Metadata or Request", l)
+}
+
+// LevelFor returns the level of a request
+func (p *Policy) LevelFor(verb, path string) Level {
+	for _, rule := range p.Rules {
+		if len(rule.Verbs) > 0 && !hasVerb(rule.Verbs, verb) {
+			continue
+		}
+		if rule.re != nil && rule.re.MatchString(path) {
+			return rule.Level
+		}
+	}
+	return p.Level
+}
+
+func hasVerb(verbs []string, verb string) bool {
+	for _, v := range verbs {
+		if strings.EqualFold(v, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+// Auditor records events in all of its sinks
+type Auditor struct {
+	Policy *Policy
+	sinks  []Sink
+}
+
+// New returns an auditor, the default policy is used when policy is nil
+func New(policy *Policy, sinks ...Sink) *Auditor {
+	if policy == nil {
+		policy = DefaultPolicy()
+	}
+	return &Auditor{Policy: policy, sinks: sinks}
+}
+
+// Log writes the event in every sink, a failing sink doesn't affect the others
+func (a *Auditor) Log(e *Event) {
+	for _, s := range a.sinks {
+		if err := s.Write(e); err != nil {
+			logrus.WithError(err).WithField("id", e.ID).Error("Failed writing audit event")
+		}
+	}
+}
+
+// Close flushes and closes the sinks
+func (a *Auditor) Close() error {
+	for _, s := range a.sinks {
+		if err := s.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Annotate adds information about the outcome of a request to its audit event
+func Annotate(r *http.Request, key, value string) {
+	annotations, ok := context.Get(r, annotationsContextKey).(map[string]string)
+	if !ok {
+		annotations = map[string]string{}
+		context.Set(r, annotationsContextKey, annotations)
+	}
+	annotations[key] = value
+}
+
+// Annotations returns the annotations added by the handlers
+func Annotations(r *http.Request) map[string]string {
+	annotations, _ := context.Get(r, annotationsContextKey).(map[string]string)
+	return annotations
+}
+
+// RedactBody removes the secrets of a JSON body, bodies which aren't
+// JSON objects are discarded.
+func RedactBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return nil
+	}
+	redact(obj)
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func redact(v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for key, val := range t {
+			if redactedFields[key] {
+				t[key] = "<redacted>"
+				continue
+			}
+			redact(val)
+		}
+	case []interface{}:
+		for _, val := range t {
+			redact(val)
+		}
+	}
+}
+
+// Outcome returns the outcome of a request by its status code
+func Outcome(statusCode int) string {
+	if statusCode >= 200 && statusCode < 400 {
+		return OutcomeSuccess
+	}
+	return OutcomeFailure
+}
+
+// Now returns the timestamp format of the events
+func Now() string {
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,167 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	p := DefaultPolicy()
+	for _, tc := range []struct {
+		verb string
+		path string
+		want Level
+	}{
+		{verb: "POST", path: "/v1/events/meetup/games", want: LevelRequest},
+		{verb: "POST", path: "/v1/events/meetup/games/g1/start", want: LevelRequest},
+		{verb: "POST", path: "/v1/events/meetup/games/g1/solve", want: LevelRequest},
+		{verb: "DELETE", path: "/v1/events/meetup/games/g1", want: LevelMetadata},
+		{verb: "POST", path: "/v1/challenges", want: LevelMetadata},
+	} {
+		if got := p.LevelFor(tc.verb, tc.path); got != tc.want {
+			t.Errorf("%s %s: expected the level %s, got %s", tc.verb, tc.path, tc.want, got)
+		}
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeplay-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "policy.yaml")
+	for _, tc := range []struct {
+		name    string
+		policy  string
+		wantErr string
+		levels  map[string]Level
+	}{
+		{
+			name: "first matching rule wins",
+			policy: `
+rules:
+- path: ^/v1/users
+  level: None
+- path: ^/v1/events/
+  verbs: [post]
+  level: Request
+- path: ^/v1/events/
+  level: None
+`,
+			levels: map[string]Level{
+				"POST /v1/users":           LevelNone,
+				"POST /v1/events/meetup":   LevelRequest,
+				"DELETE /v1/events/meetup": LevelNone,
+				// the level defaults to Metadata
+				"POST /v1/challenges": LevelMetadata,
+			},
+		},
+		{
+			name:   "default level",
+			policy: "level: None",
+			levels: map[string]Level{"POST /v1/challenges": LevelNone},
+		},
+		{name: "unknown level", policy: "level: All", wantErr: "unknown level"},
+		{name: "unknown level of a rule", policy: "rules: [{path: ^/v1, level: Body}]", wantErr: "rule 0: unknown level"},
+		{name: "invalid path", policy: "rules: [{path: '(', level: None}]", wantErr: "rule 0"},
+		{name: "unknown field", policy: "levle: None", wantErr: "failed decoding audit policy"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ioutil.WriteFile(file, []byte(tc.policy), 0600); err != nil {
+				t.Fatal(err)
+			}
+			p, err := LoadPolicy(file)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for req, want := range tc.levels {
+				parts := strings.SplitN(req, " ", 2)
+				if got := p.LevelFor(parts[0], parts[1]); got != want {
+					t.Errorf("%s: expected the level %s, got %s", req, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	body := RedactBody([]byte(`{"kind":"User","password":"secret","spec":{"keys":[{"name":"k1","value":"secret"}]}}`))
+	if strings.Contains(string(body), "secret") {
+		t.Errorf("expected the secrets to be redacted, got %s", body)
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(body, &obj); err != nil || obj["kind"] != "User" {
+		t.Errorf("expected the other fields to be kept, got %s: %v", body, err)
+	}
+	for _, data := range []string{"", "[1, 2]", "not json"} {
+		if body := RedactBody([]byte(data)); body != nil {
+			t.Errorf("%q: expected the body to be discarded, got %s", data, body)
+		}
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	for code, want := range map[int]string{
+		200: OutcomeSuccess,
+		201: OutcomeSuccess,
+		304: OutcomeSuccess,
+		400: OutcomeFailure,
+		403: OutcomeFailure,
+		500: OutcomeFailure,
+	} {
+		if got := Outcome(code); got != want {
+			t.Errorf("%d: expected %s, got %s", code, want, got)
+		}
+	}
+}
+
+type memorySink struct {
+	events []*Event
+	err    error
+	closed bool
+}
+
+func (s *memorySink) Write(e *Event) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestAuditorLog(t *testing.T) {
+	failing := &memorySink{err: fmt.Errorf("disk full")}
+	sink := &memorySink{}
+	a := New(nil, failing, sink)
+	if a.Policy == nil {
+		t.Fatal("expected the default policy")
+	}
+	a.Log(&Event{ID: "e1"})
+	// a failing sink doesn't affect the others
+	if len(sink.events) != 1 || sink.events[0].ID != "e1" {
+		t.Errorf("expected the event in the sink, got %v", sink.events)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !failing.closed || !sink.closed {
+		t.Error("expected every sink to be closed")
+	}
+}
@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func readEvents(t *testing.T, file string) []Event {
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("expected JSON lines, got %q: %v", scanner.Text(), err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeplay-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "audit.log")
+	s, err := NewFileSink(file, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"e1", "e2"} {
+		if err := s.Write(&Event{ID: id, Verb: "POST"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the events are appended when the file is opened again
+	s, err = NewFileSink(file, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(&Event{ID: "e3"}); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	events := readEvents(t, file)
+	if len(events) != 3 || events[0].ID != "e1" || events[2].ID != "e3" {
+		t.Errorf("expected the 3 events in order, got %+v", events)
+	}
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeplay-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "audit.log")
+	s, err := NewFileSink(file, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	// every event has roughly 100KB, the file is rotated every ~10 events
+	body, _ := json.Marshal(map[string]string{"data": string(make([]byte, 100*1024))})
+	for i := 0; i < 50; i++ {
+		if err := s.Write(&Event{ID: "e", RequestBody: body}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups, err := filepath.Glob(file + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// backups rotated in the same millisecond share their name
+	if len(backups) == 0 || len(backups) > 2 {
+		t.Errorf("expected only the 2 most recent backups, got %v", backups)
+	}
+	fi, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() > 1024*1024 {
+		t.Errorf("expected the file to be rotated before exceeding the max size, got %d bytes", fi.Size())
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var mu sync.Mutex
+	var received []Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e Event
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("expected a JSON request, got %s", r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		received = append(received, e)
+		mu.Unlock()
+		if e.ID == "e2" {
+			// failures are only logged
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	s := NewWebhookSink(srv.URL)
+	for _, id := range []string{"e1", "e2", "e3"} {
+		if err := s.Write(&Event{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the queued events are sent before closing
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 3 || received[0].ID != "e1" || received[2].ID != "e3" {
+		t.Errorf("expected the 3 events in order, got %+v", received)
+	}
+}
+
+func TestWebhookSinkFullQueue(t *testing.T) {
+	// the sink isn't running, the events are only queued
+	s := &WebhookSink{queue: make(chan *Event, 1)}
+	if err := s.Write(&Event{ID: "e1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(&Event{ID: "e2"}); err == nil {
+		t.Error("expected the event to be dropped")
+	}
+}
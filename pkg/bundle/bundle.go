@@ -0,0 +1,256 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+	"github.com/kubeplay/gameserver/pkg/utils"
+)
+
+const (
+	// MediaType is the content type of the archives pushed to the game server
+	MediaType = "application/gzip"
+
+	ManifestFile   = "challenge.yaml"
+	ReadmeFile     = "README.md"
+	AssetsDir      = "assets"
+	ProvisionerDir = "provisioner"
+
+	// MaxSize limits the decompressed size of the archives, the limit of the
+	// compressed size doesn't prevent archives expanding to gigabytes.
+	MaxSize = 128 << 20
+	// MaxFiles limits the number of entries of the archives
+	MaxFiles = 1000
+)
+
+var digestRe = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// Bundle packages a challenge with the files distributed to the players and the
+// manifests of its provisioner:
+//
+//	challenge.yaml   the challenge, including its keys
+//	README.md        instructions for the players
+//	assets/          files downloaded by the players
+//	provisioner/     manifests passed to the provisioner of the challenge
+type Bundle struct {
+	Challenge *types.Challenge
+	// Manifest is the raw content of challenge.yaml
+	Manifest []byte
+	// Files are indexed by their slash separated path in the bundle
+	Files map[string][]byte
+}
+
+// Load reads a bundle from a directory or a tar.gz archive
+func Load(name string) (*Bundle, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return Read(f)
+	}
+	files := map[string][]byte{}
+	err = filepath.Walk(name, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(name, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if isHidden(rel) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[rel] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return New(files)
+}
+
+// Read decodes a tar.gz archive, archives larger than MaxSize once decompressed
+// or with more than MaxFiles entries are refused.
+func Read(r io.Reader) (*Bundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding archive: %v", err)
+	}
+	defer gz.Close()
+	files := map[string][]byte{}
+	// one byte more than the limit tells apart the archives of exactly MaxSize
+	lr := &io.LimitedReader{R: gz, N: MaxSize + 1}
+	tr := tar.NewReader(lr)
+	for entries := 0; ; entries++ {
+		hdr, err := tr.Next()
+		if lr.N <= 0 {
+			return nil, fmt.Errorf("the archive exceeds %dMB decompressed", MaxSize>>20)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding archive: %v", err)
+		}
+		if entries >= MaxFiles {
+			return nil, fmt.Errorf("the archive exceeds %d files", MaxFiles)
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		name := strings.TrimPrefix(path.Clean(hdr.Name), "./")
+		if isHidden(name) {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if lr.N <= 0 {
+			return nil, fmt.Errorf("the archive exceeds %dMB decompressed", MaxSize>>20)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding archive: %v", err)
+		}
+		files[name] = data
+	}
+	return New(files)
+}
+
+// New validates the files of a bundle, the manifest is required
+func New(files map[string][]byte) (*Bundle, error) {
+	manifest, ok := files[ManifestFile]
+	if !ok {
+		return nil, fmt.Errorf("missing %s in the bundle", ManifestFile)
+	}
+	delete(files, ManifestFile)
+	for name := range files {
+		if err := validPath(name); err != nil {
+			return nil, err
+		}
+	}
+	obj, err := utils.YamlToJson(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding %s: %v", ManifestFile, err)
+	}
+	c, ok := obj.(*types.Challenge)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected kind %q, found %q", ManifestFile, types.ChallengeKind, obj.GetObjectKind())
+	}
+	if c.Name == "" {
+		return nil, fmt.Errorf("%s: missing the name of the challenge", ManifestFile)
+	}
+	return &Bundle{Challenge: c, Manifest: manifest, Files: files}, nil
+}
+
+func validPath(name string) error {
+	if path.IsAbs(name) || name != path.Clean(name) || strings.HasPrefix(name, "../") {
+		return fmt.Errorf("invalid path %q in the bundle", name)
+	}
+	switch dir := strings.SplitN(name, "/", 2)[0]; {
+	case name == ReadmeFile:
+	case dir == AssetsDir && name != dir, dir == ProvisionerDir && name != dir:
+	default:
+		return fmt.Errorf("unexpected file %q, a bundle contains %s, %s, %s/ and %s/",
+			name, ManifestFile, ReadmeFile, AssetsDir, ProvisionerDir)
+	}
+	return nil
+}
+
+// isHidden skips files like .git or .DS_Store
+func isHidden(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") && part != "." {
+			return true
+		}
+	}
+	return false
+}
+
+// Archive encodes the bundle as tar.gz, the same files always
+// produce the same archive.
+func (b *Bundle) Archive() ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	names := []string{ManifestFile}
+	for name := range b.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	for _, name := range names {
+		data := b.Manifest
+		if name != ManifestFile {
+			data = b.Files[name]
+		}
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Digest returns the content hash of data, e.g.: sha256:<hex>
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ValidDigest returns true if the digest has the format returned by Digest
+func ValidDigest(digest string) bool {
+	return digestRe.MatchString(digest)
+}
+
+// IsPublic returns true if the file is distributed to the players, the
+// manifests of the provisioner are only visible to the hosts.
+func IsPublic(name string) bool {
+	return name == ReadmeFile || strings.HasPrefix(name, AssetsDir+"/")
+}
+
+// Public returns the files of a bundle distributed to the players
+func Public(b *types.ChallengeBundle) *types.ChallengeBundle {
+	public := &types.ChallengeBundle{Digest: b.Digest, PushedAt: b.PushedAt}
+	for _, f := range b.Files {
+		if IsPublic(f.Path) {
+			public.Files = append(public.Files, f)
+		}
+	}
+	return public
+}
@@ -0,0 +1,158 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+const manifest = `kind: Challenge
+metadata:
+  name: foo
+`
+
+// archive writes the files as tar.gz, the size of the files in sizes
+// is streamed as zeros instead of keeping them in memory.
+func archive(t *testing.T, files map[string]string, sizes map[string]int64) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, data := range files {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, size := range sizes {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: size, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.CopyN(tw, zeros{}, size); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestRead(t *testing.T) {
+	many := map[string]string{ManifestFile: manifest}
+	for i := 0; i < MaxFiles; i++ {
+		many[fmt.Sprintf("assets/%d.txt", i)] = "x"
+	}
+	for _, tc := range []struct {
+		name    string
+		files   map[string]string
+		sizes   map[string]int64
+		wantErr string
+	}{
+		{
+			name:  "valid bundle",
+			files: map[string]string{ManifestFile: manifest, ReadmeFile: "# foo", "assets/a.txt": "a"},
+		},
+		{
+			name:  "files up to the decompressed limit",
+			files: map[string]string{ManifestFile: manifest},
+			sizes: map[string]int64{"assets/big": MaxSize / 2},
+		},
+		{
+			name:    "decompressed size over the limit",
+			files:   map[string]string{ManifestFile: manifest},
+			sizes:   map[string]int64{"assets/bomb": MaxSize + 1},
+			wantErr: "decompressed",
+		},
+		{
+			name:    "decompressed size over the limit across files",
+			files:   map[string]string{ManifestFile: manifest},
+			sizes:   map[string]int64{"assets/a": MaxSize / 2, "assets/b": MaxSize / 2},
+			wantErr: "decompressed",
+		},
+		{
+			name:    "too many files",
+			files:   many,
+			wantErr: "files",
+		},
+		{
+			name:    "missing manifest",
+			files:   map[string]string{ReadmeFile: "# foo"},
+			wantErr: ManifestFile,
+		},
+		{
+			name:    "unexpected file",
+			files:   map[string]string{ManifestFile: manifest, "main.go": "package main"},
+			wantErr: "unexpected file",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := Read(bytes.NewReader(archive(t, tc.files, tc.sizes)))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if b.Challenge.Name != "foo" {
+				t.Errorf("expected the challenge foo, got %q", b.Challenge.Name)
+			}
+			if len(b.Files) != len(tc.files)+len(tc.sizes)-1 {
+				t.Errorf("expected %d files, got %d", len(tc.files)+len(tc.sizes)-1, len(b.Files))
+			}
+		})
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	files := map[string][]byte{
+		ManifestFile:          []byte(manifest),
+		ReadmeFile:            []byte("# foo"),
+		"assets/a.txt":        []byte("a"),
+		"provisioner/ns.yaml": []byte("kind: Namespace"),
+	}
+	b, err := New(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := b.Archive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := b.Archive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Error("expected the same archive for the same files")
+	}
+	got, err := Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Files) != 3 || string(got.Files["assets/a.txt"]) != "a" {
+		t.Errorf("unexpected files %v", got.Files)
+	}
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+	"github.com/spf13/cobra"
+)
+
+func AuthCanICmd() *cobra.Command {
+	var subject string
+	cmd := &cobra.Command{
+		Use:          "can-i VERB PATH",
+		PreRunE:      PreLoad,
+		SilenceUsage: true,
+		Short:        "Check whether an action is allowed.",
+		Long: `Check whether an action is allowed, the verb is an HTTP method and the path
+is the request path of the API, e.g.: kubeplay auth can-i POST /v1/challenges
+
+[HOST] Use --as to check the permissions of another subject.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return errors.New("missing required arguments: <verb> <path>")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			spec := types.AccessReviewSpec{
+				Subject: subject,
+				Verb:    strings.ToUpper(args[0]),
+				Path:    args[1],
+			}
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			var status types.AccessReviewStatus
+			if subject != "" {
+				review, err := c.Auth().Review(context.Background(), &types.SubjectAccessReview{
+					TypeMeta: types.TypeMeta{Kind: types.SubjectAccessReviewKind},
+					Spec:     spec,
+				})
+				if err != nil {
+					return err
+				}
+				status = review.Status
+			} else {
+				review, err := c.Auth().CanI(context.Background(), &types.SelfSubjectAccessReview{
+					TypeMeta: types.TypeMeta{Kind: types.SelfSubjectAccessReviewKind},
+					Spec:     spec,
+				})
+				if err != nil {
+					return err
+				}
+				status = review.Status
+			}
+			if !status.Allowed {
+				fmt.Printf("no - %s\n", status.Reason)
+				os.Exit(1)
+			}
+			fmt.Println("yes")
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&subject, "as", "", "[HOST] The subject to check the permissions, e.g.: github|user.")
+	return cmd
+}
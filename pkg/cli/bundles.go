@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubeplay/gameserver/pkg/bundle"
+	"github.com/spf13/cobra"
+)
+
+// Host
+func ChallengePushCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:                   "push DIR",
+		PreRunE:               PreLoad,
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing the bundle directory or archive")
+			}
+			return nil
+		},
+		Short: "[HOST] Create or replace a challenge from a bundle (directory or tar.gz).",
+		Long: `Create or replace a challenge from a bundle, a directory or a tar.gz archive with:
+
+  challenge.yaml   the challenge, including its keys
+  README.md        instructions for the players
+  assets/          files downloaded by the players
+  provisioner/     manifests passed to the provisioner of the challenge`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := bundle.Load(args[0])
+			if err != nil {
+				return err
+			}
+			data, err := b.Archive()
+			if err != nil {
+				return err
+			}
+			cs, err := Client()
+			if err != nil {
+				return err
+			}
+			c, err := cs.Challenges().PushBundle(context.Background(), b.Challenge.Name, data)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Challenge %q pushed with %d files, digest %s\n", c.Name, len(c.Bundle.Files), c.Bundle.Digest)
+			return nil
+		},
+	}
+}
+
+// Guest
+func ChallengePullCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "pull NAME",
+		PreRunE:               PreLoad,
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing the resource name")
+			}
+			return nil
+		},
+		Short: "Download the README and the assets of a challenge.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cs, err := Client()
+			if err != nil {
+				return err
+			}
+			c, err := cs.Challenges().GetBundle(context.Background(), args[0])
+			if err != nil {
+				return err
+			}
+			outputDir := O.Bundles.OutputDir
+			if outputDir == "" {
+				outputDir = c.Name
+			}
+			for _, f := range c.Bundle.Files {
+				// never write outside of the output directory
+				if !bundle.IsPublic(f.Path) || f.Path != path.Clean(f.Path) || strings.Contains(f.Path, "..") {
+					return fmt.Errorf("unexpected file %q in the bundle", f.Path)
+				}
+				data, err := cs.Assets().Get(context.Background(), f.Digest)
+				if err != nil {
+					return err
+				}
+				if digest := bundle.Digest(data); digest != f.Digest {
+					return fmt.Errorf("the digest of %q doesn't match, expected %s, found %s", f.Path, f.Digest, digest)
+				}
+				file := filepath.Join(outputDir, filepath.FromSlash(f.Path))
+				if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+					return err
+				}
+				if err := ioutil.WriteFile(file, data, 0644); err != nil {
+					return err
+				}
+			}
+			fmt.Printf("Challenge %q pulled to %s (%d files)\n", c.Name, outputDir, len(c.Bundle.Files))
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&O.Bundles.OutputDir, "output", "o", "", "The directory where the files are written, defaults to the name of the challenge.")
+	return cmd
+}
@@ -1,14 +1,14 @@
 package cli
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
-	"path"
 	"strings"
 	"text/tabwriter"
 
-	"github.com/kubeplay/gameserver/pkg/rest"
+	"github.com/kubeplay/gameserver/pkg/client"
 	"github.com/kubeplay/gameserver/pkg/types"
 	"github.com/kubeplay/gameserver/pkg/utils"
 	"github.com/spf13/cobra"
@@ -24,38 +24,31 @@ func ChallengeGetCmd() *cobra.Command {
 		Short:        "Get or list specific challenge resource.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			isResourceScoped := len(args) > 0
-			requestURI := path.Join("/v1/challenges")
-			if isResourceScoped {
-				requestURI = path.Join(requestURI, args[0])
-			}
-			resp := rest.NewRequest(nil, GameServerURL).Get().
-				Bearer(AccessToken).
-				RequestURI(requestURI).
-				Do()
-			if err := resp.Error(); err != nil {
+			cs, err := Client()
+			if err != nil {
 				return err
 			}
 			w := new(tabwriter.Writer)
 			w.Init(os.Stdout, 0, 8, 2, '\t', tabwriter.AlignRight)
 			defer w.Flush()
 			if !isResourceScoped {
-				var itemList types.ChallengeList
-				if err := resp.Into(&itemList); err != nil {
+				itemList, err := cs.Challenges().List(context.Background(), listOptions())
+				if err != nil {
 					return err
 				}
-				fmt.Fprintln(w, "NAME\tKEYS\tAGE\t")
+				fmt.Fprintln(w, "NAME\tREVISION\tKEYS\tAGE\t")
 				for _, c := range itemList.Items {
 					d := utils.GetDeltaDuration(c.CreatedAt, "")
-					fmt.Fprintf(w, "%s\t%d\t%s\t\n", c.Name, len(c.Keys), d)
+					fmt.Fprintf(w, "%s\t%d\t%d\t%s\t\n", c.Name, c.Generation, len(c.Keys), d)
 				}
 			} else {
-				var c types.Challenge
-				if err := resp.Into(&c); err != nil {
+				c, err := cs.Challenges().Get(context.Background(), args[0])
+				if err != nil {
 					return err
 				}
 				d := utils.GetDeltaDuration(c.CreatedAt, "")
-				fmt.Fprintln(w, "NAME\tKEYS\tAGE\t")
-				fmt.Fprintf(w, "%s\t%d\t%s\t", c.Name, len(c.Keys), d)
+				fmt.Fprintln(w, "NAME\tREVISION\tKEYS\tAGE\t")
+				fmt.Fprintf(w, "%s\t%d\t%d\t%s\t", c.Name, c.Generation, len(c.Keys), d)
 				fmt.Fprintln(w)
 			}
 			return nil
@@ -78,16 +71,14 @@ func ChallengeCreateCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// fmt.Println("INPUT", O.CreateInput)
 			// return nil
-			c := &types.Challenge{
+			cs, err := Client()
+			if err != nil {
+				return err
+			}
+			c, err := cs.Challenges().Create(context.Background(), &types.Challenge{
 				TypeMeta: types.TypeMeta{Kind: types.ChallengeKind},
 				Metadata: types.Metadata{Name: args[0]},
-			}
-			err := rest.NewRequest(nil, GameServerURL).Post().
-				Bearer(AccessToken.String()).
-				RequestURI("/v1/challenges").
-				Body(c).
-				Do().
-				Into(c)
+			})
 			if err != nil {
 				return err
 			}
@@ -112,13 +103,14 @@ func ChallengeDeleteCmd() *cobra.Command {
 		},
 		Short: "[HOST] Delete a challenge by its name.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			_, err := rest.NewRequest(nil, GameServerURL).Delete().
-				RequestURI("/v1/challenges", args[0]).
-				Bearer(AccessToken.String()).
-				Do().Raw()
+			cs, err := Client()
 			if err != nil {
 				return err
 			}
+			opts := client.DeleteOptions{Cascade: types.DeletionPolicy(O.Cascade)}
+			if err := cs.Challenges().Delete(context.Background(), args[0], opts); err != nil {
+				return err
+			}
 			fmt.Printf("Challenge %q deleted!\n", args[0])
 			return nil
 		},
@@ -145,19 +137,21 @@ func HackChallengeCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			parts := strings.Split(args[0], "/")
 			eventName, gameName := parts[0], parts[1]
-			var gm types.Game
-			err := rest.NewRequest(nil, GameServerURL).Get().
-				RequestURI("/v1/events", eventName, "games", gameName).
-				Bearer(AccessToken.String()).
-				Do().Into(&gm)
+			cs, err := Client()
+			if err != nil {
+				return err
+			}
+			gm, err := cs.Games(eventName).Get(context.Background(), gameName)
 			if err != nil {
 				return err
 			}
-			var c types.Challenge
-			err = rest.NewRequest(nil, GameServerURL).Get().
-				RequestURI("/v1/challenges", gm.Challenge).
-				Bearer(AccessToken.String()).
-				Do().Into(&c)
+			// the keys of the revision the game was created with
+			var c *types.Challenge
+			if gm.ChallengeGeneration > 0 {
+				c, err = cs.Challenges().GetRevision(context.Background(), gm.Challenge, gm.ChallengeGeneration)
+			} else {
+				c, err = cs.Challenges().Get(context.Background(), gm.Challenge)
+			}
 			if err != nil {
 				return err
 			}
@@ -2,12 +2,13 @@ package cli
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
 	"fmt"
 	"os"
 	"strings"
 	"syscall"
 
+	"github.com/kubeplay/gameserver/pkg/client"
 	"github.com/kubeplay/gameserver/pkg/rest"
 	"github.com/kubeplay/gameserver/pkg/types"
 	"github.com/spf13/cobra"
@@ -22,34 +23,72 @@ func LoginCmd() *cobra.Command {
 		Short: "Authenticate to the game server.",
 		Run: func(cmd *cobra.Command, args []string) {
 			reader := bufio.NewReader(os.Stdin)
-			fmt.Print("Enter your GitHub username/e-mail: ")
+			switch O.Login.Provider {
+			case types.LocalProvider:
+				fmt.Print("Enter your username: ")
+			default:
+				fmt.Print("Enter your GitHub username/e-mail: ")
+			}
 			username, _ := reader.ReadString('\n')
-			fmt.Print("Enter your GitHub password or personal token: ")
+			switch O.Login.Provider {
+			case types.LocalProvider:
+				fmt.Print("Enter your password: ")
+			default:
+				fmt.Print("Enter your GitHub password or personal token: ")
+			}
 			credentials, _ := terminal.ReadPassword(int(syscall.Stdin))
 			fmt.Println()
 			basicAuth := &rest.BasicAuth{
 				Username: strings.TrimSpace(username),
 				Password: strings.TrimSpace(string(credentials)),
 			}
-			data, err := rest.NewRequest(nil, GameServerURL).Get().
-				BasicAuth(basicAuth).
-				RequestURI("/v1/login").
-				Do().Raw()
+			c, err := client.New(&client.Config{Host: GameServerURL.String(), HTTPClient: HTTPClient})
 			if err != nil {
 				fmt.Println(err)
 				os.Exit(1)
 			}
-			var player types.PlayerClaims
-			if err := json.Unmarshal(data, &player); err != nil {
+			player, err := c.Auth().Login(context.Background(), O.Login.Provider, basicAuth)
+			if err != nil {
 				fmt.Println(err)
 				os.Exit(1)
 			}
-			if err := WriteCredentials([]byte(player.AccessToken)); err != nil {
+			if err := WriteCredentials(
+				[]byte(player.AccessToken),
+				[]byte(player.RefreshToken),
+			); err != nil {
 				fmt.Println(err)
 				os.Exit(1)
 			}
-			fmt.Printf("Lets play %s!\n", player.Name)
+			name := player.Name
+			if name == "" {
+				name = player.Login
+			}
+			fmt.Printf("Lets play %s!\n", name)
 		},
 	}
+	cmd.Flags().StringVar(&O.Login.Provider, "provider", types.GitHubProvider, "The identity provider to authenticate: github or local.")
 	return cmd
 }
+
+func LogoutCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "logout",
+		Short:        "Revoke the current session and remove the local credentials.",
+		PreRunE:      PreLoad,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			if err := c.Auth().Logout(context.Background()); err != nil {
+				return err
+			}
+			if err := RemoveCredentials(); err != nil {
+				return err
+			}
+			fmt.Println("Logged out!")
+			return nil
+		},
+	}
+}
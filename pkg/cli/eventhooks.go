@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kubeplay/gameserver/pkg/client"
+	"github.com/kubeplay/gameserver/pkg/types"
+	"github.com/kubeplay/gameserver/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// Host
+func EventHookGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "eventhooks",
+		Aliases:      []string{"eventhook"},
+		PreRunE:      PreLoad,
+		SilenceUsage: true,
+		Short:        "[HOST] Get or list event hooks.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			isResourceScoped := len(args) > 0
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			w := new(tabwriter.Writer)
+			w.Init(os.Stdout, 0, 8, 2, '\t', tabwriter.AlignRight)
+			defer w.Flush()
+			if !isResourceScoped {
+				itemList, err := c.EventHooks().List(context.Background(), listOptions())
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(w, "NAME\tURL\tEVENT\tTRIGGERS\tLAST DELIVERY\tAGE\t")
+				for _, h := range itemList.Items {
+					event, triggers, last := "*", "*", "-"
+					if h.Event != "" {
+						event = h.Event
+					}
+					if len(h.Triggers) > 0 {
+						var t []string
+						for _, trigger := range h.Triggers {
+							t = append(t, string(trigger))
+						}
+						triggers = strings.Join(t, ",")
+					}
+					if n := len(h.Status.Deliveries); n > 0 {
+						d := h.Status.Deliveries[n-1]
+						last = "delivered"
+						if !d.Delivered {
+							last = "failed"
+						}
+					}
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t\n",
+						h.Name,
+						h.URL,
+						event,
+						triggers,
+						last,
+						utils.GetDeltaDuration(h.CreatedAt, ""),
+					)
+				}
+			} else {
+				h, err := c.EventHooks().Get(context.Background(), args[0])
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(w, "DELIVERY\tTRIGGER\tDELIVERED\tATTEMPTS\tSTATUS\tERROR\tAGE\t")
+				for _, d := range h.Status.Deliveries {
+					fmt.Fprintf(w, "%s\t%s\t%v\t%d\t%d\t%s\t%s\t\n",
+						d.ID,
+						d.Trigger,
+						d.Delivered,
+						d.Attempts,
+						d.StatusCode,
+						d.Error,
+						utils.GetDeltaDuration(d.Timestamp, ""),
+					)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// Host
+func EventHookCreateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "eventhook NAME",
+		PreRunE:      PreLoad,
+		SilenceUsage: true,
+		Short:        "[HOST] Notify an HTTP endpoint about the lifecycle of games.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing the resource name")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			h := &types.EventHook{
+				TypeMeta: types.TypeMeta{Kind: types.EventHookKind},
+				Metadata: types.Metadata{Name: args[0]},
+				URL:      O.EventHooks.URL,
+				Secret:   O.EventHooks.Secret,
+				Event:    O.EventHooks.Event,
+				Template: types.HookTemplate(O.EventHooks.Template),
+			}
+			for _, t := range O.EventHooks.Triggers {
+				h.Triggers = append(h.Triggers, types.HookTrigger(t))
+			}
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			h, err = c.EventHooks().Create(context.Background(), h)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("EventHook %q created with uid %s\n", h.Name, h.UID)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&O.EventHooks.URL, "url", "", "The endpoint receiving the notifications.")
+	cmd.Flags().StringVar(&O.EventHooks.Secret, "secret", "", "Sign the deliveries with this secret (HMAC-SHA256).")
+	cmd.Flags().StringVarP(&O.EventHooks.Event, "event", "e", "", "Notify only the games of this event.")
+	cmd.Flags().StringSliceVar(&O.EventHooks.Triggers, "trigger", nil, "Notify only these triggers: game.created, game.started, game.key_solved, game.completed or game.expired.")
+	cmd.Flags().StringVar(&O.EventHooks.Template, "template", "json", "The payload format: json, slack or discord.")
+	cmd.MarkFlagRequired("url")
+	return cmd
+}
+
+// Host
+func EventHookDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:                   "eventhooks EVENTHOOK",
+		Aliases:               []string{"eventhook"},
+		PreRunE:               PreLoad,
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing the resource name")
+			}
+			return nil
+		},
+		Short: "[HOST] Delete an event hook by its name.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			if err := c.EventHooks().Delete(context.Background(), args[0], client.DeleteOptions{}); err != nil {
+				return err
+			}
+			fmt.Printf("EventHook %q deleted!\n", args[0])
+			return nil
+		},
+	}
+}
@@ -1,13 +1,13 @@
 package cli
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
-	"path"
 	"text/tabwriter"
 
-	"github.com/kubeplay/gameserver/pkg/rest"
+	"github.com/kubeplay/gameserver/pkg/client"
 	"github.com/kubeplay/gameserver/pkg/types"
 	"github.com/kubeplay/gameserver/pkg/utils"
 	"github.com/spf13/cobra"
@@ -23,15 +23,8 @@ func EventGetCmd() *cobra.Command {
 		Short:        "Get or list specific event resource.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			isResourceScoped := len(args) > 0
-			requestURI := path.Join("/v1/events")
-			if isResourceScoped {
-				requestURI = path.Join(requestURI, args[0])
-			}
-			resp := rest.NewRequest(nil, GameServerURL).Get().
-				Bearer(AccessToken).
-				RequestURI(requestURI).
-				Do()
-			if err := resp.Error(); err != nil {
+			c, err := Client()
+			if err != nil {
 				return err
 			}
 			w := new(tabwriter.Writer)
@@ -39,8 +32,8 @@ func EventGetCmd() *cobra.Command {
 			fmt.Fprintln(w, "NAME\tPAUSED\tAGE\t")
 			defer w.Flush()
 			if !isResourceScoped {
-				var eventList types.EventList
-				if err := resp.Into(&eventList); err != nil {
+				eventList, err := c.Events().List(context.Background(), listOptions())
+				if err != nil {
 					return err
 				}
 				for _, ev := range eventList.Items {
@@ -48,8 +41,8 @@ func EventGetCmd() *cobra.Command {
 					fmt.Fprintf(w, "%s\t%v\t%s\t\n", ev.Name, ev.Paused, d)
 				}
 			} else {
-				var ev types.Event
-				if err := resp.Into(&ev); err != nil {
+				ev, err := c.Events().Get(context.Background(), args[0])
+				if err != nil {
 					return err
 				}
 				d := utils.GetDeltaDuration(ev.CreatedAt, "")
@@ -79,7 +72,7 @@ func EventJoinCmd() *cobra.Command {
 
 // Host
 func EventCreateCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:          "event",
 		SilenceUsage: true,
 		Short:        "Create an event resource.",
@@ -90,16 +83,18 @@ func EventCreateCmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Client()
+			if err != nil {
+				return err
+			}
 			ev := &types.Event{
 				TypeMeta: types.TypeMeta{Kind: types.EventKind},
 				Metadata: types.Metadata{Name: args[0]},
 			}
-			err := rest.NewRequest(nil, GameServerURL).Post().
-				Bearer(AccessToken.String()).
-				RequestURI("/v1/events").
-				Body(ev).
-				Do().
-				Into(ev)
+			if O.Events.GameTimeout > 0 {
+				ev.GameTimeout = O.Events.GameTimeout.String()
+			}
+			ev, err = c.Events().Create(context.Background(), ev)
 			if err != nil {
 				return err
 			}
@@ -107,6 +102,8 @@ func EventCreateCmd() *cobra.Command {
 			return nil
 		},
 	}
+	cmd.Flags().DurationVar(&O.Events.GameTimeout, "game-timeout", 0, "Expire the games running longer than the duration, e.g.: 2h.")
+	return cmd
 }
 
 func EventDeleteCmd() *cobra.Command {
@@ -115,7 +112,7 @@ func EventDeleteCmd() *cobra.Command {
 		Aliases:      []string{"event"},
 		PreRunE:      PreLoad,
 		SilenceUsage: true,
-		Short:        "Get or list specific event resource.",
+		Short:        "[HOST] Delete an event, its games are deleted with --cascade.",
 		Args: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 1 {
 				return errors.New("missing the resource name")
@@ -123,14 +120,14 @@ func EventDeleteCmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			requestURI := path.Join("/v1/events", args[0])
-			_, err := rest.NewRequest(nil, GameServerURL).Delete().
-				Bearer(AccessToken).
-				RequestURI(requestURI).
-				Do().Raw()
+			c, err := Client()
 			if err != nil {
 				return err
 			}
+			opts := client.DeleteOptions{Cascade: types.DeletionPolicy(O.Cascade)}
+			if err := c.Events().Delete(context.Background(), args[0], opts); err != nil {
+				return err
+			}
 			fmt.Printf("Event %q deleted!\n", args[0])
 			return nil
 		},
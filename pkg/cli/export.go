@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// Host
+func ExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "export",
+		PreRunE:               PreLoad,
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Short:                 "[HOST] Dump the objects of the game server as multi-document YAML.",
+		Long: `Dump the roles, challenges (with their revisions), events, games, role bindings,
+policies, event hooks and users as multi-document YAML, ordered by kind.
+
+Passwords, the secrets of the event hooks, the sessions and the files of the
+challenge bundles aren't exported, use "gameserver backup" for a full copy.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			data, err := c.Export(context.Background())
+			if err != nil {
+				return err
+			}
+			if O.Export.OutputFile == "" || O.Export.OutputFile == "-" {
+				_, err := os.Stdout.Write(data)
+				return err
+			}
+			return ioutil.WriteFile(O.Export.OutputFile, data, 0600)
+		},
+	}
+	cmd.Flags().StringVarP(&O.Export.OutputFile, "output", "o", "", "Write the export to this file instead of the standard output.")
+	return cmd
+}
+
+// Host
+func ImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "import -f FILE",
+		PreRunE:               PreLoad,
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Short:                 "[HOST] Create the objects of an export, existing objects are skipped.",
+		Long: `Create the objects of an export keeping their status, e.g.: the keys solved by
+the players. Challenges are imported before the events and the games, objects
+which already exist are skipped.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var data []byte
+			var err error
+			switch O.Export.InputFile {
+			case "":
+				return errors.New("missing the file to import (-f)")
+			case "-":
+				data, err = ioutil.ReadAll(os.Stdin)
+			default:
+				data, err = ioutil.ReadFile(O.Export.InputFile)
+			}
+			if err != nil {
+				return err
+			}
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			result, err := c.Import(context.Background(), data)
+			if err != nil {
+				return err
+			}
+			for _, key := range result.Created {
+				fmt.Printf("%s created\n", key)
+			}
+			for _, key := range result.Skipped {
+				fmt.Printf("%s skipped (already exists)\n", key)
+			}
+			fmt.Printf("Imported %d object(s), %d skipped\n", len(result.Created), len(result.Skipped))
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&O.Export.InputFile, "filename", "f", "", "The export to import, - reads the standard input.")
+	return cmd
+}
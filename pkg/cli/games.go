@@ -1,17 +1,17 @@
 package cli
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
-	"path"
 	"strings"
 	"text/tabwriter"
 	"time"
 
-	"github.com/kubeplay/gameserver/pkg/rest"
-	"github.com/kubeplay/gameserver/pkg/store"
+	"github.com/kubeplay/gameserver/pkg/client"
 	"github.com/kubeplay/gameserver/pkg/types"
+	"github.com/kubeplay/gameserver/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -22,17 +22,15 @@ func GameCreateCmd() *cobra.Command {
 		SilenceUsage: true,
 		PreRunE:      PreLoad,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			game := types.Game{
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			game, err := c.Games(O.Games.Event).Create(context.Background(), &types.Game{
 				TypeMeta:  types.TypeMeta{Kind: types.GameKind},
-				Metadata:  types.Metadata{Name: store.NewUUID()},
+				Metadata:  types.Metadata{Name: utils.NewUUID()},
 				Challenge: O.Games.Challenge,
-			}
-
-			err := rest.NewRequest(nil, GameServerURL).Post().
-				RequestURI("/v1/events", O.Games.Event, "games").
-				Bearer(AccessToken.String()).
-				Body(&game).
-				Do().Into(&game)
+			})
 			if err != nil {
 				return err
 			}
@@ -53,31 +51,43 @@ func GameGetCmd() *cobra.Command {
 		Aliases:      []string{"game"},
 		PreRunE:      PreLoad,
 		SilenceUsage: true,
-		Short:        "Get or list specific game resources.",
+		Short:        "Get or list specific game resources, --all-events and --mine list the games of every event.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			isResourceScoped := len(args) > 0
-			requestURI := path.Join("/v1/events", O.Games.Event, "games")
-			if isResourceScoped {
-				requestURI = path.Join(requestURI, args[0])
-			}
-			resp := rest.NewRequest(nil, GameServerURL).Get().
-				Bearer(AccessToken.String()).
-				RequestURI(requestURI).
-				Do()
-			if err := resp.Error(); err != nil {
+			allEvents := O.Games.Event == ""
+			switch {
+			case O.Games.AllEvents && !allEvents:
+				return fmt.Errorf("--all-events and --event are mutually exclusive")
+			case allEvents && !O.Games.AllEvents && !O.Games.Mine:
+				return fmt.Errorf("required flag \"event\" not set, use --all-events to list the games of every event")
+			case allEvents && isResourceScoped:
+				return fmt.Errorf("games are addressed by their event, use --event")
+			}
+			c, err := Client()
+			if err != nil {
 				return err
 			}
+			opts := listOptions()
+			if O.Games.Mine {
+				if opts.Player, err = currentSubject(); err != nil {
+					return err
+				}
+			}
+			games := c.Games(O.Games.Event)
 			w := new(tabwriter.Writer)
 			w.Init(os.Stdout, 0, 8, 2, '\t', tabwriter.AlignRight)
 			defer w.Flush()
 			if !isResourceScoped {
-				var itemList types.GameList
-				if err := resp.Into(&itemList); err != nil {
+				itemList, err := games.List(context.Background(), opts)
+				if err != nil {
 					return err
 				}
 				if len(itemList.Items) == 0 {
 					return fmt.Errorf("No resources found.")
 				}
+				if allEvents {
+					fmt.Fprint(w, "EVENT\t")
+				}
 				fmt.Fprintln(w, "NAME\tCHALLENGE\tKEYS\tDURATION\tSTATUS\t")
 				for _, gm := range itemList.Items {
 					startTime, _ := time.Parse(time.RFC3339, gm.Status.StartTime)
@@ -93,6 +103,9 @@ func GameGetCmd() *cobra.Command {
 						duration = "-"
 					}
 					completedKeys := fmt.Sprintf("%d/%d", len(gm.Status.Keys), gm.Status.RegisteredKeys)
+					if allEvents {
+						fmt.Fprintf(w, "%s\t", gm.Event)
+					}
 					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t",
 						gm.Name,
 						gm.Challenge,
@@ -103,8 +116,8 @@ func GameGetCmd() *cobra.Command {
 					fmt.Fprintln(w)
 				}
 			} else {
-				var gm types.Game
-				if err := resp.Into(&gm); err != nil {
+				gm, err := games.Get(context.Background(), args[0])
+				if err != nil {
 					return err
 				}
 
@@ -134,7 +147,8 @@ func GameGetCmd() *cobra.Command {
 		},
 	}
 	cmd.Flags().StringVarP(&O.Games.Event, "event", "e", "", "The event to list games.")
-	cmd.MarkFlagRequired("event")
+	cmd.Flags().BoolVar(&O.Games.AllEvents, "all-events", false, "List the games of every event.")
+	cmd.Flags().BoolVar(&O.Games.Mine, "mine", false, "List only your games, of every event unless --event is set.")
 	return cmd
 }
 
@@ -157,20 +171,16 @@ func GameSolveCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			parts := strings.Split(args[0], "/")
 			eventName, gameName, gameKey := parts[0], parts[1], args[1]
-			resp := rest.NewRequest(nil, GameServerURL).Post().
-				RequestURI("/v1/events", eventName, "games", gameName, "solve").
-				SetHeader(types.GameKeyHeaderName, gameKey).
-				Bearer(AccessToken.String()).
-				Do()
-			if err := resp.Error(); err != nil {
+			c, err := Client()
+			if err != nil {
 				return err
 			}
-			if resp.StatusCode() == 403 {
+			gm, err := c.Games(eventName).Solve(context.Background(), gameName, gameKey)
+			if client.IsForbidden(err) {
 				fmt.Println("The game key is invalid! Are you trying to hack the game? :(")
 				return nil
 			}
-			gm := types.Game{}
-			if err := resp.Into(&gm); err != nil {
+			if err != nil {
 				return err
 			}
 			gs := gm.Status.LastSolvedKey
@@ -209,15 +219,12 @@ func GameStartCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			parts := strings.Split(args[0], "/")
 			eventName, gameName := parts[0], parts[1]
-			resp := rest.NewRequest(nil, GameServerURL).Post().
-				RequestURI("/v1/events", eventName, "games", gameName, "start").
-				Bearer(AccessToken.String()).
-				Do()
-			if err := resp.Error(); err != nil {
+			c, err := Client()
+			if err != nil {
 				return err
 			}
-			var gm types.Game
-			if err := resp.Into(&gm); err != nil {
+			gm, err := c.Games(eventName).Start(context.Background(), gameName)
+			if err != nil {
 				return err
 			}
 			w := new(tabwriter.Writer)
@@ -248,3 +255,36 @@ func GameStartCmd() *cobra.Command {
 		},
 	}
 }
+
+func GameDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:                   "games EVENT/GAME",
+		Aliases:               []string{"game"},
+		PreRunE:               PreLoad,
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing the resource name")
+			}
+			if !strings.Contains(args[0], "/") {
+				return errors.New("specify the resource name as <event>/<game>")
+			}
+			return nil
+		},
+		Short: "[HOST] Delete a game and deprovision the environment of the player.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			parts := strings.Split(args[0], "/")
+			eventName, gameName := parts[0], parts[1]
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			if err := c.Games(eventName).Delete(context.Background(), gameName, client.DeleteOptions{}); err != nil {
+				return err
+			}
+			fmt.Printf("Game %q deleted!\n", args[0])
+			return nil
+		},
+	}
+}
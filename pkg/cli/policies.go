@@ -1,15 +1,14 @@
 package cli
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
-	"path"
 	"text/tabwriter"
 
 	"gopkg.in/yaml.v2"
 
-	"github.com/kubeplay/gameserver/pkg/rest"
 	"github.com/kubeplay/gameserver/pkg/types"
 	"github.com/kubeplay/gameserver/pkg/utils"
 	"github.com/spf13/cobra"
@@ -24,15 +23,8 @@ func PolicyGetCmd() *cobra.Command {
 		Short:        "Get or list policies.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			isResourceScoped := len(args) > 0
-			requestURI := path.Join("/v1/policies")
-			if isResourceScoped {
-				requestURI = path.Join(requestURI, args[0])
-			}
-			resp := rest.NewRequest(nil, GameServerURL).Get().
-				Bearer(AccessToken).
-				RequestURI(requestURI).
-				Do()
-			if err := resp.Error(); err != nil {
+			c, err := Client()
+			if err != nil {
 				return err
 			}
 			if !isResourceScoped {
@@ -40,8 +32,8 @@ func PolicyGetCmd() *cobra.Command {
 				w.Init(os.Stdout, 0, 8, 2, '\t', tabwriter.AlignRight)
 				defer w.Flush()
 				fmt.Fprintln(w, "NAME\tAGE\t")
-				var policyList types.PolicyList
-				if err := resp.Into(&policyList); err != nil {
+				policyList, err := c.Policies().List(context.Background(), listOptions())
+				if err != nil {
 					return err
 				}
 				for _, p := range policyList.Items {
@@ -49,8 +41,8 @@ func PolicyGetCmd() *cobra.Command {
 					fmt.Fprintf(w, "%s\t%s\t\n", p.Name, d)
 				}
 			} else {
-				var p types.Policy
-				if err := resp.Into(&p); err != nil {
+				p, err := c.Policies().Get(context.Background(), args[0])
+				if err != nil {
 					return err
 				}
 				data, err := yaml.Marshal(p)
@@ -76,16 +68,14 @@ func PolicyCreateCmd() *cobra.Command {
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			p := &types.Policy{
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			p, err := c.Policies().Create(context.Background(), &types.Policy{
 				TypeMeta: types.TypeMeta{Kind: types.PolicyKind},
 				Metadata: types.Metadata{Name: args[0]},
-			}
-			err := rest.NewRequest(nil, GameServerURL).Post().
-				Bearer(AccessToken.String()).
-				RequestURI("/v1/policies").
-				Body(p).
-				Do().
-				Into(p)
+			})
 			if err != nil {
 				return err
 			}
@@ -110,7 +100,7 @@ func PolicyCreateCmd() *cobra.Command {
 // 		},
 // 		RunE: func(cmd *cobra.Command, args []string) error {
 // 			requestURI := path.Join("/v1/events", args[0])
-// 			_, err := rest.NewRequest(nil, GameServerURL).Delete().
+// 			_, err := rest.NewRequest(HTTPClient, GameServerURL).Delete().
 // 				Bearer(AccessToken).
 // 				RequestURI(requestURI).
 // 				Do().Raw()
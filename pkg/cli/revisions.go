@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kubeplay/gameserver/pkg/client"
+	"github.com/kubeplay/gameserver/pkg/types"
+	"github.com/kubeplay/gameserver/pkg/utils"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Host
+func ChallengeRevisionsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:                   "revisions NAME",
+		PreRunE:               PreLoad,
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing the resource name")
+			}
+			return nil
+		},
+		Short: "[HOST] List the revisions of a challenge.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cs, err := Client()
+			if err != nil {
+				return err
+			}
+			itemList, err := cs.Challenges().Revisions(context.Background(), args[0])
+			if err != nil {
+				return err
+			}
+			w := new(tabwriter.Writer)
+			w.Init(os.Stdout, 0, 8, 2, '\t', tabwriter.AlignRight)
+			defer w.Flush()
+			fmt.Fprintln(w, "REVISION\tKEYS\tBUNDLE\tAGE\t")
+			for _, c := range itemList.Items {
+				digest := "-"
+				if c.Bundle != nil {
+					digest = strings.TrimPrefix(c.Bundle.Digest, "sha256:")[:12]
+				}
+				d := utils.GetDeltaDuration(c.CreatedAt, "")
+				fmt.Fprintf(w, "%d\t%d\t%s\t%s\t\n", c.Generation, len(c.Keys), digest, d)
+			}
+			return nil
+		},
+	}
+}
+
+// Host
+func ChallengeDiffCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:                   "diff NAME REVISION [REVISION]",
+		PreRunE:               PreLoad,
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return errors.New("missing the resource name and the revision")
+			}
+			for _, rev := range args[1:] {
+				if _, err := strconv.ParseInt(rev, 10, 64); err != nil {
+					return fmt.Errorf("invalid revision %q, expected a generation number", rev)
+				}
+			}
+			return nil
+		},
+		Short: "[HOST] Compare two revisions of a challenge, the latest one is used when the second is omitted.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cs, err := Client()
+			if err != nil {
+				return err
+			}
+			from, err := getChallengeRevision(cs, args[0], args[1])
+			if err != nil {
+				return err
+			}
+			var to *types.Challenge
+			if len(args) > 2 {
+				to, err = getChallengeRevision(cs, args[0], args[2])
+			} else {
+				to, err = cs.Challenges().Get(context.Background(), args[0])
+			}
+			if err != nil {
+				return err
+			}
+			a, err := challengeSpecLines(from)
+			if err != nil {
+				return err
+			}
+			b, err := challengeSpecLines(to)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("--- %s revision %d\n+++ %s revision %d\n", from.Name, from.Generation, to.Name, to.Generation)
+			for _, line := range utils.DiffLines(a, b) {
+				fmt.Println(line)
+			}
+			return nil
+		},
+	}
+}
+
+func getChallengeRevision(cs *client.Clientset, name, revision string) (*types.Challenge, error) {
+	generation, err := strconv.ParseInt(revision, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return cs.Challenges().GetRevision(context.Background(), name, generation)
+}
+
+// challengeSpecLines renders the challenge as YAML without its metadata
+func challengeSpecLines(c *types.Challenge) ([]string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	delete(spec, "metadata")
+	data, err = yaml.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), nil
+}
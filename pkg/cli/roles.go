@@ -0,0 +1,210 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kubeplay/gameserver/pkg/client"
+	"github.com/kubeplay/gameserver/pkg/types"
+	"github.com/kubeplay/gameserver/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// Host
+func RoleGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "roles",
+		Aliases:      []string{"role"},
+		PreRunE:      PreLoad,
+		SilenceUsage: true,
+		Short:        "[HOST] Get or list roles.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			isResourceScoped := len(args) > 0
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			w := new(tabwriter.Writer)
+			w.Init(os.Stdout, 0, 8, 2, '\t', tabwriter.AlignRight)
+			defer w.Flush()
+			if !isResourceScoped {
+				itemList, err := c.Roles().List(context.Background(), listOptions())
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(w, "NAME\tRULES\tAGE\t")
+				for _, ro := range itemList.Items {
+					d := "-"
+					if ro.CreatedAt != "" {
+						d = utils.GetDeltaDuration(ro.CreatedAt, "")
+					}
+					fmt.Fprintf(w, "%s\t%d\t%s\t\n", ro.Name, len(ro.Rules), d)
+				}
+			} else {
+				ro, err := c.Roles().Get(context.Background(), args[0])
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(w, "OBJECT\tACTIONS\t")
+				for _, rule := range ro.Rules {
+					fmt.Fprintf(w, "%s\t%s\t\n", rule.Object, rule.Actions)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// Host
+func RoleBindingGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "rolebindings",
+		Aliases:      []string{"rolebinding"},
+		PreRunE:      PreLoad,
+		SilenceUsage: true,
+		Short:        "[HOST] Get or list role bindings.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			isResourceScoped := len(args) > 0
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			var items []types.RoleBinding
+			if !isResourceScoped {
+				itemList, err := c.RoleBindings().List(context.Background(), listOptions())
+				if err != nil {
+					return err
+				}
+				items = itemList.Items
+			} else {
+				rb, err := c.RoleBindings().Get(context.Background(), args[0])
+				if err != nil {
+					return err
+				}
+				items = append(items, *rb)
+			}
+			w := new(tabwriter.Writer)
+			w.Init(os.Stdout, 0, 8, 2, '\t', tabwriter.AlignRight)
+			defer w.Flush()
+			fmt.Fprintln(w, "NAME\tROLE\tEVENT\tSUBJECTS\tAGE\t")
+			for _, rb := range items {
+				event := rb.Event
+				if event == "" {
+					event = "*"
+				}
+				d := utils.GetDeltaDuration(rb.CreatedAt, "")
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n",
+					rb.Name,
+					rb.Role,
+					event,
+					strings.Join(rb.Subjects, ","),
+					d,
+				)
+			}
+			return nil
+		},
+	}
+}
+
+// Host
+func RoleBindingCreateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "rolebinding NAME",
+		PreRunE:      PreLoad,
+		SilenceUsage: true,
+		Short:        "[HOST] Grant a role to subjects.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing the resource name")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rb := &types.RoleBinding{
+				TypeMeta: types.TypeMeta{Kind: types.RoleBindingKind},
+				Metadata: types.Metadata{Name: args[0]},
+				Role:     O.RoleBindings.Role,
+				Subjects: O.RoleBindings.Subjects,
+				Event:    O.RoleBindings.Event,
+			}
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			rb, err = c.RoleBindings().Create(context.Background(), rb)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("RoleBinding %q created with uid %s\n", rb.Name, rb.UID)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&O.RoleBindings.Role, "role", "", "The role to grant, e.g.: host.")
+	cmd.Flags().StringSliceVar(&O.RoleBindings.Subjects, "subject", nil, "The subjects to grant the role, e.g.: github|user.")
+	cmd.Flags().StringVarP(&O.RoleBindings.Event, "event", "e", "", "Grant the role only in this event.")
+	cmd.MarkFlagRequired("role")
+	cmd.MarkFlagRequired("subject")
+	return cmd
+}
+
+// Host
+func RoleDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:                   "roles ROLE",
+		Aliases:               []string{"role"},
+		PreRunE:               PreLoad,
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing the resource name")
+			}
+			return nil
+		},
+		Short: "[HOST] Delete a role by its name.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			if err := c.Roles().Delete(context.Background(), args[0], client.DeleteOptions{}); err != nil {
+				return err
+			}
+			fmt.Printf("Role %q deleted!\n", args[0])
+			return nil
+		},
+	}
+}
+
+// Host
+func RoleBindingDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:                   "rolebindings ROLEBINDING",
+		Aliases:               []string{"rolebinding"},
+		PreRunE:               PreLoad,
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing the resource name")
+			}
+			return nil
+		},
+		Short: "[HOST] Delete a role binding by its name.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			if err := c.RoleBindings().Delete(context.Background(), args[0], client.DeleteOptions{}); err != nil {
+				return err
+			}
+			fmt.Printf("RoleBinding %q deleted!\n", args[0])
+			return nil
+		},
+	}
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/kubeplay/gameserver/pkg/client"
+	"github.com/kubeplay/gameserver/pkg/types"
+	"github.com/kubeplay/gameserver/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// Host
+func SessionGetCmd() *cobra.Command {
+	var subject string
+	cmd := &cobra.Command{
+		Use:          "sessions",
+		Aliases:      []string{"session"},
+		PreRunE:      PreLoad,
+		SilenceUsage: true,
+		Short:        "[HOST] Get or list active sessions.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			isResourceScoped := len(args) > 0
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			w := new(tabwriter.Writer)
+			w.Init(os.Stdout, 0, 8, 2, '\t', tabwriter.AlignRight)
+			defer w.Flush()
+			fmt.Fprintln(w, "NAME\tSUBJECT\tEXPIRES\tAGE\t")
+			var items []types.Session
+			if !isResourceScoped {
+				opts := listOptions()
+				opts.Subject = subject
+				itemList, err := c.Sessions().List(context.Background(), opts)
+				if err != nil {
+					return err
+				}
+				items = itemList.Items
+			} else {
+				sess, err := c.Sessions().Get(context.Background(), args[0])
+				if err != nil {
+					return err
+				}
+				items = append(items, *sess)
+			}
+			for _, sess := range items {
+				d := utils.GetDeltaDuration(sess.CreatedAt, "")
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", sess.Name, sess.Subject, sess.ExpiresAt, d)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&subject, "subject", "", "List only the sessions of the subject, e.g.: github|user.")
+	return cmd
+}
+
+// Host
+func SessionDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:                   "sessions SESSION",
+		Aliases:               []string{"session"},
+		PreRunE:               PreLoad,
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing the resource name")
+			}
+			return nil
+		},
+		Short: "[HOST] Revoke a session and its tokens.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			if err := c.Sessions().Delete(context.Background(), args[0], client.DeleteOptions{}); err != nil {
+				return err
+			}
+			fmt.Printf("Session %q revoked!\n", args[0])
+			return nil
+		},
+	}
+}
@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/kubeplay/gameserver/pkg/client"
+	"github.com/kubeplay/gameserver/pkg/types"
+	"github.com/kubeplay/gameserver/pkg/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Host
+func UserGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:          "users",
+		Aliases:      []string{"user"},
+		PreRunE:      PreLoad,
+		SilenceUsage: true,
+		Short:        "[HOST] Get or list local user accounts.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			isResourceScoped := len(args) > 0
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			w := new(tabwriter.Writer)
+			w.Init(os.Stdout, 0, 8, 2, '\t', tabwriter.AlignRight)
+			defer w.Flush()
+			fmt.Fprintln(w, "NAME\tDISPLAY NAME\tEMAIL\tAGE\t")
+			if !isResourceScoped {
+				itemList, err := c.Users().List(context.Background(), listOptions())
+				if err != nil {
+					return err
+				}
+				for _, u := range itemList.Items {
+					d := utils.GetDeltaDuration(u.CreatedAt, "")
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", u.Name, u.DisplayName, u.Email, d)
+				}
+			} else {
+				u, err := c.Users().Get(context.Background(), args[0])
+				if err != nil {
+					return err
+				}
+				d := utils.GetDeltaDuration(u.CreatedAt, "")
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", u.Name, u.DisplayName, u.Email, d)
+			}
+			return nil
+		},
+	}
+}
+
+// Host
+func UserCreateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "user [NAME]",
+		PreRunE:      PreLoad,
+		SilenceUsage: true,
+		Short:        "[HOST] Create local user accounts.",
+		Long: `Create a local user account, the password is prompted interactively.
+
+Users could be imported in bulk using a CSV file with the columns:
+username,password[,display name[,email]]`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 && O.Users.FromCSV == "" {
+				return errors.New("missing the resource name")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if O.Users.FromCSV != "" {
+				return importUsersFromCSV(O.Users.FromCSV)
+			}
+			fmt.Print("Enter the user password: ")
+			password, err := terminal.ReadPassword(int(syscall.Stdin))
+			fmt.Println()
+			if err != nil {
+				return err
+			}
+			u := &types.User{
+				TypeMeta:    types.TypeMeta{Kind: types.UserKind},
+				Metadata:    types.Metadata{Name: args[0]},
+				DisplayName: O.Users.DisplayName,
+				Email:       O.Users.Email,
+				Password:    strings.TrimSpace(string(password)),
+			}
+			if err := createUser(u); err != nil {
+				return err
+			}
+			fmt.Printf("User %q created with uid %s\n", u.Name, u.UID)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&O.Users.DisplayName, "display-name", "", "The name displayed for the user.")
+	cmd.Flags().StringVar(&O.Users.Email, "email", "", "The e-mail of the user.")
+	cmd.Flags().StringVar(&O.Users.FromCSV, "from-csv", "", "Import users in bulk from a CSV file.")
+	return cmd
+}
+
+// Host
+func UserDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:                   "users USER",
+		Aliases:               []string{"user"},
+		PreRunE:               PreLoad,
+		SilenceUsage:          true,
+		DisableFlagsInUseLine: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing the resource name")
+			}
+			return nil
+		},
+		Short: "[HOST] Delete a local user account.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := Client()
+			if err != nil {
+				return err
+			}
+			if err := c.Users().Delete(context.Background(), args[0], client.DeleteOptions{}); err != nil {
+				return err
+			}
+			fmt.Printf("User %q deleted!\n", args[0])
+			return nil
+		},
+	}
+}
+
+func createUser(u *types.User) error {
+	c, err := Client()
+	if err != nil {
+		return err
+	}
+	created, err := c.Users().Create(context.Background(), u)
+	if err != nil {
+		return err
+	}
+	*u = *created
+	return nil
+}
+
+func importUsersFromCSV(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	for line := 1; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// skip the header
+		if line == 1 && record[0] == "username" {
+			continue
+		}
+		if len(record) < 2 {
+			return fmt.Errorf("line %d: expected at least the username and password columns", line)
+		}
+		u := &types.User{
+			TypeMeta: types.TypeMeta{Kind: types.UserKind},
+			Metadata: types.Metadata{Name: record[0]},
+			Password: record[1],
+		}
+		if len(record) > 2 {
+			u.DisplayName = record[2]
+		}
+		if len(record) > 3 {
+			u.Email = record[3]
+		}
+		if err := createUser(u); err != nil {
+			return fmt.Errorf("line %d: failed creating user %q: %v", line, u.Name, err)
+		}
+		fmt.Printf("User %q created with uid %s\n", u.Name, u.UID)
+	}
+}
@@ -2,25 +2,37 @@ package cli
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/x509"
 	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/kubeplay/gameserver/pkg/client"
+	"github.com/kubeplay/gameserver/pkg/rest"
 	"github.com/kubeplay/gameserver/pkg/types"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
-const KubeplayAddrEnv = "KUBEPLAY_ADDR"
+const (
+	KubeplayAddrEnv               = "KUBEPLAY_ADDR"
+	KubeplayCAFileEnv             = "KUBEPLAY_CA_FILE"
+	KubeplayInsecureSkipVerifyEnv = "KUBEPLAY_INSECURE_SKIP_TLS_VERIFY"
+	KubeplayClientCertEnv         = "KUBEPLAY_CLIENT_CERT"
+	KubeplayClientKeyEnv          = "KUBEPLAY_CLIENT_KEY"
+)
 
 type Token struct {
 	Data []byte
@@ -47,48 +59,187 @@ func (t *Token) String() string {
 }
 
 var (
-	KubePlayConfig   = os.ExpandEnv("$HOME/.kubeplay")
-	KubePlayToken    = path.Join(os.ExpandEnv(KubePlayConfig), "credentials")
-	AccessToken      = &Token{}
-	GameServerURL, _ = url.Parse(os.Getenv("KUBEPLAY_ADDR"))
+	KubePlayConfig       = os.ExpandEnv("$HOME/.kubeplay")
+	KubePlayToken        = path.Join(os.ExpandEnv(KubePlayConfig), "credentials")
+	KubePlayRefreshToken = path.Join(os.ExpandEnv(KubePlayConfig), "refresh-token")
+	AccessToken          = &Token{}
+	GameServerURL, _     = url.Parse(os.Getenv("KUBEPLAY_ADDR"))
+	// HTTPClient performs the requests to the game server, it's configured by LoadTLSConfig
+	HTTPClient rest.HTTPClient
 )
 
+// LoadTLSConfig configures the HTTP client with the TLS flags, falling back to
+// the environment variables when a flag isn't set
+func LoadTLSConfig(cmd *cobra.Command, args []string) error {
+	t := &O.TLS
+	if t.CAFile == "" {
+		t.CAFile = os.Getenv(KubeplayCAFileEnv)
+	}
+	if !t.InsecureSkipVerify {
+		t.InsecureSkipVerify, _ = strconv.ParseBool(os.Getenv(KubeplayInsecureSkipVerifyEnv))
+	}
+	if t.CertFile == "" {
+		t.CertFile = os.Getenv(KubeplayClientCertEnv)
+	}
+	if t.KeyFile == "" {
+		t.KeyFile = os.Getenv(KubeplayClientKeyEnv)
+	}
+	if t.InsecureSkipVerify {
+		fmt.Fprintln(os.Stderr, "WARNING: the server certificate won't be verified, use it only for development")
+	}
+	client, err := rest.NewHTTPClient(t)
+	if err != nil {
+		return err
+	}
+	HTTPClient = client
+	return nil
+}
+
 func PreLoad(cmd *cobra.Command, args []string) (err error) {
 	if GameServerURL == nil {
 		return fmt.Errorf("Wrong or missing kubeplay address %q", KubeplayAddrEnv)
 	}
 	AccessToken.Data, err = ioutil.ReadFile(KubePlayToken)
 	AccessToken.Data = bytes.TrimSuffix(AccessToken.Data, []byte("\n"))
-	return
+	if os.IsNotExist(err) && O.TLS.CertFile != "" {
+		// authenticated by the client certificate
+		return nil
+	}
+	if err != nil {
+		return
+	}
+	claims, err := AccessToken.Claims()
+	if err != nil {
+		return err
+	}
+	// Refresh the access token when it's about to expire
+	if time.Until(time.Unix(claims.ExpiresAt, 0)) > time.Minute {
+		return nil
+	}
+	if err := RefreshCredentials(); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: your session has expired, please login again (%v)\n", err)
+	}
+	return nil
 }
 
-// TODO: check if the token is expired
-func WriteCredentials(accessToken []byte) error {
+// RefreshCredentials exchanges the refresh token for a new access token
+func RefreshCredentials() error {
+	refreshToken, err := ioutil.ReadFile(KubePlayRefreshToken)
+	if err != nil {
+		return err
+	}
+	c, err := Client()
+	if err != nil {
+		return err
+	}
+	player, err := c.Auth().Refresh(context.Background(), string(bytes.TrimSpace(refreshToken)))
+	if err != nil {
+		return err
+	}
+	AccessToken.Data = []byte(player.AccessToken)
+	return WriteCredentials(AccessToken.Data, []byte(player.RefreshToken))
+}
+
+func WriteCredentials(accessToken, refreshToken []byte) error {
 	fi, err := os.Stat(KubePlayConfig)
 	if os.IsNotExist(err) {
 		if err := os.MkdirAll(KubePlayConfig, 0744); err != nil {
 			return err
 		}
-		return ioutil.WriteFile(KubePlayToken, append(accessToken, '\n'), 0600)
+		return writeCredentialFiles(accessToken, refreshToken)
 	}
 	switch mode := fi.Mode(); {
 	case mode.IsDir():
-		return ioutil.WriteFile(KubePlayToken, append(accessToken, '\n'), 0600)
+		return writeCredentialFiles(accessToken, refreshToken)
 	default:
 		return fmt.Errorf("kubeplay config path %q is a file", KubePlayConfig)
 	}
 }
 
+func writeCredentialFiles(accessToken, refreshToken []byte) error {
+	if err := ioutil.WriteFile(KubePlayToken, append(accessToken, '\n'), 0600); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(KubePlayRefreshToken, append(refreshToken, '\n'), 0600)
+}
+
+// RemoveCredentials erases the credentials stored locally
+func RemoveCredentials() error {
+	for _, file := range []string{KubePlayToken, KubePlayRefreshToken} {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
 type CmdGames struct {
 	Challenge string
 	Event     string
+	AllEvents bool
+	Mine      bool
+}
+
+type CmdEvents struct {
+	GameTimeout time.Duration
+}
+
+type CmdLogin struct {
+	Provider string
+}
+
+type CmdUsers struct {
+	DisplayName string
+	Email       string
+	FromCSV     string
+}
+
+type CmdRoleBindings struct {
+	Role     string
+	Subjects []string
+	Event    string
+}
+
+type CmdEventHooks struct {
+	URL      string
+	Secret   string
+	Event    string
+	Triggers []string
+	Template string
+}
+
+type CmdBundles struct {
+	OutputDir string
+}
+
+// CmdExport are the files of the export and import commands
+type CmdExport struct {
+	OutputFile string
+	InputFile  string
+}
+
+// CmdSelectors filter the lists of the get commands
+type CmdSelectors struct {
+	Labels string
+	Fields string
 }
 
 type CmdOptions struct {
 	ShowVersionAndExit bool
 
-	Games       CmdGames
-	CreateInput string
+	Games        CmdGames
+	Events       CmdEvents
+	Login        CmdLogin
+	Users        CmdUsers
+	RoleBindings CmdRoleBindings
+	EventHooks   CmdEventHooks
+	Bundles      CmdBundles
+	Export       CmdExport
+	Selectors    CmdSelectors
+	TLS          rest.TLSConfig
+	CreateInput  string
+	// Cascade is the deletion policy of the dependents: block, cascade or orphan
+	Cascade string
 }
 
 type CreateVar struct {
@@ -112,6 +263,57 @@ func (v *CreateVar) Type() string {
 	return ""
 }
 
+// currentSubject returns the player of the credentials, e.g.: github|user or x509|name
+func currentSubject() (string, error) {
+	if len(AccessToken.Data) > 0 {
+		claims, err := AccessToken.Claims()
+		if err != nil {
+			return "", err
+		}
+		return claims.Username(), nil
+	}
+	if O.TLS.CertFile == "" {
+		return "", fmt.Errorf("missing credentials, login first")
+	}
+	data, err := ioutil.ReadFile(O.TLS.CertFile)
+	if err != nil {
+		return "", err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", fmt.Errorf("failed decoding the client certificate %q", O.TLS.CertFile)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	claims, err := types.ClientCertificateClaims(cert)
+	if err != nil {
+		return "", err
+	}
+	return claims.Username(), nil
+}
+
+// Client returns the clientset of the game server with the current credentials
+func Client() (*client.Clientset, error) {
+	if GameServerURL == nil {
+		return nil, fmt.Errorf("Wrong or missing kubeplay address %q", KubeplayAddrEnv)
+	}
+	return client.New(&client.Config{
+		Host:        GameServerURL.String(),
+		HTTPClient:  HTTPClient,
+		BearerToken: AccessToken.String(),
+	})
+}
+
+// listOptions are the selectors of the get commands, the items of every page are listed
+func listOptions() client.ListOptions {
+	return client.ListOptions{
+		LabelSelector: O.Selectors.Labels,
+		FieldSelector: O.Selectors.Fields,
+	}
+}
+
 func SolveGameKey(gameKeyHash, gameUID, keyName string, key types.Key) bool {
 	hash := hmac.New(sha256.New, []byte(key.Value))
 	hash.Write([]byte(gameUID))
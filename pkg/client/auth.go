@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+
+	"github.com/kubeplay/gameserver/pkg/rest"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// AuthInterface authenticates the players and checks their permissions
+type AuthInterface interface {
+	// Login exchanges the credentials of a provider (github or local) for tokens
+	Login(ctx context.Context, provider string, credentials *rest.BasicAuth) (*types.PlayerClaims, error)
+	// Refresh exchanges a refresh token for a new access token
+	Refresh(ctx context.Context, refreshToken string) (*types.PlayerClaims, error)
+	// Logout revokes the session of the access token
+	Logout(ctx context.Context) error
+	// CanI reviews an action of the current subject
+	CanI(ctx context.Context, review *types.SelfSubjectAccessReview) (*types.SelfSubjectAccessReview, error)
+	// Review reviews an action of any subject
+	Review(ctx context.Context, review *types.SubjectAccessReview) (*types.SubjectAccessReview, error)
+}
+
+// Auth returns the client of the authentication and the access reviews
+func (c *Clientset) Auth() AuthInterface {
+	return &auth{c: c}
+}
+
+type auth struct {
+	c *Clientset
+}
+
+func (a *auth) Login(ctx context.Context, provider string, credentials *rest.BasicAuth) (*types.PlayerClaims, error) {
+	player := &types.PlayerClaims{}
+	req := a.c.request(ctx, "GET", "login").
+		BasicAuth(credentials).
+		AddQuery("provider", provider)
+	if err := into(req.Do(), player); err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
+func (a *auth) Refresh(ctx context.Context, refreshToken string) (*types.PlayerClaims, error) {
+	player := &types.PlayerClaims{}
+	req := a.c.request(ctx, "POST", "refresh").
+		SetHeader(types.RefreshTokenHeaderName, refreshToken)
+	if err := into(req.Do(), player); err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
+func (a *auth) Logout(ctx context.Context) error {
+	return into(a.c.request(ctx, "POST", "logout").Do(), nil)
+}
+
+func (a *auth) CanI(ctx context.Context, review *types.SelfSubjectAccessReview) (*types.SelfSubjectAccessReview, error) {
+	result := &types.SelfSubjectAccessReview{}
+	r := &resource{c: a.c, path: []string{"selfsubjectaccessreviews"}}
+	if err := r.create(ctx, review, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (a *auth) Review(ctx context.Context, review *types.SubjectAccessReview) (*types.SubjectAccessReview, error) {
+	result := &types.SubjectAccessReview{}
+	r := &resource{c: a.c, path: []string{"subjectaccessreviews"}}
+	if err := r.create(ctx, review, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
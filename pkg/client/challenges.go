@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/kubeplay/gameserver/pkg/bundle"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// ChallengeInterface manages the challenges, their revisions and bundles
+type ChallengeInterface interface {
+	List(ctx context.Context, opts ListOptions) (*types.ChallengeList, error)
+	Get(ctx context.Context, name string) (*types.Challenge, error)
+	Create(ctx context.Context, c *types.Challenge) (*types.Challenge, error)
+	Update(ctx context.Context, c *types.Challenge) (*types.Challenge, error)
+	Delete(ctx context.Context, name string, opts DeleteOptions) error
+	// Revisions lists the previous revisions of a challenge
+	Revisions(ctx context.Context, name string) (*types.ChallengeList, error)
+	// GetRevision returns a revision by its generation
+	GetRevision(ctx context.Context, name string, generation int64) (*types.Challenge, error)
+	// GetBundle returns the challenge with the public files of its bundle
+	GetBundle(ctx context.Context, name string) (*types.Challenge, error)
+	// PushBundle creates or replaces a challenge from a bundle archive, see bundle.Bundle.Archive
+	PushBundle(ctx context.Context, name string, archive []byte) (*types.Challenge, error)
+}
+
+// AssetInterface downloads the files of the bundles
+type AssetInterface interface {
+	// Get returns a file by its digest, e.g.: sha256:<hex>
+	Get(ctx context.Context, digest string) ([]byte, error)
+}
+
+// Challenges returns the client of the challenges
+func (c *Clientset) Challenges() ChallengeInterface {
+	return &challenges{resource{c: c, path: []string{"challenges"}}}
+}
+
+// Assets returns the client of the files of the bundles
+func (c *Clientset) Assets() AssetInterface {
+	return &assets{resource{c: c, path: []string{"assets"}}}
+}
+
+type challenges struct {
+	resource
+}
+
+func (r *challenges) List(ctx context.Context, opts ListOptions) (*types.ChallengeList, error) {
+	list := &types.ChallengeList{}
+	if err := r.list(ctx, opts, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *challenges) Get(ctx context.Context, name string) (*types.Challenge, error) {
+	c := &types.Challenge{}
+	if err := r.get(ctx, name, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *challenges) Create(ctx context.Context, c *types.Challenge) (*types.Challenge, error) {
+	result := &types.Challenge{}
+	if err := r.create(ctx, c, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *challenges) Update(ctx context.Context, c *types.Challenge) (*types.Challenge, error) {
+	result := &types.Challenge{}
+	if err := r.update(ctx, c.Name, c, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *challenges) Delete(ctx context.Context, name string, opts DeleteOptions) error {
+	return r.delete(ctx, name, opts)
+}
+
+func (r *challenges) Revisions(ctx context.Context, name string) (*types.ChallengeList, error) {
+	list := &types.ChallengeList{}
+	revisions := &resource{c: r.c, path: r.itemPath(name, "revisions")}
+	if err := revisions.list(ctx, ListOptions{}, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *challenges) GetRevision(ctx context.Context, name string, generation int64) (*types.Challenge, error) {
+	c := &types.Challenge{}
+	req := r.c.request(ctx, "GET", r.itemPath(name, "revisions", strconv.FormatInt(generation, 10))...)
+	if err := into(req.Do(), c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *challenges) GetBundle(ctx context.Context, name string) (*types.Challenge, error) {
+	c := &types.Challenge{}
+	if err := into(r.c.request(ctx, "GET", r.itemPath(name, "bundle")...).Do(), c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *challenges) PushBundle(ctx context.Context, name string, archive []byte) (*types.Challenge, error) {
+	c := &types.Challenge{}
+	req := r.c.request(ctx, "PUT", r.itemPath(name, "bundle")...).
+		RawBody(archive, bundle.MediaType)
+	if err := into(req.Do(), c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+type assets struct {
+	resource
+}
+
+func (r *assets) Get(ctx context.Context, digest string) ([]byte, error) {
+	return raw(r.c.request(ctx, "GET", r.itemPath(digest)...).Do())
+}
@@ -0,0 +1,198 @@
+// Package client is a typed clientset of the game server built on the rest
+// package, e.g.: client.Games("meetup").Solve(ctx, name, key)
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/kubeplay/gameserver/pkg/rest"
+	"github.com/kubeplay/gameserver/pkg/selector"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// DefaultPageSize is the number of items requested by page when listing every item
+const DefaultPageSize = 500
+
+// Config holds the options to connect to the game server
+type Config struct {
+	// Host is the address of the game server, e.g.: https://kubeplay.example.com
+	Host string
+	// HTTPClient performs the requests, e.g.: rest.NewHTTPClient with TLS options.
+	// The default client is used when it's nil.
+	HTTPClient rest.HTTPClient
+	// BearerToken is the access token of the requests, the requests are
+	// authenticated by the client certificate of the HTTPClient when it's empty
+	BearerToken string
+	// Version is the path of the API version, defaults to /v1
+	Version string
+}
+
+// Clientset groups the clients of every resource of the game server
+type Clientset struct {
+	baseURL    *url.URL
+	httpClient rest.HTTPClient
+	token      string
+	version    string
+}
+
+// New returns a clientset for the config
+func New(c *Config) (*Clientset, error) {
+	u, err := url.Parse(c.Host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid host %q: %v", c.Host, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid host %q, expected an URL, e.g.: https://kubeplay.example.com", c.Host)
+	}
+	version := c.Version
+	if version == "" {
+		version = "/v1"
+	}
+	return &Clientset{baseURL: u, httpClient: c.HTTPClient, token: c.BearerToken, version: version}, nil
+}
+
+// ListOptions filter and page the lists
+type ListOptions struct {
+	LabelSelector string
+	FieldSelector string
+	// Limit and Continue request a single page, the items of every page are
+	// returned when Limit is zero (they're requested DefaultPageSize at a time).
+	Limit    int64
+	Continue string
+	// Player lists only the games of a player, e.g.: github|user
+	Player string
+	// Subject lists only the sessions of a subject
+	Subject string
+}
+
+// DeleteOptions are the options of the deletions
+type DeleteOptions struct {
+	// Cascade is what happens to the games of events and challenges,
+	// the server blocks the deletion when it's empty.
+	Cascade types.DeletionPolicy
+}
+
+// request returns a request of the API version to the path
+func (c *Clientset) request(ctx context.Context, verb string, segments ...string) *rest.Request {
+	u := *c.baseURL
+	uri := path.Join(append([]string{"/", u.Path, c.version}, segments...)...)
+	req := rest.NewRequest(c.httpClient, &u).
+		Verb(verb).
+		Context(ctx).
+		RequestURI(uri)
+	if c.token != "" {
+		req.Bearer(c.token)
+	}
+	return req
+}
+
+// into decodes the result of a request, obj could be nil to discard the body
+func into(result *rest.Result, obj interface{}) error {
+	if err := result.Error(); err != nil {
+		return err
+	}
+	if !result.IsSuccess() {
+		return newStatusError(result)
+	}
+	if obj == nil {
+		return nil
+	}
+	return result.Into(obj)
+}
+
+// raw returns the body of the result of a request
+func raw(result *rest.Result) ([]byte, error) {
+	if err := into(result, nil); err != nil {
+		return nil, err
+	}
+	return result.Body(), nil
+}
+
+// resource performs the requests of a kind, path is the collection, e.g.: events/meetup/games
+type resource struct {
+	c    *Clientset
+	path []string
+}
+
+func (r *resource) itemPath(name string, subresources ...string) []string {
+	return append(append(append([]string{}, r.path...), name), subresources...)
+}
+
+func (r *resource) get(ctx context.Context, name string, obj interface{}) error {
+	return into(r.c.request(ctx, "GET", r.itemPath(name)...).Do(), obj)
+}
+
+func (r *resource) list(ctx context.Context, opts ListOptions, list interface{}) error {
+	req := r.c.request(ctx, "GET", r.path...)
+	for param, value := range map[string]string{
+		selector.LabelSelectorParam: opts.LabelSelector,
+		selector.FieldSelectorParam: opts.FieldSelector,
+		"player":                    opts.Player,
+		"subject":                   opts.Subject,
+	} {
+		if value != "" {
+			req.AddQuery(param, value)
+		}
+	}
+	if opts.Limit == 0 {
+		return into(req.DoList(DefaultPageSize), list)
+	}
+	req.AddQuery("limit", strconv.FormatInt(opts.Limit, 10))
+	if opts.Continue != "" {
+		req.AddQuery("continue", opts.Continue)
+	}
+	return into(req.Do(), list)
+}
+
+func (r *resource) create(ctx context.Context, obj, result interface{}) error {
+	return into(r.c.request(ctx, "POST", r.path...).Body(obj).Do(), result)
+}
+
+func (r *resource) update(ctx context.Context, name string, obj, result interface{}) error {
+	return into(r.c.request(ctx, "PUT", r.itemPath(name)...).Body(obj).Do(), result)
+}
+
+func (r *resource) delete(ctx context.Context, name string, opts DeleteOptions) error {
+	req := r.c.request(ctx, "DELETE", r.itemPath(name)...)
+	if opts.Cascade != "" {
+		req.AddQuery("cascade", string(opts.Cascade))
+	}
+	return into(req.Do(), nil)
+}
+
+// Create creates an object of any kind but games (they belong to an event),
+// e.g.: the objects of a manifest. The object is updated with the response.
+func (c *Clientset) Create(ctx context.Context, obj types.Object) error {
+	kind := obj.GetObjectKind()
+	if kind == types.GameKind {
+		return fmt.Errorf("games are created by their event, use Games(event).Create")
+	}
+	if !isRegistered(kind) {
+		return fmt.Errorf("unknown kind %q", kind)
+	}
+	r := &resource{c: c, path: []string{resourceName(kind)}}
+	return r.create(ctx, obj, obj)
+}
+
+// resourceName returns the plural name of a kind in the paths, e.g.: policies
+func resourceName(kind string) string {
+	name := strings.ToLower(kind)
+	if strings.HasSuffix(name, "y") {
+		return strings.TrimSuffix(name, "y") + "ies"
+	}
+	return name + "s"
+}
+
+func isRegistered(kind string) bool {
+	for _, obj := range types.RegisteredTypes {
+		if obj.GetObjectKind() == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// newTestClientset serves the handler and returns a clientset authenticated with the token "secret"
+func newTestClientset(t *testing.T, handler http.HandlerFunc) (*Clientset, func()) {
+	srv := httptest.NewServer(handler)
+	c, err := New(&Config{Host: srv.URL, BearerToken: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, srv.Close
+}
+
+func TestNew(t *testing.T) {
+	for _, host := range []string{"", "kubeplay.example.com", "://kubeplay"} {
+		if _, err := New(&Config{Host: host}); err == nil {
+			t.Errorf("%q: expected an invalid host", host)
+		}
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	c, teardown := newTestClientset(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/events/missing":
+			http.Error(w, `event "missing" not found`, http.StatusNotFound)
+		case "/v1/events/meetup":
+			http.Error(w, "event \"meetup\" is used by 1 game(s)", http.StatusConflict)
+		case "/v1/events/meetup/games/g1/solve":
+			http.Error(w, "invalid key", http.StatusForbidden)
+		default:
+			http.Error(w, "unexpected", http.StatusInternalServerError)
+		}
+	})
+	defer teardown()
+	ctx := context.Background()
+
+	_, err := c.Events().Get(ctx, "missing")
+	if !IsNotFound(err) || IsConflict(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+	se, ok := err.(*StatusError)
+	if !ok || se.Message != `event "missing" not found` {
+		t.Errorf("expected the message of the server without the trailing newline, got %#v", err)
+	}
+	if err := c.Events().Delete(ctx, "meetup", DeleteOptions{}); !IsConflict(err) || StatusCode(err) != http.StatusConflict {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+	if _, err := c.Games("meetup").Solve(ctx, "g1", "key"); !IsForbidden(err) {
+		t.Errorf("expected a forbidden error, got %v", err)
+	}
+	if _, err := c.Games("").Solve(ctx, "g1", "key"); err != errMissingEvent {
+		t.Errorf("expected games to be addressed by their event, got %v", err)
+	}
+
+	// errors of the connection aren't status errors
+	teardown()
+	_, err = c.Events().Get(ctx, "meetup")
+	if err == nil || StatusCode(err) != 0 || IsNotFound(err) {
+		t.Errorf("expected a connection error, got %v", err)
+	}
+}
+
+func TestRequests(t *testing.T) {
+	var got []string
+	c, teardown := newTestClientset(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		got = append(got, fmt.Sprintf("%s %s", r.Method, r.URL.RequestURI()))
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/v1/events/meetup/games/g1/solve":
+			fmt.Fprintf(w, `{"kind":"Game","metadata":{"name":"g1"},"status":{"phase":%q}}`, r.Header.Get(types.GameKeyHeaderName))
+		case r.Method == "POST":
+			var obj map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&obj)
+			obj["metadata"].(map[string]interface{})["uid"] = "u1"
+			json.NewEncoder(w).Encode(obj)
+		default:
+			fmt.Fprint(w, `{"kind":"List","items":[]}`)
+		}
+	})
+	defer teardown()
+	ctx := context.Background()
+
+	if _, err := c.Games("meetup").List(ctx, ListOptions{FieldSelector: "status.phase=Running", Limit: 10, Continue: "c1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Games("").List(ctx, ListOptions{Player: "github|alice", Limit: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Challenges().Delete(ctx, "foo", DeleteOptions{Cascade: types.DeletionOrphan}); err != nil {
+		t.Fatal(err)
+	}
+	gm, err := c.Games("meetup").Solve(ctx, "g1", "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gm.Status.Phase != "k1" {
+		t.Errorf("expected the key in the header, got %q", gm.Status.Phase)
+	}
+	ev := &types.Event{TypeMeta: types.TypeMeta{Kind: types.EventKind}, Metadata: types.Metadata{Name: "meetup"}}
+	if err := c.Create(ctx, ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.UID != "u1" {
+		t.Errorf("expected the object to be updated with the response, got %+v", ev.Metadata)
+	}
+	if err := c.Create(ctx, &types.Game{TypeMeta: types.TypeMeta{Kind: types.GameKind}}); err == nil {
+		t.Error("expected games to be created by their event")
+	}
+
+	want := []string{
+		"GET /v1/events/meetup/games?continue=c1&fieldSelector=status.phase%3DRunning&limit=10",
+		"GET /v1/games?limit=1&player=github%7Calice",
+		"DELETE /v1/challenges/foo?cascade=orphan",
+		"POST /v1/events/meetup/games/g1/solve",
+		"POST /v1/events",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected the requests:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestListEveryPage(t *testing.T) {
+	c, teardown := newTestClientset(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("limit") != fmt.Sprint(DefaultPageSize) {
+			t.Errorf("expected pages of %d items, got %s", DefaultPageSize, r.URL.Query().Get("limit"))
+		}
+		switch r.URL.Query().Get("continue") {
+		case "":
+			fmt.Fprint(w, `{"kind":"List","metadata":{"continue":"p2"},"items":[{"metadata":{"name":"e1"}}]}`)
+		case "p2":
+			fmt.Fprint(w, `{"kind":"List","metadata":{},"items":[{"metadata":{"name":"e2"}}]}`)
+		}
+	})
+	defer teardown()
+	list, err := c.Events().List(context.Background(), ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 2 || list.Items[0].Name != "e1" || list.Items[1].Name != "e2" {
+		t.Errorf("expected the items of every page, got %+v", list.Items)
+	}
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kubeplay/gameserver/pkg/rest"
+)
+
+// StatusError is the error of a request refused by the server
+type StatusError struct {
+	StatusCode int
+	// Message is the error returned by the server
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("failed (%d) performing request to the remote server: %s", e.StatusCode, e.Message)
+}
+
+func newStatusError(result *rest.Result) *StatusError {
+	return &StatusError{
+		StatusCode: result.StatusCode(),
+		Message:    strings.TrimSpace(string(result.Body())),
+	}
+}
+
+// StatusCode returns the status of a StatusError, zero for the other errors
+// (e.g.: the server isn't reachable).
+func StatusCode(err error) int {
+	if e, ok := err.(*StatusError); ok {
+		return e.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound returns true when the object doesn't exist
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// IsForbidden returns true when the request isn't allowed, e.g.: an invalid game key
+func IsForbidden(err error) bool {
+	return StatusCode(err) == http.StatusForbidden
+}
+
+// IsUnauthorized returns true when the credentials are invalid or revoked
+func IsUnauthorized(err error) bool {
+	return StatusCode(err) == http.StatusUnauthorized
+}
+
+// IsBadRequest returns true when the server refused the object, e.g.: it
+// already exists or it's invalid.
+func IsBadRequest(err error) bool {
+	return StatusCode(err) == http.StatusBadRequest
+}
+
+// IsConflict returns true when the object was changed or it has dependents,
+// e.g.: deleting an event with games without a cascade policy.
+func IsConflict(err error) bool {
+	return StatusCode(err) == http.StatusConflict
+}
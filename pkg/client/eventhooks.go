@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// EventHookInterface manages the event hooks notifying HTTP endpoints about the games
+type EventHookInterface interface {
+	List(ctx context.Context, opts ListOptions) (*types.EventHookList, error)
+	Get(ctx context.Context, name string) (*types.EventHook, error)
+	Create(ctx context.Context, obj *types.EventHook) (*types.EventHook, error)
+	Update(ctx context.Context, obj *types.EventHook) (*types.EventHook, error)
+	Delete(ctx context.Context, name string, opts DeleteOptions) error
+}
+
+// EventHooks returns the client of the event hooks notifying HTTP endpoints about the games
+func (c *Clientset) EventHooks() EventHookInterface {
+	return &eventHooks{resource{c: c, path: []string{"eventhooks"}}}
+}
+
+type eventHooks struct {
+	resource
+}
+
+func (r *eventHooks) List(ctx context.Context, opts ListOptions) (*types.EventHookList, error) {
+	list := &types.EventHookList{}
+	if err := r.list(ctx, opts, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *eventHooks) Get(ctx context.Context, name string) (*types.EventHook, error) {
+	obj := &types.EventHook{}
+	if err := r.get(ctx, name, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *eventHooks) Create(ctx context.Context, obj *types.EventHook) (*types.EventHook, error) {
+	result := &types.EventHook{}
+	if err := r.create(ctx, obj, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *eventHooks) Update(ctx context.Context, obj *types.EventHook) (*types.EventHook, error) {
+	result := &types.EventHook{}
+	if err := r.update(ctx, obj.Name, obj, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *eventHooks) Delete(ctx context.Context, name string, opts DeleteOptions) error {
+	return r.delete(ctx, name, opts)
+}
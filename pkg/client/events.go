@@ -0,0 +1,144 @@
+package client
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// EventInterface manages the events, the API has no updates of events
+type EventInterface interface {
+	List(ctx context.Context, opts ListOptions) (*types.EventList, error)
+	Get(ctx context.Context, name string) (*types.Event, error)
+	Create(ctx context.Context, ev *types.Event) (*types.Event, error)
+	Delete(ctx context.Context, name string, opts DeleteOptions) error
+}
+
+// GameInterface manages the games of an event, the games are only changed by
+// starting and solving them
+type GameInterface interface {
+	// List lists the games of every event when the event is empty
+	List(ctx context.Context, opts ListOptions) (*types.GameList, error)
+	Get(ctx context.Context, name string) (*types.Game, error)
+	Create(ctx context.Context, gm *types.Game) (*types.Game, error)
+	Delete(ctx context.Context, name string, opts DeleteOptions) error
+	// Start starts a pending game
+	Start(ctx context.Context, name string) (*types.Game, error)
+	// Solve verifies a game key, an invalid key fails with IsForbidden
+	Solve(ctx context.Context, name, key string) (*types.Game, error)
+}
+
+// Events returns the client of the events
+func (c *Clientset) Events() EventInterface {
+	return &events{resource{c: c, path: []string{"events"}}}
+}
+
+// Games returns the client of the games of an event
+func (c *Clientset) Games(event string) GameInterface {
+	return &games{resource: resource{c: c, path: []string{"events", event, "games"}}, event: event}
+}
+
+type events struct {
+	resource
+}
+
+func (e *events) List(ctx context.Context, opts ListOptions) (*types.EventList, error) {
+	list := &types.EventList{}
+	if err := e.list(ctx, opts, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (e *events) Get(ctx context.Context, name string) (*types.Event, error) {
+	ev := &types.Event{}
+	if err := e.get(ctx, name, ev); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+func (e *events) Create(ctx context.Context, ev *types.Event) (*types.Event, error) {
+	result := &types.Event{}
+	if err := e.create(ctx, ev, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (e *events) Delete(ctx context.Context, name string, opts DeleteOptions) error {
+	return e.delete(ctx, name, opts)
+}
+
+type games struct {
+	resource
+	event string
+}
+
+var errMissingEvent = errors.New("games are addressed by their event")
+
+func (g *games) List(ctx context.Context, opts ListOptions) (*types.GameList, error) {
+	r := &g.resource
+	if g.event == "" {
+		r = &resource{c: g.c, path: []string{"games"}}
+	}
+	list := &types.GameList{}
+	if err := r.list(ctx, opts, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (g *games) Get(ctx context.Context, name string) (*types.Game, error) {
+	if g.event == "" {
+		return nil, errMissingEvent
+	}
+	gm := &types.Game{}
+	if err := g.get(ctx, name, gm); err != nil {
+		return nil, err
+	}
+	return gm, nil
+}
+
+func (g *games) Create(ctx context.Context, gm *types.Game) (*types.Game, error) {
+	if g.event == "" {
+		return nil, errMissingEvent
+	}
+	result := &types.Game{}
+	if err := g.create(ctx, gm, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (g *games) Delete(ctx context.Context, name string, opts DeleteOptions) error {
+	if g.event == "" {
+		return errMissingEvent
+	}
+	return g.delete(ctx, name, opts)
+}
+
+func (g *games) Start(ctx context.Context, name string) (*types.Game, error) {
+	if g.event == "" {
+		return nil, errMissingEvent
+	}
+	gm := &types.Game{}
+	if err := into(g.c.request(ctx, "POST", g.itemPath(name, "start")...).Do(), gm); err != nil {
+		return nil, err
+	}
+	return gm, nil
+}
+
+func (g *games) Solve(ctx context.Context, name, key string) (*types.Game, error) {
+	if g.event == "" {
+		return nil, errMissingEvent
+	}
+	gm := &types.Game{}
+	req := g.c.request(ctx, "POST", g.itemPath(name, "solve")...).
+		SetHeader(types.GameKeyHeaderName, key)
+	if err := into(req.Do(), gm); err != nil {
+		return nil, err
+	}
+	return gm, nil
+}
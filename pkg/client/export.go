@@ -0,0 +1,22 @@
+package client
+
+import (
+	"context"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// Export dumps the objects of the game server as multi-document YAML
+func (c *Clientset) Export(ctx context.Context) ([]byte, error) {
+	return raw(c.request(ctx, "GET", "export").Do())
+}
+
+// Import creates the objects of an export, the existing objects are skipped
+func (c *Clientset) Import(ctx context.Context, data []byte) (*types.ImportResult, error) {
+	result := &types.ImportResult{}
+	req := c.request(ctx, "POST", "import").RawBody(data, types.ExportMediaType)
+	if err := into(req.Do(), result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
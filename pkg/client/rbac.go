@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// PolicyInterface manages the policies, the rules of a subject. The API has no
+// updates of policies, they are replaced by deleting and creating them.
+type PolicyInterface interface {
+	List(ctx context.Context, opts ListOptions) (*types.PolicyList, error)
+	Get(ctx context.Context, name string) (*types.Policy, error)
+	Create(ctx context.Context, obj *types.Policy) (*types.Policy, error)
+	Delete(ctx context.Context, name string, opts DeleteOptions) error
+}
+
+// RoleInterface manages the roles, the rules granted by the role bindings
+type RoleInterface interface {
+	List(ctx context.Context, opts ListOptions) (*types.RoleList, error)
+	Get(ctx context.Context, name string) (*types.Role, error)
+	Create(ctx context.Context, obj *types.Role) (*types.Role, error)
+	Update(ctx context.Context, obj *types.Role) (*types.Role, error)
+	Delete(ctx context.Context, name string, opts DeleteOptions) error
+}
+
+// RoleBindingInterface manages the role bindings, the roles granted to subjects
+type RoleBindingInterface interface {
+	List(ctx context.Context, opts ListOptions) (*types.RoleBindingList, error)
+	Get(ctx context.Context, name string) (*types.RoleBinding, error)
+	Create(ctx context.Context, obj *types.RoleBinding) (*types.RoleBinding, error)
+	Update(ctx context.Context, obj *types.RoleBinding) (*types.RoleBinding, error)
+	Delete(ctx context.Context, name string, opts DeleteOptions) error
+}
+
+// Policies returns the client of the policies, the rules of a subject
+func (c *Clientset) Policies() PolicyInterface {
+	return &policies{resource{c: c, path: []string{"policies"}}}
+}
+
+// Roles returns the client of the roles, the rules granted by the role bindings
+func (c *Clientset) Roles() RoleInterface {
+	return &roles{resource{c: c, path: []string{"roles"}}}
+}
+
+// RoleBindings returns the client of the role bindings, the roles granted to subjects
+func (c *Clientset) RoleBindings() RoleBindingInterface {
+	return &roleBindings{resource{c: c, path: []string{"rolebindings"}}}
+}
+
+type policies struct {
+	resource
+}
+
+func (r *policies) List(ctx context.Context, opts ListOptions) (*types.PolicyList, error) {
+	list := &types.PolicyList{}
+	if err := r.list(ctx, opts, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *policies) Get(ctx context.Context, name string) (*types.Policy, error) {
+	obj := &types.Policy{}
+	if err := r.get(ctx, name, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *policies) Create(ctx context.Context, obj *types.Policy) (*types.Policy, error) {
+	result := &types.Policy{}
+	if err := r.create(ctx, obj, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *policies) Delete(ctx context.Context, name string, opts DeleteOptions) error {
+	return r.delete(ctx, name, opts)
+}
+
+type roles struct {
+	resource
+}
+
+func (r *roles) List(ctx context.Context, opts ListOptions) (*types.RoleList, error) {
+	list := &types.RoleList{}
+	if err := r.list(ctx, opts, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *roles) Get(ctx context.Context, name string) (*types.Role, error) {
+	obj := &types.Role{}
+	if err := r.get(ctx, name, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *roles) Create(ctx context.Context, obj *types.Role) (*types.Role, error) {
+	result := &types.Role{}
+	if err := r.create(ctx, obj, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *roles) Update(ctx context.Context, obj *types.Role) (*types.Role, error) {
+	result := &types.Role{}
+	if err := r.update(ctx, obj.Name, obj, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *roles) Delete(ctx context.Context, name string, opts DeleteOptions) error {
+	return r.delete(ctx, name, opts)
+}
+
+type roleBindings struct {
+	resource
+}
+
+func (r *roleBindings) List(ctx context.Context, opts ListOptions) (*types.RoleBindingList, error) {
+	list := &types.RoleBindingList{}
+	if err := r.list(ctx, opts, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *roleBindings) Get(ctx context.Context, name string) (*types.RoleBinding, error) {
+	obj := &types.RoleBinding{}
+	if err := r.get(ctx, name, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *roleBindings) Create(ctx context.Context, obj *types.RoleBinding) (*types.RoleBinding, error) {
+	result := &types.RoleBinding{}
+	if err := r.create(ctx, obj, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *roleBindings) Update(ctx context.Context, obj *types.RoleBinding) (*types.RoleBinding, error) {
+	result := &types.RoleBinding{}
+	if err := r.update(ctx, obj.Name, obj, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *roleBindings) Delete(ctx context.Context, name string, opts DeleteOptions) error {
+	return r.delete(ctx, name, opts)
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// UserInterface manages the local user accounts
+type UserInterface interface {
+	List(ctx context.Context, opts ListOptions) (*types.UserList, error)
+	Get(ctx context.Context, name string) (*types.User, error)
+	Create(ctx context.Context, obj *types.User) (*types.User, error)
+	Update(ctx context.Context, obj *types.User) (*types.User, error)
+	Delete(ctx context.Context, name string, opts DeleteOptions) error
+}
+
+// SessionInterface manages the active sessions, deleting a session revokes its tokens
+type SessionInterface interface {
+	List(ctx context.Context, opts ListOptions) (*types.SessionList, error)
+	Get(ctx context.Context, name string) (*types.Session, error)
+	Delete(ctx context.Context, name string, opts DeleteOptions) error
+}
+
+// Users returns the client of the local user accounts
+func (c *Clientset) Users() UserInterface {
+	return &users{resource{c: c, path: []string{"users"}}}
+}
+
+// Sessions returns the client of the active sessions
+func (c *Clientset) Sessions() SessionInterface {
+	return &sessions{resource{c: c, path: []string{"sessions"}}}
+}
+
+type users struct {
+	resource
+}
+
+func (r *users) List(ctx context.Context, opts ListOptions) (*types.UserList, error) {
+	list := &types.UserList{}
+	if err := r.list(ctx, opts, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *users) Get(ctx context.Context, name string) (*types.User, error) {
+	obj := &types.User{}
+	if err := r.get(ctx, name, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *users) Create(ctx context.Context, obj *types.User) (*types.User, error) {
+	result := &types.User{}
+	if err := r.create(ctx, obj, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *users) Update(ctx context.Context, obj *types.User) (*types.User, error) {
+	result := &types.User{}
+	if err := r.update(ctx, obj.Name, obj, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *users) Delete(ctx context.Context, name string, opts DeleteOptions) error {
+	return r.delete(ctx, name, opts)
+}
+
+type sessions struct {
+	resource
+}
+
+func (r *sessions) List(ctx context.Context, opts ListOptions) (*types.SessionList, error) {
+	list := &types.SessionList{}
+	if err := r.list(ctx, opts, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *sessions) Get(ctx context.Context, name string) (*types.Session, error) {
+	obj := &types.Session{}
+	if err := r.get(ctx, name, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (r *sessions) Delete(ctx context.Context, name string, opts DeleteOptions) error {
+	return r.delete(ctx, name, opts)
+}
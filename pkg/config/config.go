@@ -0,0 +1,247 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/kubeplay/gameserver/pkg/api/auth"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const redacted = "<redacted>"
+
+// ServerConfig holds the configuration of the game server
+type ServerConfig struct {
+	// ListenAddr is the address the API is served, e.g.: 0.0.0.0:8080
+	ListenAddr string `json:"listenAddr" yaml:"listenAddr"`
+	// ShutdownTimeout is how long in-flight requests are drained on SIGTERM
+	ShutdownTimeout time.Duration `json:"shutdownTimeout" yaml:"shutdownTimeout"`
+	// PoliciesFile is where the casbin policies are persisted
+	PoliciesFile string `json:"policiesFile" yaml:"policiesFile"`
+	// SigningKeys are the files of PEM encoded private keys (RSA or ECDSA)
+	// used to sign player tokens, the first key signs new tokens.
+	SigningKeys []string `json:"signingKeys" yaml:"signingKeys"`
+
+	TLS          TLSConfig          `json:"tls" yaml:"tls"`
+	Store        StoreConfig        `json:"store" yaml:"store"`
+	Audit        AuditConfig        `json:"audit" yaml:"audit"`
+	Provisioners ProvisionersConfig `json:"provisioners" yaml:"provisioners"`
+	GitHub       GitHubConfig       `json:"github" yaml:"github"`
+	Bootstrap    BootstrapConfig    `json:"bootstrap" yaml:"bootstrap"`
+}
+
+// TLSConfig serves the API over HTTPS when the certificate pair is set
+type TLSConfig struct {
+	// CertFile and KeyFile are reloaded when they change on disk
+	CertFile string `json:"certFile,omitempty" yaml:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty" yaml:"keyFile,omitempty"`
+	// SelfSigned generates the certificate pair when it doesn't exist (development only)
+	SelfSigned bool `json:"selfSigned,omitempty" yaml:"selfSigned,omitempty"`
+	// ClientCAFile verifies client certificates, the common name of a
+	// certificate is the login and its organizations are the roles.
+	ClientCAFile string `json:"clientCAFile,omitempty" yaml:"clientCAFile,omitempty"`
+	// RequireClientCert rejects connections without a valid client certificate
+	RequireClientCert bool `json:"requireClientCert,omitempty" yaml:"requireClientCert,omitempty"`
+}
+
+// Enabled returns true when the API must be served over HTTPS
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// AuditConfig records the mutating requests, it's disabled without sinks
+type AuditConfig struct {
+	// File receives the events as JSON lines
+	File       string `json:"file,omitempty" yaml:"file,omitempty"`
+	MaxSizeMB  int    `json:"maxSizeMB,omitempty" yaml:"maxSizeMB,omitempty"`
+	MaxBackups int    `json:"maxBackups,omitempty" yaml:"maxBackups,omitempty"`
+	// WebhookURL receives every event as a JSON POST request
+	WebhookURL string `json:"webhookURL,omitempty" yaml:"webhookURL,omitempty"`
+	// PolicyFile decides which requests and bodies are recorded
+	PolicyFile string `json:"policyFile,omitempty" yaml:"policyFile,omitempty"`
+}
+
+// Enabled returns true when any sink is configured
+func (a AuditConfig) Enabled() bool {
+	return a.File != "" || a.WebhookURL != ""
+}
+
+// ProvisionersConfig registers the provisioners the challenges are able to select
+type ProvisionersConfig struct {
+	Exec       ExecProvisionerConfig       `json:"exec,omitempty" yaml:"exec,omitempty"`
+	Kubernetes KubernetesProvisionerConfig `json:"kubernetes,omitempty" yaml:"kubernetes,omitempty"`
+}
+
+// ExecProvisionerConfig registers the "exec" provisioner when the command is set
+type ExecProvisionerConfig struct {
+	Command string   `json:"command,omitempty" yaml:"command,omitempty"`
+	Args    []string `json:"args,omitempty" yaml:"args,omitempty"`
+}
+
+// KubernetesProvisionerConfig registers the "kubernetes" provisioner, it creates a
+// namespace per game. The in-cluster configuration is used when the host is empty.
+type KubernetesProvisionerConfig struct {
+	Enabled         bool   `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Host            string `json:"host,omitempty" yaml:"host,omitempty"`
+	TokenFile       string `json:"tokenFile,omitempty" yaml:"tokenFile,omitempty"`
+	CAFile          string `json:"caFile,omitempty" yaml:"caFile,omitempty"`
+	NamespacePrefix string `json:"namespacePrefix,omitempty" yaml:"namespacePrefix,omitempty"`
+}
+
+type StoreConfig struct {
+	// File is the path of the bbolt database
+	File   string `json:"file" yaml:"file"`
+	Bucket string `json:"bucket" yaml:"bucket"`
+}
+
+type GitHubConfig struct {
+	// Token is used to verify memberships of organizations and teams
+	Token string `json:"token,omitempty" yaml:"token,omitempty"`
+	// RoleMappings grants roles to members of organizations and teams
+	RoleMappings []auth.RoleMapping `json:"roleMappings,omitempty" yaml:"roleMappings,omitempty"`
+	// FakeMembershipsFile replaces the GitHub API by static memberships
+	FakeMembershipsFile string `json:"fakeMembershipsFile,omitempty" yaml:"fakeMembershipsFile,omitempty"`
+}
+
+// BootstrapConfig grants a role to a subject in every event on startup
+type BootstrapConfig struct {
+	Subject string `json:"subject,omitempty" yaml:"subject,omitempty"`
+	Role    string `json:"role,omitempty" yaml:"role,omitempty"`
+}
+
+// Default returns the configuration used when it's not overridden by a file or flags
+func Default() *ServerConfig {
+	return &ServerConfig{
+		ListenAddr:      "0.0.0.0:8080",
+		ShutdownTimeout: 30 * time.Second,
+		PoliciesFile:    auth.DefaultPoliciesFile,
+		Store: StoreConfig{
+			File:   "/tmp/kubeplay.db",
+			Bucket: "/registry/v1",
+		},
+		Audit: AuditConfig{
+			MaxSizeMB:  100,
+			MaxBackups: 10,
+		},
+		Bootstrap: BootstrapConfig{
+			Role: auth.HostRole,
+		},
+	}
+}
+
+// Load reads a YAML file on top of the default configuration
+func Load(file string) (*ServerConfig, error) {
+	c := Default()
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("failed decoding config file %q: %v", file, err)
+	}
+	return c, nil
+}
+
+// Validate verifies if the configuration is able to start the server
+func (c *ServerConfig) Validate() error {
+	if _, _, err := net.SplitHostPort(c.ListenAddr); err != nil {
+		return fmt.Errorf("listenAddr: %v", err)
+	}
+	if c.ShutdownTimeout <= 0 {
+		return fmt.Errorf("shutdownTimeout: it must be greater than zero")
+	}
+	if c.PoliciesFile == "" {
+		return fmt.Errorf("policiesFile: it must not be empty")
+	}
+	if len(c.SigningKeys) == 0 {
+		return fmt.Errorf("signingKeys: missing signing keys, at least one PEM encoded private key is required")
+	}
+	for _, key := range c.SigningKeys {
+		if _, err := os.Stat(key); err != nil {
+			return fmt.Errorf("signingKeys: %v", err)
+		}
+	}
+	if c.TLS.Enabled() {
+		if c.TLS.CertFile == "" || c.TLS.KeyFile == "" {
+			return fmt.Errorf("tls: both the certFile and the keyFile are required")
+		}
+		if !c.TLS.SelfSigned {
+			for _, file := range []string{c.TLS.CertFile, c.TLS.KeyFile} {
+				if _, err := os.Stat(file); err != nil {
+					return fmt.Errorf("tls: %v", err)
+				}
+			}
+		}
+	} else if c.TLS.SelfSigned || c.TLS.ClientCAFile != "" || c.TLS.RequireClientCert {
+		return fmt.Errorf("tls: the certFile and the keyFile are required to serve over HTTPS")
+	}
+	if c.TLS.RequireClientCert && c.TLS.ClientCAFile == "" {
+		return fmt.Errorf("tls.clientCAFile: it's required to verify client certificates")
+	}
+	if c.Store.File == "" || c.Store.Bucket == "" {
+		return fmt.Errorf("store: the file and the bucket must not be empty")
+	}
+	if fi, err := os.Stat(c.Store.File); err == nil && fi.IsDir() {
+		return fmt.Errorf("store.file: %q is a directory", c.Store.File)
+	}
+	if c.Audit.MaxSizeMB < 0 || c.Audit.MaxBackups < 0 {
+		return fmt.Errorf("audit: maxSizeMB and maxBackups must not be negative")
+	}
+	if c.Audit.WebhookURL != "" {
+		if u, err := url.Parse(c.Audit.WebhookURL); err != nil || u.Host == "" {
+			return fmt.Errorf("audit.webhookURL: invalid URL %q", c.Audit.WebhookURL)
+		}
+	}
+	if c.Audit.PolicyFile != "" {
+		if _, err := os.Stat(c.Audit.PolicyFile); err != nil {
+			return fmt.Errorf("audit.policyFile: %v", err)
+		}
+	}
+	if k := c.Provisioners.Kubernetes; k.Enabled {
+		if k.Host != "" {
+			if u, err := url.Parse(k.Host); err != nil || u.Host == "" {
+				return fmt.Errorf("provisioners.kubernetes.host: invalid URL %q", k.Host)
+			}
+		}
+		for _, file := range []string{k.TokenFile, k.CAFile} {
+			if file == "" {
+				continue
+			}
+			if _, err := os.Stat(file); err != nil {
+				return fmt.Errorf("provisioners.kubernetes: %v", err)
+			}
+		}
+	}
+	for _, m := range c.GitHub.RoleMappings {
+		if m.Group == "" || m.Role == "" {
+			return fmt.Errorf("github.roleMappings: the group and the role must not be empty")
+		}
+	}
+	if c.GitHub.FakeMembershipsFile != "" {
+		if _, err := os.Stat(c.GitHub.FakeMembershipsFile); err != nil {
+			return fmt.Errorf("github.fakeMembershipsFile: %v", err)
+		}
+	}
+	if c.Bootstrap.Subject != "" && c.Bootstrap.Role == "" {
+		return fmt.Errorf("bootstrap.role: it must not be empty")
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration without secrets, safe to be
+// logged. The files of the keys are kept, only their paths are logged.
+func (c *ServerConfig) Redacted() *ServerConfig {
+	copy := *c
+	if copy.GitHub.Token != "" {
+		copy.GitHub.Token = redacted
+	}
+	// webhooks usually authenticate with a token in the URL
+	if copy.Audit.WebhookURL != "" {
+		copy.Audit.WebhookURL = redacted
+	}
+	return &copy
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedacted(t *testing.T) {
+	c := Default()
+	c.SigningKeys = []string{"/etc/kubeplay/signing.pem", "/etc/kubeplay/previous.pem"}
+	c.TLS.KeyFile = "/etc/kubeplay/tls.key"
+	c.GitHub.Token = "ghp_secret"
+	c.Audit.WebhookURL = "https://hooks.example.com/audit?token=secret"
+
+	r := c.Redacted()
+	if r.GitHub.Token != redacted {
+		t.Errorf("expected the GitHub token to be redacted, got %q", r.GitHub.Token)
+	}
+	if r.Audit.WebhookURL != redacted {
+		t.Errorf("expected the audit webhook URL to be redacted, got %q", r.Audit.WebhookURL)
+	}
+	// the paths of the keys are logged, not their content
+	if !reflect.DeepEqual(r.SigningKeys, c.SigningKeys) {
+		t.Errorf("expected the signing keys to be kept, got %v", r.SigningKeys)
+	}
+	if r.TLS.KeyFile != c.TLS.KeyFile {
+		t.Errorf("expected the TLS key file to be kept, got %q", r.TLS.KeyFile)
+	}
+	// the configuration isn't changed
+	if c.GitHub.Token != "ghp_secret" || c.Audit.WebhookURL == redacted {
+		t.Error("expected the original configuration to be kept")
+	}
+}
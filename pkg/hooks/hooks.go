@@ -0,0 +1,167 @@
+package hooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+const (
+	SignatureHeaderName = "X-Kubeplay-Signature"
+	TriggerHeaderName   = "X-Kubeplay-Trigger"
+	DeliveryHeaderName  = "X-Kubeplay-Delivery"
+)
+
+const (
+	maxAttempts    = 5
+	initialBackoff = time.Second
+)
+
+// Client performs the deliveries, it could be replaced for testing
+var Client = &http.Client{Timeout: 10 * time.Second}
+
+// Payload is the notification of a game lifecycle change
+type Payload struct {
+	ID        string            `json:"id"`
+	Trigger   types.HookTrigger `json:"trigger"`
+	Timestamp string            `json:"timestamp"`
+	Event     string            `json:"event"`
+	Game      string            `json:"game"`
+	Player    string            `json:"player"`
+	Challenge string            `json:"challenge"`
+	// Key is the key solved, only for game.key_solved
+	Key string `json:"key,omitempty"`
+	// Text is a human readable message of the notification
+	Text string `json:"text"`
+}
+
+// NewPayload returns the payload of a trigger, the text is rendered from the game
+func NewPayload(id string, trigger types.HookTrigger, event string, gm *types.Game, key string) *Payload {
+	p := &Payload{
+		ID:        id,
+		Trigger:   trigger,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Event:     event,
+		Game:      gm.Name,
+		Player:    gm.Player,
+		Challenge: gm.Challenge,
+		Key:       key,
+	}
+	_, player := types.SplitSubject(gm.Player)
+	switch trigger {
+	case types.HookGameCreated:
+		p.Text = fmt.Sprintf("%s joined the challenge %s!", player, gm.Challenge)
+	case types.HookGameStarted:
+		p.Text = fmt.Sprintf("%s started the challenge %s!", player, gm.Challenge)
+	case types.HookKeySolved:
+		p.Text = fmt.Sprintf("%s solved %s!", player, key)
+	case types.HookGameCompleted:
+		p.Text = fmt.Sprintf("%s completed the challenge %s!", player, gm.Challenge)
+	case types.HookGameExpired:
+		p.Text = fmt.Sprintf("%s ran out of time in the challenge %s.", player, gm.Challenge)
+	}
+	return p
+}
+
+// Render encodes the payload with the template of the hook, chat templates
+// are compatible with Slack and Discord incoming webhooks.
+func Render(template types.HookTemplate, p *Payload) ([]byte, error) {
+	switch template {
+	case "", types.HookTemplateJSON:
+		return json.Marshal(p)
+	case types.HookTemplateSlack:
+		return json.Marshal(map[string]string{"text": p.Text})
+	case types.HookTemplateDiscord:
+		return json.Marshal(map[string]string{"content": p.Text})
+	}
+	return nil, fmt.Errorf("unknown template %q, expected one of: json, slack or discord", template)
+}
+
+// Sign returns the HMAC-SHA256 of the body, receivers verify it by signing the raw body with the secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Matches returns true if the hook is notified about the trigger in the event
+func Matches(h *types.EventHook, trigger types.HookTrigger, event string) bool {
+	if h.Event != "" && h.Event != event {
+		return false
+	}
+	if len(h.Triggers) == 0 {
+		return true
+	}
+	for _, t := range h.Triggers {
+		if t == trigger {
+			return true
+		}
+	}
+	return false
+}
+
+// Deliver posts the payload to the hook, failures (network errors or 5xx/429 responses)
+// are retried with an exponential backoff. It blocks until the delivery finishes.
+func Deliver(h *types.EventHook, p *Payload) types.HookDelivery {
+	d := types.HookDelivery{
+		ID:        p.ID,
+		Trigger:   p.Trigger,
+		Timestamp: p.Timestamp,
+	}
+	body, err := Render(h.Template, p)
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+	backoff := initialBackoff
+	for d.Attempts < maxAttempts {
+		if d.Attempts > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		d.Attempts++
+		retry, err := post(h, p, body, &d)
+		if err == nil {
+			d.Delivered, d.Error = true, ""
+			return d
+		}
+		d.Error = err.Error()
+		if !retry {
+			return d
+		}
+	}
+	return d
+}
+
+func post(h *types.EventHook, p *Payload, body []byte, d *types.HookDelivery) (bool, error) {
+	req, err := http.NewRequest("POST", h.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TriggerHeaderName, string(p.Trigger))
+	req.Header.Set(DeliveryHeaderName, p.ID)
+	if h.Secret != "" {
+		req.Header.Set(SignatureHeaderName, Sign(h.Secret, body))
+	}
+	resp, err := Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	d.StatusCode = resp.StatusCode
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return false, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+}
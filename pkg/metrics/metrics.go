@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "kubeplay"
+
+// Results of solving game keys
+const (
+	SolveAccepted  = "accepted"
+	SolveDuplicate = "duplicate"
+	SolveRejected  = "rejected"
+)
+
+var (
+	// RequestsTotal counts the API requests by the route template and status code
+	RequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of API requests by method, route template and status code.",
+	}, []string{"method", "route", "code"})
+
+	// RequestDuration measures the latency of the API requests
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of API requests by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "code"})
+
+	// SolveAttempts counts the attempts of solving game keys by its result
+	SolveAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "game",
+		Name:      "solve_attempts_total",
+		Help:      "Number of attempts of solving game keys by event and result (accepted, duplicate or rejected).",
+	}, []string{"event", "result"})
+
+	// KeysSolved counts the keys solved for the first time in a game
+	KeysSolved = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "game",
+		Name:      "keys_solved_total",
+		Help:      "Number of keys solved by challenge and key.",
+	}, []string{"challenge", "key"})
+
+	// StoreOperationDuration measures the latency of the database operations
+	StoreOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "store",
+		Name:      "operation_duration_seconds",
+		Help:      "Latency of database operations by operation (get, list, save, update or delete).",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		RequestsTotal,
+		RequestDuration,
+		SolveAttempts,
+		KeysSolved,
+		StoreOperationDuration,
+	)
+}
+
+// ObserveStoreOperation records the latency of an operation started at start, e.g.:
+//
+//	defer metrics.ObserveStoreOperation("get", time.Now())
+func ObserveStoreOperation(operation string, start time.Time) {
+	StoreOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
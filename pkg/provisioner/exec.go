@@ -0,0 +1,120 @@
+package provisioner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	ActionProvision   = "provision"
+	ActionDeprovision = "deprovision"
+)
+
+// maxOutputMessage limits the output of a failed script recorded in the game status
+const maxOutputMessage = 512
+
+var (
+	// outputLine is how scripts report outputs, e.g.: kubeplay-output url=https://...
+	outputLine    = regexp.MustCompile(`^kubeplay-output ([a-zA-Z0-9_.-]+)=(.*)$`)
+	invalidEnvKey = regexp.MustCompile(`[^A-Z0-9_]+`)
+)
+
+// Exec runs a script configured in the game server, the challenges only select it
+// and pass params, they're never able to choose the command.
+//
+// The script receives the action (provision or deprovision) as its first argument
+// and the game in environment variables: KUBEPLAY_EVENT, KUBEPLAY_GAME, KUBEPLAY_GAME_UID,
+// KUBEPLAY_PLAYER, KUBEPLAY_CHALLENGE, KUBEPLAY_ENVIRONMENT and KUBEPLAY_PARAM_<NAME>.
+// The manifests of the challenge bundle are written in KUBEPLAY_MANIFESTS_DIR.
+// Lines in the format "kubeplay-output <key>=<value>" are recorded as outputs.
+type Exec struct {
+	Command string
+	Args    []string
+}
+
+// NewExec returns a provisioner running the command
+func NewExec(command string, args ...string) (*Exec, error) {
+	if _, err := exec.LookPath(command); err != nil {
+		return nil, err
+	}
+	return &Exec{Command: command, Args: args}, nil
+}
+
+func (e *Exec) Provision(ctx context.Context, req *Request) (map[string]string, error) {
+	return e.run(ctx, ActionProvision, req)
+}
+
+func (e *Exec) Deprovision(ctx context.Context, req *Request) error {
+	_, err := e.run(ctx, ActionDeprovision, req)
+	return err
+}
+
+func (e *Exec) run(ctx context.Context, action string, req *Request) (map[string]string, error) {
+	cmd := exec.CommandContext(ctx, e.Command, append(append([]string{}, e.Args...), action)...)
+	cmd.Env = append(os.Environ(),
+		"KUBEPLAY_EVENT="+req.Event,
+		"KUBEPLAY_GAME="+req.Game.Name,
+		"KUBEPLAY_GAME_UID="+req.Game.UID,
+		"KUBEPLAY_PLAYER="+req.Game.Player,
+		"KUBEPLAY_CHALLENGE="+req.Challenge.Name,
+		"KUBEPLAY_ENVIRONMENT="+EnvironmentName("", req),
+	)
+	for key, value := range req.Params {
+		envKey := invalidEnvKey.ReplaceAllString(strings.ToUpper(key), "_")
+		cmd.Env = append(cmd.Env, fmt.Sprintf("KUBEPLAY_PARAM_%s=%s", envKey, value))
+	}
+	if len(req.Manifests) > 0 {
+		dir, err := writeManifests(req.Manifests)
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		cmd.Env = append(cmd.Env, "KUBEPLAY_MANIFESTS_DIR="+dir)
+	}
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(out.String())
+		if len(msg) > maxOutputMessage {
+			msg = "..." + msg[len(msg)-maxOutputMessage:]
+		}
+		return nil, fmt.Errorf("%s failed: %v: %s", action, err, msg)
+	}
+	outputs := map[string]string{}
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		if m := outputLine.FindStringSubmatch(scanner.Text()); m != nil {
+			outputs[m[1]] = m[2]
+		}
+	}
+	return outputs, nil
+}
+
+// writeManifests writes the manifests in a temporary directory removed after the script runs
+func writeManifests(manifests map[string][]byte) (string, error) {
+	dir, err := ioutil.TempDir("", "kubeplay-manifests")
+	if err != nil {
+		return "", err
+	}
+	for name, data := range manifests {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+		if err := ioutil.WriteFile(file, data, 0600); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
@@ -0,0 +1,57 @@
+package provisioner
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestExecOutputs(t *testing.T) {
+	script := `
+echo "provisioning $KUBEPLAY_EVENT/$KUBEPLAY_GAME"
+echo "kubeplay-output action=$1"
+echo "kubeplay-output namespace=$KUBEPLAY_ENVIRONMENT"
+echo "kubeplay-output url=https://example.com/?a=b"
+echo "kubeplay-output tier=$KUBEPLAY_PARAM_LABEL_TIER"
+echo "kubeplay-output invalid key=value"
+echo "  kubeplay-output indented=value"
+`
+	e, err := NewExec("sh", "-c", script, "sh")
+	if err != nil {
+		t.Skipf("sh isn't available: %v", err)
+	}
+	req := newRequest("meetup", "g1")
+	req.Params = map[string]string{"label.tier": "beginner"}
+	outputs, err := e.Provision(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"action":    ActionProvision,
+		"namespace": "meetup-g1",
+		"url":       "https://example.com/?a=b",
+		"tier":      "beginner",
+	}
+	if len(outputs) != len(want) {
+		t.Errorf("expected the outputs %v, got %v", want, outputs)
+	}
+	for key, value := range want {
+		if outputs[key] != value {
+			t.Errorf("expected the output %s=%q, got %q", key, value, outputs[key])
+		}
+	}
+}
+
+func TestExecFailure(t *testing.T) {
+	e, err := NewExec("sh", "-c", `echo "kubectl: connection refused"; exit 3`, "sh")
+	if err != nil {
+		t.Skipf("sh isn't available: %v", err)
+	}
+	err = e.Deprovision(context.Background(), newRequest("meetup", "g1"))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), "deprovision failed") || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("expected the action and the output in the error, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package provisioner
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/kubeplay/gameserver/pkg/rest"
+)
+
+const (
+	inClusterTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	inClusterCAFile    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+
+	// labelPrefix identifies the namespaces managed by the game server
+	labelPrefix = "kubeplay.io/"
+	// paramLabelPrefix are the challenge params added as labels to the namespace, e.g.:
+	// label.tier: beginner -> kubeplay.io/tier=beginner
+	paramLabelPrefix = "label."
+)
+
+// KubernetesConfig holds the connection to the Kubernetes API
+type KubernetesConfig struct {
+	// Host is the URL of the API server, the in-cluster API is used when it's empty
+	Host      string
+	TokenFile string
+	CAFile    string
+	// NamespacePrefix is prepended to the namespaces, e.g.: kubeplay-
+	NamespacePrefix string
+}
+
+// Kubernetes creates a namespace for every game, the manifests of the challenge
+// bundle aren't applied, use the exec provisioner to deploy them.
+type Kubernetes struct {
+	client          rest.HTTPClient
+	host            *url.URL
+	token           string
+	namespacePrefix string
+}
+
+// NewKubernetes connects to the Kubernetes API, the client is replaced by a
+// fake client for testing. A nil client uses the CA of the config.
+func NewKubernetes(cfg KubernetesConfig, client rest.HTTPClient) (*Kubernetes, error) {
+	if cfg.Host == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("missing the Kubernetes API host and it isn't running in a cluster")
+		}
+		cfg.Host = "https://" + host + ":" + port
+		if cfg.TokenFile == "" {
+			cfg.TokenFile = inClusterTokenFile
+		}
+		if cfg.CAFile == "" {
+			cfg.CAFile = inClusterCAFile
+		}
+	}
+	host, err := url.Parse(cfg.Host)
+	if err != nil {
+		return nil, err
+	}
+	k := &Kubernetes{client: client, host: host, namespacePrefix: cfg.NamespacePrefix}
+	if cfg.TokenFile != "" {
+		data, err := ioutil.ReadFile(cfg.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		k.token = strings.TrimSpace(string(data))
+	}
+	if k.client == nil {
+		if k.client, err = rest.NewHTTPClient(&rest.TLSConfig{CAFile: cfg.CAFile}); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+type objectMeta struct {
+	Name        string            `json:"name"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type namespace struct {
+	APIVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	Metadata   objectMeta `json:"metadata"`
+}
+
+func (k *Kubernetes) request() *rest.Request {
+	// the base URL is mutated by the request
+	host := *k.host
+	req := rest.NewRequest(k.client, &host)
+	if k.token != "" {
+		req.Bearer(k.token)
+	}
+	return req
+}
+
+// Provision creates the namespace of the game, it succeeds if it already exists
+func (k *Kubernetes) Provision(ctx context.Context, req *Request) (map[string]string, error) {
+	ns := &namespace{
+		APIVersion: "v1",
+		Kind:       "Namespace",
+		Metadata: objectMeta{
+			Name: EnvironmentName(k.namespacePrefix, req),
+			Labels: map[string]string{
+				labelPrefix + "event":     req.Event,
+				labelPrefix + "game":      req.Game.Name,
+				labelPrefix + "challenge": req.Challenge.Name,
+			},
+			Annotations: map[string]string{
+				labelPrefix + "player":   req.Game.Player,
+				labelPrefix + "game-uid": req.Game.UID,
+			},
+		},
+	}
+	for key, value := range req.Params {
+		if strings.HasPrefix(key, paramLabelPrefix) {
+			ns.Metadata.Labels[labelPrefix+strings.TrimPrefix(key, paramLabelPrefix)] = value
+		}
+	}
+	resp := k.request().Post().
+		Context(ctx).
+		RequestURI("/api/v1/namespaces").
+		Body(ns).
+		Do()
+	if err := resp.Error(); err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode() {
+	case http.StatusCreated, http.StatusOK, http.StatusConflict:
+	default:
+		_, err := resp.Raw()
+		return nil, fmt.Errorf("failed creating namespace %q: %v", ns.Metadata.Name, err)
+	}
+	return map[string]string{"namespace": ns.Metadata.Name}, nil
+}
+
+// Deprovision deletes the namespace and everything inside of it
+func (k *Kubernetes) Deprovision(ctx context.Context, req *Request) error {
+	name := EnvironmentName(k.namespacePrefix, req)
+	resp := k.request().Delete().
+		Context(ctx).
+		RequestURI("/api/v1/namespaces", name).
+		Do()
+	if err := resp.Error(); err != nil {
+		return err
+	}
+	switch resp.StatusCode() {
+	case http.StatusOK, http.StatusAccepted, http.StatusNotFound:
+		return nil
+	}
+	_, err := resp.Raw()
+	return fmt.Errorf("failed deleting namespace %q: %v", name, err)
+}
@@ -0,0 +1,166 @@
+package provisioner
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kubeplay/gameserver/pkg/rest/fake"
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+func newRequest(event, game string) *Request {
+	gm := &types.Game{Player: "github|user"}
+	gm.Name = game
+	gm.UID = "uid-1"
+	ch := &types.Challenge{}
+	ch.Name = "foo"
+	return &Request{Event: event, Game: gm, Challenge: ch}
+}
+
+func newFakeKubernetes(t *testing.T, client *fake.HTTPClient) *Kubernetes {
+	k, err := NewKubernetes(KubernetesConfig{Host: "https://kubernetes.example.com", NamespacePrefix: "kubeplay-"}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestKubernetesProvision(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		statusCode int
+		wantErr    bool
+	}{
+		{name: "created", statusCode: http.StatusCreated},
+		{name: "already exists", statusCode: http.StatusConflict},
+		{name: "forbidden", statusCode: http.StatusForbidden, wantErr: true},
+		{name: "server error", statusCode: http.StatusInternalServerError, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewHTTPClient().
+				On("POST", "/api/v1/namespaces", fake.Response{StatusCode: tc.statusCode, Body: "{}"})
+			req := newRequest("meetup", "g1")
+			req.Params = map[string]string{"label.tier": "beginner", "replicas": "2"}
+			outputs, err := newFakeKubernetes(t, client).Provision(context.Background(), req)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for status %d", tc.statusCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if outputs["namespace"] != "kubeplay-meetup-g1" {
+				t.Errorf("expected the namespace kubeplay-meetup-g1 in the outputs, got %v", outputs)
+			}
+			if len(client.Requests) != 1 {
+				t.Fatalf("expected 1 request, got %d", len(client.Requests))
+			}
+			data, err := ioutil.ReadAll(client.Requests[0].Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ns := &namespace{}
+			if err := json.Unmarshal(data, ns); err != nil {
+				t.Fatal(err)
+			}
+			if ns.Kind != "Namespace" || ns.Metadata.Name != "kubeplay-meetup-g1" {
+				t.Errorf("unexpected namespace %+v", ns)
+			}
+			for key, value := range map[string]string{
+				"kubeplay.io/event":     "meetup",
+				"kubeplay.io/game":      "g1",
+				"kubeplay.io/challenge": "foo",
+				"kubeplay.io/tier":      "beginner",
+			} {
+				if ns.Metadata.Labels[key] != value {
+					t.Errorf("expected the label %s=%s, got %q", key, value, ns.Metadata.Labels[key])
+				}
+			}
+			if _, ok := ns.Metadata.Labels["kubeplay.io/replicas"]; ok {
+				t.Errorf("expected only the label params as labels")
+			}
+			if ns.Metadata.Annotations["kubeplay.io/player"] != "github|user" {
+				t.Errorf("expected the player annotation, got %v", ns.Metadata.Annotations)
+			}
+		})
+	}
+}
+
+func TestKubernetesDeprovision(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		statusCode int
+		wantErr    bool
+	}{
+		{name: "deleted", statusCode: http.StatusOK},
+		{name: "deletion accepted", statusCode: http.StatusAccepted},
+		{name: "not found", statusCode: http.StatusNotFound},
+		{name: "forbidden", statusCode: http.StatusForbidden, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewHTTPClient().
+				On("DELETE", "/api/v1/namespaces/kubeplay-meetup-g1", fake.Response{StatusCode: tc.statusCode, Body: "{}"})
+			err := newFakeKubernetes(t, client).Deprovision(context.Background(), newRequest("meetup", "g1"))
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("expected error=%v, got %v", tc.wantErr, err)
+			}
+			if len(client.Requests) != 1 || client.Requests[0].Method != "DELETE" {
+				t.Errorf("expected a single DELETE request, got %v", client.Requests)
+			}
+		})
+	}
+}
+
+func TestEnvironmentName(t *testing.T) {
+	long := strings.Repeat("a", 70)
+	for _, tc := range []struct {
+		name   string
+		prefix string
+		event  string
+		game   string
+		want   string
+	}{
+		{name: "prefix", prefix: "kubeplay-", event: "meetup", game: "g1", want: "kubeplay-meetup-g1"},
+		{name: "invalid characters", event: "Meetup_Oct", game: "g.1", want: "meetup-oct-g-1"},
+		{name: "trimmed dashes", event: "-meetup", game: "g1-", want: "meetup-g1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := EnvironmentName(tc.prefix, newRequest(tc.event, tc.game)); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	t.Run("truncated names", func(t *testing.T) {
+		a := EnvironmentName("", newRequest("meetup", long+"1"))
+		b := EnvironmentName("", newRequest("meetup", long+"2"))
+		for _, name := range []string{a, b} {
+			if len(name) > 63 {
+				t.Errorf("expected at most 63 characters, got %d: %q", len(name), name)
+			}
+			if !strings.HasPrefix(name, "meetup-aaa") {
+				t.Errorf("expected the name to keep its prefix, got %q", name)
+			}
+		}
+		if a == b {
+			t.Errorf("expected unique names for long games sharing a prefix, got %q", a)
+		}
+		if a != EnvironmentName("", newRequest("meetup", long+"1")) {
+			t.Errorf("expected the same name for the same game")
+		}
+	})
+
+	t.Run("truncated at a dash", func(t *testing.T) {
+		// the character 54 is a dash, it's trimmed before the hash
+		name := EnvironmentName("", newRequest(strings.Repeat("a", 53), long))
+		if strings.Contains(name, "--") {
+			t.Errorf("expected no double dashes, got %q", name)
+		}
+	})
+}
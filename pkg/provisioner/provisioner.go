@@ -0,0 +1,70 @@
+package provisioner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+// Request describes the game which the environment is provisioned for
+type Request struct {
+	Event     string
+	Game      *types.Game
+	Challenge *types.Challenge
+	// Params are the parameters of the challenge provisioner spec
+	Params map[string]string
+	// Manifests are the files in the provisioner directory of the challenge bundle
+	Manifests map[string][]byte
+}
+
+// Provisioner creates and destroys the environment of a player, both
+// operations must be idempotent since they could be retried.
+type Provisioner interface {
+	// Provision creates the environment, the outputs are recorded in the game status
+	Provision(ctx context.Context, req *Request) (map[string]string, error)
+	// Deprovision destroys the environment, it must succeed if it doesn't exist
+	Deprovision(ctx context.Context, req *Request) error
+}
+
+var (
+	mu           sync.RWMutex
+	provisioners = map[string]Provisioner{}
+)
+
+// Register makes a provisioner available to the challenges by its name
+func Register(name string, p Provisioner) {
+	mu.Lock()
+	defer mu.Unlock()
+	provisioners[name] = p
+}
+
+// Get returns a registered provisioner
+func Get(name string) (Provisioner, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := provisioners[name]
+	if !ok {
+		return nil, fmt.Errorf("provisioner %q isn't registered in the game server", name)
+	}
+	return p, nil
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// EnvironmentName returns a DNS-1123 compliant name for the environment of a game
+func EnvironmentName(prefix string, req *Request) string {
+	name := strings.ToLower(fmt.Sprintf("%s%s-%s", prefix, req.Event, req.Game.Name))
+	name = strings.Trim(invalidNameChars.ReplaceAllString(name, "-"), "-")
+	if len(name) > 63 {
+		// keep long names unique
+		sum := sha256.Sum256([]byte(name))
+		name = fmt.Sprintf("%s-%s", strings.TrimRight(name[:54], "-"), hex.EncodeToString(sum[:4]))
+	}
+	return name
+}
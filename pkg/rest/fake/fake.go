@@ -0,0 +1,72 @@
+// Package fake provides an HTTP client which answers requests without a
+// remote server, it's useful to exercise code built on top of the rest package.
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// Response is the answer of a fake request
+type Response struct {
+	StatusCode int
+	Body       interface{}
+}
+
+// HTTPClient implements rest.HTTPClient, the responses are matched by "<METHOD> <PATH>"
+type HTTPClient struct {
+	mu        sync.Mutex
+	responses map[string]Response
+
+	// Requests are all the requests performed, in order
+	Requests []*http.Request
+}
+
+// NewHTTPClient returns a client answering 404 for requests without a response
+func NewHTTPClient() *HTTPClient {
+	return &HTTPClient{responses: map[string]Response{}}
+}
+
+// On registers the response of a request, e.g.: On("POST", "/api/v1/namespaces", Response{StatusCode: 201})
+func (c *HTTPClient) On(method, path string, resp Response) *HTTPClient {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses[method+" "+path] = resp
+	return c
+}
+
+func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Requests = append(c.Requests, req)
+	resp, ok := c.responses[req.Method+" "+req.URL.Path]
+	if !ok {
+		resp = Response{
+			StatusCode: http.StatusNotFound,
+			Body:       fmt.Sprintf("%s %s not found", req.Method, req.URL.Path),
+		}
+	}
+	var body []byte
+	switch b := resp.Body.(type) {
+	case nil:
+	case string:
+		body = []byte(b)
+	case []byte:
+		body = b
+	default:
+		var err error
+		if body, err = json.Marshal(b); err != nil {
+			return nil, err
+		}
+	}
+	return &http.Response{
+		StatusCode: resp.StatusCode,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
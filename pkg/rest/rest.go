@@ -9,6 +9,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -72,6 +73,11 @@ func (r Result) IsSuccess() bool {
 	return r.statusCode == 200 || r.statusCode == 201 || r.statusCode == 204 || r.statusCode == 202
 }
 
+// Body returns the body of the response, including the errors of the server
+func (r Result) Body() []byte {
+	return r.body
+}
+
 func (r Result) ContentType() string {
 	return r.contentType
 }
@@ -195,10 +201,59 @@ func (r *Request) Body(bodyData interface{}) *Request {
 	return r
 }
 
+// RawBody sends the data as is with the content type, e.g.: archives
+func (r *Request) RawBody(data []byte, contentType string) *Request {
+	r.body = bytes.NewReader(data)
+	r.SetHeader("Content-Type", contentType)
+	return r
+}
+
 func (r *Request) Error() error {
 	return r.err
 }
 
+// DoList performs a list request following the continue tokens returned by the
+// server, the items of all the pages are returned in a single list.
+func (r *Request) DoList(limit int64) *Result {
+	var items []json.RawMessage
+	r.query.Set("limit", strconv.FormatInt(limit, 10))
+	for {
+		result := r.Do()
+		if result.err != nil || !result.IsSuccess() {
+			return result
+		}
+		var page struct {
+			Metadata struct {
+				Continue string `json:"continue"`
+			} `json:"metadata"`
+			Items []json.RawMessage `json:"items"`
+		}
+		if err := json.Unmarshal(result.body, &page); err != nil {
+			result.err = fmt.Errorf("failed decoding page [%v]", err)
+			return result
+		}
+		items = append(items, page.Items...)
+		if page.Metadata.Continue != "" {
+			r.query.Set("continue", page.Metadata.Continue)
+			continue
+		}
+		// the last page is returned with the items of all of them
+		var list map[string]json.RawMessage
+		if err := json.Unmarshal(result.body, &list); err != nil {
+			result.err = fmt.Errorf("failed decoding page [%v]", err)
+			return result
+		}
+		data, err := json.Marshal(items)
+		if err != nil {
+			result.err = err
+			return result
+		}
+		list["items"] = data
+		result.body, result.err = json.Marshal(list)
+		return result
+	}
+}
+
 func (r *Request) Do() *Result {
 	result := &Result{}
 	if r.err != nil {
@@ -218,6 +273,9 @@ func (r *Request) Do() *Result {
 		r.err = fmt.Errorf("failed creating request [%v]", err)
 		return result
 	}
+	if r.ctx != nil {
+		request = request.WithContext(r.ctx)
+	}
 	q := request.URL.Query()
 	q = r.query
 	request.URL.RawQuery = q.Encode()
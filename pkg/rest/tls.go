@@ -0,0 +1,56 @@
+package rest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// TLSConfig holds the options to verify the server and authenticate the client
+type TLSConfig struct {
+	// CAFile is a bundle of PEM encoded certificates to verify the server,
+	// the system roots are used when it's empty
+	CAFile string
+	// InsecureSkipVerify disables the verification of the server certificate
+	InsecureSkipVerify bool
+	// CertFile and KeyFile are the client certificate pair
+	CertFile string
+	KeyFile  string
+}
+
+// IsEmpty returns true when no option is set
+func (t *TLSConfig) IsEmpty() bool {
+	return t == nil || *t == TLSConfig{}
+}
+
+// NewHTTPClient returns a client configured with the TLS options
+func NewHTTPClient(t *TLSConfig) (*http.Client, error) {
+	if t.IsEmpty() {
+		return http.DefaultClient, nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: t.InsecureSkipVerify}
+	if t.CAFile != "" {
+		data, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %q", t.CAFile)
+		}
+	}
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed loading client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
+	}
+	return &http.Client{Transport: transport}, nil
+}
@@ -0,0 +1,311 @@
+package selector
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+const (
+	// LabelSelectorParam and FieldSelectorParam are the query params of the list endpoints
+	LabelSelectorParam = "labelSelector"
+	FieldSelectorParam = "fieldSelector"
+)
+
+type operator string
+
+const (
+	opEquals       operator = "="
+	opNotEquals    operator = "!="
+	opIn           operator = "in"
+	opNotIn        operator = "notin"
+	opExists       operator = "exists"
+	opDoesNotExist operator = "!"
+)
+
+var (
+	// label keys have an optional DNS prefix, e.g.: kubeplay.io/track
+	labelNameRe   = regexp.MustCompile(`^([a-zA-Z0-9]([-a-zA-Z0-9_.]{0,61}[a-zA-Z0-9])?)?$`)
+	labelPrefixRe = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]{0,251}[a-z0-9])?$`)
+	fieldPathRe   = regexp.MustCompile(`^[a-zA-Z0-9_-]+(\.[a-zA-Z0-9_./-]+)*$`)
+	setRe         = regexp.MustCompile(`^(\S+)\s+(in|notin)\s+\((.*)\)$`)
+)
+
+type requirement struct {
+	key    string
+	op     operator
+	values []string
+}
+
+// Selector filters the objects of a list by their labels and fields, the
+// requirements are ANDed.
+//
+// Labels: env=prod, env!=prod, env in (prod,staging), env notin (dev), env and !env
+// Fields: status.phase=Running, player!=github|user (the JSON path of the field)
+type Selector struct {
+	labels []requirement
+	fields []requirement
+}
+
+// Parse decodes the label and field selectors, empty selectors match everything
+func Parse(labelSelector, fieldSelector string) (*Selector, error) {
+	s := &Selector{}
+	for _, term := range splitTerms(labelSelector) {
+		req, err := parseLabelRequirement(term)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector %q: %v", labelSelector, err)
+		}
+		s.labels = append(s.labels, req)
+	}
+	for _, term := range splitTerms(fieldSelector) {
+		req, err := parseFieldRequirement(term)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field selector %q: %v", fieldSelector, err)
+		}
+		s.fields = append(s.fields, req)
+	}
+	return s, nil
+}
+
+// Empty returns true if the selector matches every object
+func (s *Selector) Empty() bool {
+	return len(s.labels) == 0 && len(s.fields) == 0
+}
+
+// Matches returns true if the object meets all the requirements, the fields are
+// read from the JSON representation of the object.
+func (s *Selector) Matches(obj types.Object) bool {
+	if s.Empty() {
+		return true
+	}
+	var labels map[string]string
+	if meta := obj.GetObjectMeta(); meta != nil {
+		labels = meta.Labels
+	}
+	for _, req := range s.labels {
+		value, ok := labels[req.key]
+		if !req.matches(value, ok) {
+			return false
+		}
+	}
+	if len(s.fields) == 0 {
+		return true
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return false
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return false
+	}
+	for _, req := range s.fields {
+		value, ok := fieldValue(fields, req.key)
+		if !req.matches(value, ok) {
+			return false
+		}
+	}
+	return true
+}
+
+// ExactMatch returns the value of a field required with an equality, e.g.:
+// player for player=github|user. Lists could scan an index by the value.
+func (s *Selector) ExactMatch(field string) (string, bool) {
+	for _, req := range s.fields {
+		if req.key == field && req.op == opEquals {
+			return req.values[0], true
+		}
+	}
+	return "", false
+}
+
+// SelectsField returns true if a requirement of the selector reads the field
+func (s *Selector) SelectsField(field string) bool {
+	for _, req := range s.fields {
+		if req.key == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (r requirement) matches(value string, exists bool) bool {
+	switch r.op {
+	case opEquals:
+		return exists && value == r.values[0]
+	case opNotEquals:
+		return !exists || value != r.values[0]
+	case opIn:
+		return exists && contains(r.values, value)
+	case opNotIn:
+		return !exists || !contains(r.values, value)
+	case opExists:
+		return exists
+	case opDoesNotExist:
+		return !exists
+	}
+	return false
+}
+
+// ValidateLabels verifies the keys and values of the labels of an object
+func ValidateLabels(labels map[string]string) error {
+	for key, value := range labels {
+		if err := validLabelKey(key); err != nil {
+			return err
+		}
+		if err := validLabelValue(value); err != nil {
+			return fmt.Errorf("invalid value of the label %q: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// splitTerms splits the requirements by commas, except the ones in the sets
+func splitTerms(selector string) []string {
+	var terms []string
+	depth, start := 0, 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	terms = append(terms, selector[start:])
+	var result []string
+	for _, term := range terms {
+		if term = strings.TrimSpace(term); term != "" {
+			result = append(result, term)
+		}
+	}
+	return result
+}
+
+func parseLabelRequirement(term string) (requirement, error) {
+	var req requirement
+	switch {
+	case strings.HasPrefix(term, "!"):
+		req = requirement{key: strings.TrimSpace(term[1:]), op: opDoesNotExist}
+	case setRe.MatchString(term):
+		m := setRe.FindStringSubmatch(term)
+		req = requirement{key: m[1], op: operator(m[2])}
+		for _, value := range strings.Split(m[3], ",") {
+			value = strings.TrimSpace(value)
+			if err := validLabelValue(value); err != nil {
+				return req, err
+			}
+			req.values = append(req.values, value)
+		}
+	default:
+		key, op, value, ok := splitOperator(term)
+		if !ok {
+			req = requirement{key: term, op: opExists}
+			break
+		}
+		if err := validLabelValue(value); err != nil {
+			return req, err
+		}
+		req = requirement{key: key, op: op, values: []string{value}}
+	}
+	return req, validLabelKey(req.key)
+}
+
+func parseFieldRequirement(term string) (requirement, error) {
+	key, op, value, ok := splitOperator(term)
+	if !ok {
+		return requirement{}, fmt.Errorf("%q: expected <field>=<value> or <field>!=<value>", term)
+	}
+	if !fieldPathRe.MatchString(key) {
+		return requirement{}, fmt.Errorf("invalid field %q", key)
+	}
+	return requirement{key: key, op: op, values: []string{value}}, nil
+}
+
+// splitOperator splits terms like key=value, key==value and key!=value
+func splitOperator(term string) (string, operator, string, bool) {
+	if i := strings.Index(term, "!="); i > 0 {
+		return strings.TrimSpace(term[:i]), opNotEquals, strings.TrimSpace(term[i+2:]), true
+	}
+	if i := strings.Index(term, "="); i > 0 {
+		value := strings.TrimPrefix(term[i+1:], "=")
+		return strings.TrimSpace(term[:i]), opEquals, strings.TrimSpace(value), true
+	}
+	return "", "", "", false
+}
+
+// fieldValue returns a scalar field by its path, e.g.: status.phase or metadata.labels.track
+func fieldValue(fields map[string]interface{}, path string) (string, bool) {
+	var value interface{} = fields
+	for _, key := range splitPath(path) {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if value, ok = m[key]; !ok {
+			return "", false
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case nil:
+		return "", true
+	}
+	// lists and objects couldn't be compared
+	return "", false
+}
+
+// splitPath splits a field path by dots, the keys of the labels and the
+// annotations may contain dots, e.g.: metadata.labels.kubeplay.io/track
+func splitPath(path string) []string {
+	for _, prefix := range []string{"metadata.labels.", "metadata.annotations."} {
+		if strings.HasPrefix(path, prefix) {
+			return append(strings.Split(strings.TrimSuffix(prefix, "."), "."), strings.TrimPrefix(path, prefix))
+		}
+	}
+	return strings.Split(path, ".")
+}
+
+func validLabelKey(key string) error {
+	name := key
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		if !labelPrefixRe.MatchString(key[:i]) {
+			return fmt.Errorf("invalid prefix of the label key %q", key)
+		}
+		name = key[i+1:]
+	}
+	if name == "" || !labelNameRe.MatchString(name) {
+		return fmt.Errorf("invalid label key %q, it must have at most 63 alphanumeric characters, '-', '_' or '.'", key)
+	}
+	return nil
+}
+
+func validLabelValue(value string) error {
+	if !labelNameRe.MatchString(value) {
+		return fmt.Errorf("invalid label value %q, it must be empty or have at most 63 alphanumeric characters, '-', '_' or '.'", value)
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package selector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+func TestSplitTerms(t *testing.T) {
+	for _, tc := range []struct {
+		selector string
+		want     []string
+	}{
+		{selector: "", want: nil},
+		{selector: "env=prod", want: []string{"env=prod"}},
+		{selector: "env=prod, track!=k8s", want: []string{"env=prod", "track!=k8s"}},
+		{selector: "env in (prod,staging),track", want: []string{"env in (prod,staging)", "track"}},
+		{selector: "env notin (dev, qa), !track", want: []string{"env notin (dev, qa)", "!track"}},
+		{selector: " , env=prod,, ", want: []string{"env=prod"}},
+	} {
+		if got := splitTerms(tc.selector); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: expected %q, got %q", tc.selector, tc.want, got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		labels     string
+		fields     string
+		wantLabels []requirement
+		wantFields []requirement
+		wantErr    string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:   "equality",
+			labels: "env=prod,track==k8s,level!=hard",
+			wantLabels: []requirement{
+				{key: "env", op: opEquals, values: []string{"prod"}},
+				{key: "track", op: opEquals, values: []string{"k8s"}},
+				{key: "level", op: opNotEquals, values: []string{"hard"}},
+			},
+		},
+		{
+			name:   "sets",
+			labels: "env in (prod, staging),level notin (hard)",
+			wantLabels: []requirement{
+				{key: "env", op: opIn, values: []string{"prod", "staging"}},
+				{key: "level", op: opNotIn, values: []string{"hard"}},
+			},
+		},
+		{
+			name:   "existence",
+			labels: "kubeplay.io/track,!env",
+			wantLabels: []requirement{
+				{key: "kubeplay.io/track", op: opExists},
+				{key: "env", op: opDoesNotExist},
+			},
+		},
+		{
+			name:   "fields",
+			fields: "status.phase=Running,player!=github|user",
+			wantFields: []requirement{
+				{key: "status.phase", op: opEquals, values: []string{"Running"}},
+				{key: "player", op: opNotEquals, values: []string{"github|user"}},
+			},
+		},
+		{
+			name:    "invalid label key",
+			labels:  "env$=prod",
+			wantErr: "invalid label selector",
+		},
+		{
+			name:    "invalid label value",
+			labels:  "env=prod staging",
+			wantErr: "invalid label value",
+		},
+		{
+			name:    "invalid value in a set",
+			labels:  "env in (prod,$)",
+			wantErr: "invalid label value",
+		},
+		{
+			name:    "invalid prefix",
+			labels:  "-kubeplay.io/track",
+			wantErr: "invalid prefix",
+		},
+		{
+			name:    "field without operator",
+			fields:  "status.phase",
+			wantErr: "expected <field>=<value>",
+		},
+		{
+			name:    "sets of fields aren't supported",
+			fields:  "status.phase in (Running)",
+			wantErr: "invalid field selector",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := Parse(tc.labels, tc.fields)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(s.labels, tc.wantLabels) {
+				t.Errorf("expected the labels %+v, got %+v", tc.wantLabels, s.labels)
+			}
+			if !reflect.DeepEqual(s.fields, tc.wantFields) {
+				t.Errorf("expected the fields %+v, got %+v", tc.wantFields, s.fields)
+			}
+			if s.Empty() != (tc.wantLabels == nil && tc.wantFields == nil) {
+				t.Errorf("unexpected empty selector: %v", s.Empty())
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	gm := &types.Game{
+		TypeMeta: types.TypeMeta{Kind: types.GameKind},
+		Metadata: types.Metadata{
+			Name:   "g1",
+			Labels: map[string]string{"env": "prod", "kubeplay.io/track": "k8s"},
+		},
+		Challenge: "foo",
+		Player:    "github|user",
+		Status:    types.GameStatus{Phase: types.GameRunning, RegisteredKeys: 2},
+	}
+	for _, tc := range []struct {
+		labels string
+		fields string
+		want   bool
+	}{
+		{want: true},
+		{labels: "env=prod", want: true},
+		{labels: "env=dev", want: false},
+		{labels: "env!=dev", want: true},
+		{labels: "missing!=dev", want: true},
+		{labels: "env in (dev,prod)", want: true},
+		{labels: "env in (dev,qa)", want: false},
+		{labels: "missing in (prod)", want: false},
+		{labels: "env notin (dev,qa)", want: true},
+		{labels: "env notin (prod)", want: false},
+		{labels: "missing notin (prod)", want: true},
+		{labels: "kubeplay.io/track", want: true},
+		{labels: "missing", want: false},
+		{labels: "!missing", want: true},
+		{labels: "!env", want: false},
+		{labels: "env=prod,kubeplay.io/track=k8s", want: true},
+		{labels: "env=prod,kubeplay.io/track=other", want: false},
+		{fields: "status.phase=Running", want: true},
+		{fields: "status.phase!=Running", want: false},
+		{fields: "player=github|user", want: true},
+		{fields: "status.registeredKeys=2", want: true},
+		{fields: "metadata.labels.kubeplay.io/track=k8s", want: true},
+		{fields: "status.missing=x", want: false},
+		{fields: "status.missing!=x", want: true},
+		// objects couldn't be compared
+		{fields: "status=x", want: false},
+		{labels: "env=prod", fields: "challenge=foo", want: true},
+		{labels: "env=prod", fields: "challenge=bar", want: false},
+	} {
+		s, err := Parse(tc.labels, tc.fields)
+		if err != nil {
+			t.Fatalf("%q %q: %v", tc.labels, tc.fields, err)
+		}
+		if got := s.Matches(gm); got != tc.want {
+			t.Errorf("labels %q fields %q: expected %v, got %v", tc.labels, tc.fields, tc.want, got)
+		}
+	}
+}
+
+func TestExactMatchAndSelectsField(t *testing.T) {
+	s, err := Parse("", "player=github|user,status.phase!=Running")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := s.ExactMatch("player"); !ok || value != "github|user" {
+		t.Errorf("expected an exact match of the player, got %q %v", value, ok)
+	}
+	if _, ok := s.ExactMatch("status.phase"); ok {
+		t.Error("expected no exact match of an inequality")
+	}
+	if !s.SelectsField("status.phase") || s.SelectsField("challenge") {
+		t.Error("expected only the fields of the requirements to be selected")
+	}
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// backupTimeout limits how long a backup waits for the writes in progress
+const backupTimeout = 30 * time.Second
+
+// Backup writes a consistent copy of the database while the server is running,
+// the snapshot is read in a single transaction and the writes wait for it.
+func Backup(dbfile string, w io.Writer) (int64, error) {
+	if _, err := os.Stat(dbfile); err != nil {
+		return 0, err
+	}
+	db, err := bolt.Open(dbfile, 0600, &bolt.Options{ReadOnly: true, Timeout: backupTimeout})
+	if err != nil {
+		return 0, fmt.Errorf("failed opening database %q: %v", dbfile, err)
+	}
+	defer db.Close()
+	var size int64
+	err = db.View(func(tx *bolt.Tx) error {
+		var err error
+		size, err = tx.WriteTo(w)
+		return err
+	})
+	return size, err
+}
+
+// Restore replaces the database with a backup, the backup must have the bucket
+// of the objects. The file is replaced at once, the server should be stopped
+// or the requests in flight are lost.
+func Restore(dbfile, pathPrefix, backup string) error {
+	// opening a missing file read-only creates an empty one
+	if _, err := os.Stat(backup); err != nil {
+		return err
+	}
+	src, err := bolt.Open(backup, 0600, &bolt.Options{ReadOnly: true, Timeout: backupTimeout})
+	if err != nil {
+		return fmt.Errorf("failed opening backup %q: %v", backup, err)
+	}
+	defer src.Close()
+	tmp, err := ioutil.TempFile(filepath.Dir(dbfile), filepath.Base(dbfile)+".restore-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	err = src.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(pathPrefix)) == nil {
+			return fmt.Errorf("bucket %q not found in the backup", pathPrefix)
+		}
+		_, err := tx.WriteTo(tmp)
+		return err
+	})
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dbfile)
+}
@@ -0,0 +1,185 @@
+package store
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	GamePlayerIndex    = "player"
+	GameChallengeIndex = "challenge"
+	GamePhaseIndex     = "phase"
+)
+
+// indexSeparator splits the value and the object key of the index entries,
+// e.g.: github|user\x00/event/meetup/game/foo
+const indexSeparator = "\x00"
+
+// Index is a secondary index of a kind, the entries are stored in their own
+// bucket and maintained in the same transaction as the objects.
+type Index struct {
+	Name string
+	// Field is the field selector served by the index, e.g.: status.phase
+	Field string
+	// Value returns the value of an object in the index, empty values aren't indexed
+	Value func(obj types.Object) string
+}
+
+// Indexes are the secondary indexes of each kind
+var Indexes = map[string][]Index{
+	types.GameKind: {
+		{
+			Name:  GamePlayerIndex,
+			Field: "player",
+			Value: func(obj types.Object) string { return obj.(*types.Game).Player },
+		},
+		{
+			Name:  GameChallengeIndex,
+			Field: "challenge",
+			Value: func(obj types.Object) string { return obj.(*types.Game).Challenge },
+		},
+		{
+			Name:  GamePhaseIndex,
+			Field: "status.phase",
+			Value: func(obj types.Object) string { return string(obj.(*types.Game).Status.Phase) },
+		},
+	},
+}
+
+// lookupIndex returns the definition of an index of a kind
+func lookupIndex(kind, name string) *Index {
+	for _, idx := range Indexes[kind] {
+		if idx.Name == name {
+			return &idx
+		}
+	}
+	return nil
+}
+
+func (s *Store) indexMetaBucket() []byte {
+	return []byte(path.Join(s.pathPrefix, "index"))
+}
+
+func (s *Store) indexBucket(kind, name string) []byte {
+	return []byte(path.Join(s.pathPrefix, "index", strings.ToLower(kind), name))
+}
+
+func indexKey(value string, objKey []byte) []byte {
+	return append([]byte(value+indexSeparator), objKey...)
+}
+
+// putIndexes adds the entries of an object stored in the key
+func (s *Store) putIndexes(tx *bolt.Tx, objKey []byte, obj types.Object) error {
+	kind := obj.GetObjectKind()
+	for _, idx := range Indexes[kind] {
+		value := idx.Value(obj)
+		if value == "" {
+			continue
+		}
+		b, err := tx.CreateBucketIfNotExists(s.indexBucket(kind, idx.Name))
+		if err != nil {
+			return err
+		}
+		if err := b.Put(indexKey(value, objKey), []byte{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteIndexes removes the entries of the object stored in the key, data is
+// the stored object which is decoded by the kind it was saved with.
+func (s *Store) deleteIndexes(tx *bolt.Tx, objKey, data []byte) error {
+	obj := decodeIndexed(data)
+	if obj == nil {
+		return nil
+	}
+	kind := obj.GetObjectKind()
+	for _, idx := range Indexes[kind] {
+		b := tx.Bucket(s.indexBucket(kind, idx.Name))
+		if b == nil {
+			continue
+		}
+		if err := b.Delete(indexKey(idx.Value(obj), objKey)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeIndexed decodes the objects of the kinds with indexes, other values
+// (e.g.: blobs) return nil.
+func decodeIndexed(data []byte) types.Object {
+	meta := &types.TypeMeta{}
+	if err := json.Unmarshal(data, meta); err != nil || len(Indexes[meta.Kind]) == 0 {
+		return nil
+	}
+	data, _, err := types.ConvertToStorageVersion(meta.Kind, data)
+	if err != nil {
+		return nil
+	}
+	obj, err := types.Decode(meta, data)
+	if err != nil {
+		return nil
+	}
+	return obj
+}
+
+// indexDefinitions identifies the indexes declared, e.g.: game/challenge,game/phase
+func indexDefinitions() string {
+	var names []string
+	for kind, indexes := range Indexes {
+		for _, idx := range indexes {
+			names = append(names, path.Join(strings.ToLower(kind), idx.Name))
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// buildIndexes rebuilds every index when the declared ones changed, e.g.: the
+// first time the server starts with a database created without indexes.
+func (s *Store) buildIndexes(tx *bolt.Tx) error {
+	meta, err := tx.CreateBucketIfNotExists(s.indexMetaBucket())
+	if err != nil {
+		return err
+	}
+	definitions := indexDefinitions()
+	if string(meta.Get([]byte("definitions"))) == definitions {
+		return nil
+	}
+	prefix := string(s.indexMetaBucket()) + "/"
+	var stale [][]byte
+	err = tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+		if strings.HasPrefix(string(name), prefix) {
+			stale = append(stale, append([]byte{}, name...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range stale {
+		if err := tx.DeleteBucket(name); err != nil {
+			return err
+		}
+	}
+	b := tx.Bucket([]byte(s.pathPrefix))
+	if b != nil {
+		err := b.ForEach(func(k, v []byte) error {
+			if obj := decodeIndexed(v); obj != nil {
+				return s.putIndexes(tx, k, obj)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return meta.Put([]byte("definitions"), []byte(definitions))
+}
@@ -0,0 +1,201 @@
+package store
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+	bolt "go.etcd.io/bbolt"
+)
+
+func gameKeys(t *testing.T, file, event, index, value string) []string {
+	s := New(file, testBucket).Kind(types.GameKind)
+	if event == "" {
+		s.Resources(strings.ToLower(types.EventKind))
+	} else {
+		s.Resources(strings.ToLower(types.EventKind), event, strings.ToLower(types.GameKind))
+	}
+	keys, err := s.ListKeys(gameKeyRe, ListOptions{Index: index, IndexValue: value})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestIndexes(t *testing.T) {
+	file, cleanup := newTestDB(t)
+	defer cleanup()
+	saveGame(t, file, "meetup", "g1", "github|a", types.GamePending)
+	saveGame(t, file, "meetup", "g2", "github|b", types.GamePending)
+	saveGame(t, file, "meetup-2", "g1", "github|a", types.GameRunning)
+	saveGame(t, file, "other", "g1", "github|a", types.GamePending)
+
+	for _, tc := range []struct {
+		name  string
+		event string
+		index string
+		value string
+		want  []string
+	}{
+		{
+			name:  "every event",
+			index: GamePlayerIndex,
+			value: "github|a",
+			want:  []string{"/event/meetup-2/game/g1", "/event/meetup/game/g1", "/event/other/game/g1"},
+		},
+		{
+			name:  "a single event",
+			event: "meetup",
+			index: GamePlayerIndex,
+			value: "github|a",
+			want:  []string{"/event/meetup/game/g1"},
+		},
+		{
+			name:  "an event sharing the first characters of the name",
+			event: "meetup-2",
+			index: GamePlayerIndex,
+			value: "github|a",
+			want:  []string{"/event/meetup-2/game/g1"},
+		},
+		{
+			name:  "values sharing a prefix",
+			index: GamePlayerIndex,
+			value: "github|",
+			want:  nil,
+		},
+		{
+			name:  "phase",
+			index: GamePhaseIndex,
+			value: string(types.GameRunning),
+			want:  []string{"/event/meetup-2/game/g1"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := gameKeys(t, file, tc.event, tc.index, tc.value); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+
+	t.Run("unknown index", func(t *testing.T) {
+		_, err := games(file, "meetup").ListKeys(gameKeyRe, ListOptions{Index: "unknown", IndexValue: "x"})
+		if err == nil {
+			t.Error("expected an error for an unknown index")
+		}
+	})
+}
+
+func TestIndexesUpdateAndDelete(t *testing.T) {
+	file, cleanup := newTestDB(t)
+	defer cleanup()
+	saveGame(t, file, "meetup", "g1", "github|a", types.GamePending)
+
+	s := games(file, "meetup")
+	obj, err := s.Get("g1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := obj.(*types.Game)
+	updated := *old
+	updated.Status.Phase = types.GameRunning
+	if _, err := s.Update(old, &updated); err != nil {
+		t.Fatal(err)
+	}
+	if got := gameKeys(t, file, "", GamePhaseIndex, string(types.GamePending)); len(got) != 0 {
+		t.Errorf("expected the old value to be removed from the index, got %v", got)
+	}
+	if got := gameKeys(t, file, "", GamePhaseIndex, string(types.GameRunning)); len(got) != 1 {
+		t.Errorf("expected the new value in the index, got %v", got)
+	}
+
+	// deleting the event removes the games and their entries
+	err = New(file, testBucket).Kind(types.EventKind).Resources(strings.ToLower(types.EventKind)).Delete("meetup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, value := range map[string]string{
+		GamePlayerIndex:    "github|a",
+		GameChallengeIndex: "foo",
+		GamePhaseIndex:     string(types.GameRunning),
+	} {
+		if got := gameKeys(t, file, "", index, value); len(got) != 0 {
+			t.Errorf("expected no entries in the index %s after deleting the games, got %v", index, got)
+		}
+	}
+}
+
+func TestBuildIndexes(t *testing.T) {
+	file, cleanup := newTestDB(t)
+	defer cleanup()
+	saveGame(t, file, "meetup", "g1", "github|a", types.GamePending)
+	saveGame(t, file, "meetup", "g2", "github|b", types.GamePending)
+
+	defer func(indexes []Index) { Indexes[types.GameKind] = indexes }(Indexes[types.GameKind])
+	// declaring a new index rebuilds every index with the stored objects
+	Indexes[types.GameKind] = append(append([]Index{}, Indexes[types.GameKind]...), Index{
+		Name:  "name",
+		Value: func(obj types.Object) string { return obj.GetObjectMeta().Name },
+	})
+	if err := New(file, testBucket).Init(); err != nil {
+		t.Fatal(err)
+	}
+	if got := gameKeys(t, file, "", "name", "g2"); !reflect.DeepEqual(got, []string{"/event/meetup/game/g2"}) {
+		t.Errorf("expected the stored games in the new index, got %v", got)
+	}
+	if got := gameKeys(t, file, "", GamePlayerIndex, "github|a"); len(got) != 1 {
+		t.Errorf("expected the existing indexes to be rebuilt, got %v", got)
+	}
+
+	// removing the index drops its bucket
+	Indexes[types.GameKind] = Indexes[types.GameKind][:len(Indexes[types.GameKind])-1]
+	s := New(file, testBucket)
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	db, err := s.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(s.indexBucket(types.GameKind, "name")) != nil {
+			t.Error("expected the bucket of the removed index to be dropped")
+		}
+		if tx.Bucket(s.indexBucket(types.GameKind, GamePlayerIndex)) == nil {
+			t.Error("expected the bucket of the player index")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildIndexesUnchanged(t *testing.T) {
+	file, cleanup := newTestDB(t)
+	defer cleanup()
+	saveGame(t, file, "meetup", "g1", "github|a", types.GamePending)
+
+	// entries aren't rebuilt when the definitions didn't change, an entry
+	// removed out of band stays removed
+	s := New(file, testBucket)
+	db, err := s.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.indexBucket(types.GameKind, GamePlayerIndex)).
+			Delete(indexKey("github|a", []byte("/event/meetup/game/g1")))
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := New(file, testBucket).Init(); err != nil {
+		t.Fatal(err)
+	}
+	if got := gameKeys(t, file, "", GamePlayerIndex, "github|a"); len(got) != 0 {
+		t.Errorf("expected the indexes to be kept, got %v", got)
+	}
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+	bolt "go.etcd.io/bbolt"
+)
+
+// errDryRun rolls back the transaction of a migration which only counts the objects
+var errDryRun = errors.New("dry run")
+
+// MigrationResult counts the objects of a migration, the converted ones by
+// their kind, e.g.: Game
+type MigrationResult struct {
+	Objects   int
+	Converted map[string]int
+}
+
+// Migrate rewrites the objects stored with a previous version with the
+// StorageVersion in a single transaction, the indexes are updated with them.
+// Other values of the bucket (e.g.: blobs) are kept.
+func Migrate(dbfile, pathPrefix string, dryRun bool) (*MigrationResult, error) {
+	s := New(dbfile, pathPrefix)
+	db, err := s.DB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	result := &MigrationResult{Converted: map[string]int{}}
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(pathPrefix))
+		if b == nil {
+			return fmt.Errorf("bucket %q doesn't exists", pathPrefix)
+		}
+		type converted struct {
+			key, old, new []byte
+			kind          string
+		}
+		var objs []converted
+		err := b.ForEach(func(k, v []byte) error {
+			meta := &types.TypeMeta{}
+			if err := json.Unmarshal(v, meta); err != nil || !registered(meta.Kind) {
+				return nil
+			}
+			result.Objects++
+			data, changed, err := types.ConvertToStorageVersion(meta.Kind, v)
+			if err != nil {
+				return fmt.Errorf("%s: %v", string(k), err)
+			}
+			if changed {
+				objs = append(objs, converted{key: append([]byte{}, k...), old: append([]byte{}, v...), new: data, kind: meta.Kind})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, o := range objs {
+			result.Converted[o.kind]++
+			if dryRun {
+				continue
+			}
+			if err := s.deleteIndexes(tx, o.key, o.old); err != nil {
+				return err
+			}
+			if err := b.Put(o.key, o.new); err != nil {
+				return err
+			}
+			if obj := decodeIndexed(o.new); obj != nil {
+				if err := s.putIndexes(tx, o.key, obj); err != nil {
+					return err
+				}
+			}
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err == errDryRun {
+		err = nil
+	}
+	return result, err
+}
+
+func registered(kind string) bool {
+	for _, obj := range types.RegisteredTypes {
+		if obj.GetObjectKind() == kind {
+			return true
+		}
+	}
+	return false
+}
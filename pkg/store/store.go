@@ -2,6 +2,7 @@ package store
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"path"
@@ -10,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/kubeplay/gameserver/pkg/metrics"
 	"github.com/kubeplay/gameserver/pkg/types"
 	bolt "go.etcd.io/bbolt"
 )
@@ -34,6 +36,22 @@ func New(dbfile, pathPrefix string) *Store {
 	return store
 }
 
+// Init creates the bucket of the store if it doesn't exist and builds the
+// secondary indexes when they changed
+func (s *Store) Init() error {
+	db, err := s.DB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(s.pathPrefix)); err != nil {
+			return err
+		}
+		return s.buildIndexes(tx)
+	})
+}
+
 func (s *Store) Kind(kind string) *Store {
 	// s.kind = strings.ToLower(kind)
 	for _, obj := range types.RegisteredTypes {
@@ -53,6 +71,7 @@ func (s *Store) Resources(keys ...string) *Store {
 }
 
 func (s *Store) SaveObject(obj types.Object) (types.Object, error) {
+	defer metrics.ObserveStoreOperation("save", time.Now())
 	db, err := s.DB()
 	if err != nil {
 		return nil, err
@@ -62,6 +81,7 @@ func (s *Store) SaveObject(obj types.Object) (types.Object, error) {
 	meta := obj.GetObjectMeta()
 	meta.UID = NewUUID()
 	meta.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	obj.SetAPIVersion(types.StorageVersion)
 	err = db.Update(func(tx *bolt.Tx) error {
 		data, err := json.Marshal(obj)
 		if err != nil {
@@ -75,12 +95,50 @@ func (s *Store) SaveObject(obj types.Object) (types.Object, error) {
 		if o := b.Get(objectKey); o != nil {
 			return fmt.Errorf("object %q already exists", string(objectKey))
 		}
-		return b.Put(objectKey, data)
+		if err := b.Put(objectKey, data); err != nil {
+			return err
+		}
+		return s.putIndexes(tx, objectKey, obj)
 	})
 	return obj, err
 }
 
+// ImportObject stores an object as it is, keeping its uid and creation time
+// (e.g.: the objects of an export). Existing objects aren't replaced, it
+// returns false for them.
+func (s *Store) ImportObject(obj types.Object) (bool, error) {
+	defer metrics.ObserveStoreOperation("save", time.Now())
+	db, err := s.DB()
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+	created := false
+	obj.SetAPIVersion(types.StorageVersion)
+	err = db.Update(func(tx *bolt.Tx) error {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		b, err := tx.CreateBucketIfNotExists([]byte(s.pathPrefix))
+		if err != nil {
+			return err
+		}
+		objectKey := []byte(s.GetResourcePath())
+		if o := b.Get(objectKey); o != nil {
+			return nil
+		}
+		if err := b.Put(objectKey, data); err != nil {
+			return err
+		}
+		created = true
+		return s.putIndexes(tx, objectKey, obj)
+	})
+	return created, err
+}
+
 func (s *Store) Update(old, new types.Object) (types.Object, error) {
+	defer metrics.ObserveStoreOperation("update", time.Now())
 	db, err := s.DB()
 	if err != nil {
 		return nil, err
@@ -95,6 +153,7 @@ func (s *Store) Update(old, new types.Object) (types.Object, error) {
 	if !reflect.DeepEqual(newMeta.Annotations, oldMeta.Annotations) {
 		newMeta.Annotations = oldMeta.Annotations
 	}
+	new.SetAPIVersion(types.StorageVersion)
 	return new, db.Update(func(tx *bolt.Tx) error {
 		data, err := json.Marshal(new)
 		if err != nil {
@@ -105,11 +164,79 @@ func (s *Store) Update(old, new types.Object) (types.Object, error) {
 			return fmt.Errorf("bucket %q doesn't exists", s.pathPrefix)
 		}
 		objectKey := []byte(s.GetResourcePath())
-		return b.Put(objectKey, data)
+		if old := b.Get(objectKey); old != nil {
+			if err := s.deleteIndexes(tx, objectKey, old); err != nil {
+				return err
+			}
+		}
+		if err := b.Put(objectKey, data); err != nil {
+			return err
+		}
+		return s.putIndexes(tx, objectKey, new)
 	})
 }
 
+// UpdateFunc reads the object, changes it with the function and saves it in a
+// single transaction, the changes of concurrent updates aren't lost. The object
+// isn't saved when the function fails, its error is returned.
+func (s *Store) UpdateFunc(name string, fn func(obj types.Object) error) (types.Object, error) {
+	defer metrics.ObserveStoreOperation("update", time.Now())
+	db, err := s.DB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	obj := s.newObject()
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(s.pathPrefix))
+		if b == nil {
+			return fmt.Errorf("bucket %q doesn't exists", s.pathPrefix)
+		}
+		s.path = path.Join(s.path, name)
+		objectKey := []byte(s.GetResourcePath())
+		old := b.Get(objectKey)
+		if old == nil {
+			return &NotFoundError{Key: string(objectKey)}
+		}
+		if err := s.decode(old, obj); err != nil {
+			return err
+		}
+		if err := fn(obj); err != nil {
+			return err
+		}
+		obj.SetAPIVersion(types.StorageVersion)
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if err := s.deleteIndexes(tx, objectKey, old); err != nil {
+			return err
+		}
+		if err := b.Put(objectKey, data); err != nil {
+			return err
+		}
+		return s.putIndexes(tx, objectKey, obj)
+	})
+	return obj, err
+}
+
+// NotFoundError is returned when an object or a blob doesn't exist
+type NotFoundError struct {
+	Key string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("obj %q not found", e.Key)
+}
+
+// IsNotFound returns true when the error is a NotFoundError
+func IsNotFound(err error) bool {
+	_, ok := err.(*NotFoundError)
+	return ok
+}
+
 func (s *Store) Get(name string) (types.Object, error) {
+	defer metrics.ObserveStoreOperation("get", time.Now())
 	db, err := s.DB()
 	if err != nil {
 		return nil, err
@@ -126,40 +253,198 @@ func (s *Store) Get(name string) (types.Object, error) {
 		data := b.Get(objKey)
 
 		if data == nil {
-			return fmt.Errorf("obj %q not found", string(objKey))
+			return &NotFoundError{Key: string(objKey)}
 		}
-		return json.Unmarshal(data, obj)
+		return s.decode(data, obj)
 	})
 	return obj, err
 }
 
+// ListOptions pages the objects returned by ListPage
+type ListOptions struct {
+	// Limit is the maximum number of objects of a page, zero returns all of them
+	Limit int64
+	// Continue is the token returned with the previous page
+	Continue string
+	// Match filters the objects, the limit only counts the ones matching
+	Match func(obj types.Object) bool
+	// Index and IndexValue scan only the objects with the value in a secondary
+	// index of the kind, e.g.: the games of a player
+	Index      string
+	IndexValue string
+}
+
+// Page is a chunk of the objects of a list
+type Page struct {
+	Items []types.Object
+	// Keys are the keys of the items in the bucket
+	Keys []string
+	// Continue requests the next page, it's empty in the last one
+	Continue string
+	// RemainingItemCount is the number of objects after the page, it isn't
+	// counted when the objects are filtered by a match.
+	RemainingItemCount *int64
+}
+
 func (s *Store) List(re *regexp.Regexp) ([]types.Object, error) {
+	page, err := s.ListPage(re, ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// ListPage returns the objects of the resource path matching the regexp in
+// the order of their keys, the continue token is the key of the last object
+// of the page where the cursor is positioned for the next one. Objects listed
+// by an index are ordered by their key in the index.
+func (s *Store) ListPage(re *regexp.Regexp, opts ListOptions) (*Page, error) {
+	defer metrics.ObserveStoreOperation("list", time.Now())
 	db, err := s.DB()
 	if err != nil {
 		return nil, err
 	}
 	defer db.Close()
-	var items []types.Object
+	page := &Page{}
 	err = db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(s.pathPrefix))
 		if b == nil {
 			return fmt.Errorf("bucket %q doesn't exists", s.pathPrefix)
 		}
-		c := b.Cursor()
 		prefix := []byte(s.GetResourcePath())
-		for k, v := c.Seek(prefix); k != nil && re.Match(k); k, v = c.Next() {
+		c, scanPrefix, err := s.listCursor(tx, b, prefix, opts)
+		if err != nil || c == nil {
+			return err
+		}
+		k, v := c.Seek(scanPrefix)
+		if opts.Continue != "" {
+			last, err := decodeContinue(opts.Continue, scanPrefix)
+			if err != nil {
+				return err
+			}
+			// the object may have been deleted, seek positions the cursor after it
+			if k, v = c.Seek(last); bytes.Equal(k, last) {
+				k, v = c.Next()
+			}
+		}
+		var lastKey []byte
+		var remaining int64
+		// child keys are interleaved with the objects (e.g.: /event/<name>/game/...),
+		// scan the whole prefix and skip the keys that don't match
+		for ; k != nil && bytes.HasPrefix(k, scanPrefix); k, v = c.Next() {
+			objKey := k
+			if opts.Index != "" {
+				// the entries of an index have the objects of every resource path
+				if objKey = k[len(scanPrefix):]; !bytes.HasPrefix(objKey, prefix) {
+					continue
+				}
+			}
+			if !re.Match(objKey) {
+				continue
+			}
+			full := opts.Limit > 0 && int64(len(page.Items)) == opts.Limit
+			if full && opts.Match == nil {
+				// the objects after the page are counted without decoding them
+				remaining++
+				continue
+			}
+			if opts.Index != "" {
+				if v = b.Get(objKey); v == nil {
+					continue
+				}
+			}
 			obj := s.newObject()
-			if err := json.Unmarshal(v, obj); err != nil {
+			if err := s.decode(v, obj); err != nil {
 				return err
 			}
-			items = append(items, obj)
+			if opts.Match != nil && !opts.Match(obj) {
+				continue
+			}
+			if full {
+				page.Continue = encodeContinue(lastKey)
+				return nil
+			}
+			page.Items = append(page.Items, obj)
+			page.Keys = append(page.Keys, string(objKey))
+			lastKey = append(lastKey[:0], k...)
+		}
+		if remaining > 0 {
+			page.Continue = encodeContinue(lastKey)
+			page.RemainingItemCount = &remaining
 		}
 		return nil
 	})
-	return items, err
+	return page, err
+}
+
+// ListKeys returns the keys of the objects matching the regexp without decoding
+// them, e.g.: counting the games of a phase by the index. The limit, the
+// continue token and the match of the options are ignored.
+func (s *Store) ListKeys(re *regexp.Regexp, opts ListOptions) ([]string, error) {
+	defer metrics.ObserveStoreOperation("list", time.Now())
+	db, err := s.DB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	var keys []string
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(s.pathPrefix))
+		if b == nil {
+			return fmt.Errorf("bucket %q doesn't exists", s.pathPrefix)
+		}
+		prefix := []byte(s.GetResourcePath())
+		c, scanPrefix, err := s.listCursor(tx, b, prefix, opts)
+		if err != nil || c == nil {
+			return err
+		}
+		for k, _ := c.Seek(scanPrefix); k != nil && bytes.HasPrefix(k, scanPrefix); k, _ = c.Next() {
+			objKey := k
+			if opts.Index != "" {
+				objKey = k[len(scanPrefix):]
+			}
+			if bytes.HasPrefix(objKey, prefix) && re.Match(objKey) {
+				keys = append(keys, string(objKey))
+			}
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// listCursor returns the cursor of a list and the prefix of its keys, lists by
+// an index scan the entries of the value. A nil cursor is an empty list.
+func (s *Store) listCursor(tx *bolt.Tx, b *bolt.Bucket, prefix []byte, opts ListOptions) (*bolt.Cursor, []byte, error) {
+	if opts.Index == "" {
+		return b.Cursor(), prefix, nil
+	}
+	kind := s.objType.GetObjectKind()
+	if lookupIndex(kind, opts.Index) == nil {
+		return nil, nil, fmt.Errorf("unknown index %q of the kind %q", opts.Index, kind)
+	}
+	// the bucket is created with the first entry
+	ib := tx.Bucket(s.indexBucket(kind, opts.Index))
+	if ib == nil {
+		return nil, nil, nil
+	}
+	return ib.Cursor(), indexKey(opts.IndexValue, nil), nil
+}
+
+func encodeContinue(key []byte) string {
+	return base64.RawURLEncoding.EncodeToString(key)
+}
+
+// decodeContinue returns the key of a continue token, tokens of other resources are refused
+func decodeContinue(token string, prefix []byte) ([]byte, error) {
+	key, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !bytes.HasPrefix(key, prefix) {
+		return nil, fmt.Errorf("invalid continue token %q", token)
+	}
+	return key, nil
 }
 
 func (s *Store) Delete(name string) error {
+	defer metrics.ObserveStoreOperation("delete", time.Now())
 	db, err := s.DB()
 	if err != nil {
 		return err
@@ -172,21 +457,114 @@ func (s *Store) Delete(name string) error {
 		}
 		s.path = path.Join(s.path, name)
 		c := b.Cursor()
-		prefix := []byte(s.GetResourcePath())
-		// Lookup and delete all child keys
+		objKey := []byte(s.GetResourcePath())
+		// Lookup and delete all child keys, the separator avoids deleting
+		// objects sharing the prefix of the name (e.g.: /challenge/foo and /challenge/foo-2)
+		prefix := append(append([]byte{}, objKey...), '/')
+		keys := [][]byte{objKey}
 		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
-			if err := b.Delete(k); err != nil {
+			keys = append(keys, append([]byte{}, k...))
+		}
+		for _, k := range keys {
+			if err := s.deleteKey(tx, b, k); err != nil {
 				return err
 			}
 		}
-		return b.Delete(prefix)
+		return nil
+	})
+}
+
+// PutBlob stores raw content by its key in the resource path, e.g.: /asset/sha256:<hex>.
+// Blobs are content addressed, an existing key isn't written again.
+func (s *Store) PutBlob(key string, data []byte) error {
+	defer metrics.ObserveStoreOperation("put_blob", time.Now())
+	db, err := s.DB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(s.pathPrefix))
+		if err != nil {
+			return err
+		}
+		s.path = path.Join(s.path, key)
+		blobKey := []byte(s.GetResourcePath())
+		if b.Get(blobKey) != nil {
+			return nil
+		}
+		return b.Put(blobKey, data)
 	})
 }
 
+// GetBlob returns the raw content stored by PutBlob
+func (s *Store) GetBlob(key string) ([]byte, error) {
+	defer metrics.ObserveStoreOperation("get_blob", time.Now())
+	db, err := s.DB()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	var data []byte
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(s.pathPrefix))
+		if b == nil {
+			return fmt.Errorf("bucket %q doesn't exists", s.pathPrefix)
+		}
+		s.path = path.Join(s.path, key)
+		blobKey := []byte(s.GetResourcePath())
+		v := b.Get(blobKey)
+		if v == nil {
+			return &NotFoundError{Key: string(blobKey)}
+		}
+		// the value is only valid during the transaction
+		data = append([]byte{}, v...)
+		return nil
+	})
+	return data, err
+}
+
+// DeleteObject removes only the object, its child keys are kept
+func (s *Store) DeleteObject(name string) error {
+	defer metrics.ObserveStoreOperation("delete", time.Now())
+	db, err := s.DB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(s.pathPrefix))
+		if b == nil {
+			return fmt.Errorf("bucket %q doesn't exists", s.pathPrefix)
+		}
+		s.path = path.Join(s.path, name)
+		return s.deleteKey(tx, b, []byte(s.GetResourcePath()))
+	})
+}
+
+// deleteKey removes an object with its index entries
+func (s *Store) deleteKey(tx *bolt.Tx, b *bolt.Bucket, key []byte) error {
+	if data := b.Get(key); data != nil {
+		if err := s.deleteIndexes(tx, key, data); err != nil {
+			return err
+		}
+	}
+	return b.Delete(key)
+}
+
 func (s *Store) GetResourcePath() string {
 	return path.Join("/", s.path)
 }
 
+// decode converts the objects stored with a previous version before decoding them
+func (s *Store) decode(data []byte, obj types.Object) error {
+	data, _, err := types.ConvertToStorageVersion(s.objType.GetObjectKind(), data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, obj)
+}
+
 func (s *Store) newObject() types.Object {
 	if s.objType == nil {
 		// TODO: raise error
@@ -194,6 +572,22 @@ func (s *Store) newObject() types.Object {
 	return s.objType.New()
 }
 
+// Ping verifies the database could be opened and the bucket exists,
+// it doesn't block when the file is locked for longer than the timeout.
+func (s *Store) Ping(timeout time.Duration) error {
+	db, err := bolt.Open(s.dbfile, 0600, &bolt.Options{Timeout: timeout})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(s.pathPrefix)) == nil {
+			return fmt.Errorf("bucket %q not found", s.pathPrefix)
+		}
+		return nil
+	})
+}
+
 func (s *Store) DB() (*bolt.DB, error) {
 	db, err := bolt.Open(s.dbfile, 0600, nil)
 	if err != nil {
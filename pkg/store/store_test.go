@@ -0,0 +1,275 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kubeplay/gameserver/pkg/types"
+)
+
+const testBucket = "kubeplay"
+
+var gameKeyRe = regexp.MustCompile(`^\/event\/[^/]+\/game\/[^/]+$`)
+
+// newTestDB initializes a database in a temporary directory, the returned func removes it
+func newTestDB(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "kubeplay-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "kubeplay.db")
+	if err := New(file, testBucket).Init(); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return file, func() { os.RemoveAll(dir) }
+}
+
+func games(file, event string) *Store {
+	return New(file, testBucket).
+		Kind(types.GameKind).
+		Resources(strings.ToLower(types.EventKind), event, strings.ToLower(types.GameKind))
+}
+
+func saveGame(t *testing.T, file, event, name, player string, phase types.GamePhase) {
+	gm := &types.Game{
+		TypeMeta:  types.TypeMeta{Kind: types.GameKind},
+		Metadata:  types.Metadata{Name: name},
+		Challenge: "foo",
+		Player:    player,
+		Status:    types.GameStatus{Phase: phase},
+	}
+	_, err := New(file, testBucket).
+		Kind(types.GameKind).
+		Resources(strings.ToLower(types.EventKind), event, strings.ToLower(types.GameKind), name).
+		SaveObject(gm)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func names(page *Page) []string {
+	var out []string
+	for _, obj := range page.Items {
+		out = append(out, obj.GetObjectMeta().Name)
+	}
+	return out
+}
+
+func TestGetNotFound(t *testing.T) {
+	file, cleanup := newTestDB(t)
+	defer cleanup()
+	_, err := games(file, "meetup").Get("missing")
+	if !IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+	saveGame(t, file, "meetup", "g1", "github|a", types.GamePending)
+	if _, err := games(file, "meetup").Get("g1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, err = New(file, testBucket).Kind(types.ChallengeKind).Resources("asset").GetBlob("sha256:missing")
+	if !IsNotFound(err) {
+		t.Errorf("expected a not found error for blobs, got %v", err)
+	}
+}
+
+func TestUpdateFunc(t *testing.T) {
+	file, cleanup := newTestDB(t)
+	defer cleanup()
+	_, err := games(file, "meetup").UpdateFunc("missing", func(obj types.Object) error { return nil })
+	if !IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+	saveGame(t, file, "meetup", "g1", "github|a", types.GamePending)
+
+	// a failed change isn't saved
+	errSkip := errors.New("skip")
+	_, err = games(file, "meetup").UpdateFunc("g1", func(obj types.Object) error {
+		obj.(*types.Game).Status.Phase = types.GameRunning
+		return errSkip
+	})
+	if err != errSkip {
+		t.Errorf("expected the error of the function, got %v", err)
+	}
+	if got := gameKeys(t, file, "", GamePhaseIndex, string(types.GamePending)); len(got) != 1 {
+		t.Errorf("expected the game to be kept pending, got %v", got)
+	}
+
+	// the concurrent changes are applied one after the other
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := games(file, "meetup").UpdateFunc("g1", func(obj types.Object) error {
+				gm := obj.(*types.Game)
+				gm.Status.Phase = types.GameRunning
+				gm.Status.Keys = append(gm.Status.Keys, types.GameKeyStatus{KeyName: fmt.Sprintf("key-%d", i)})
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	obj, err := games(file, "meetup").Get("g1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(obj.(*types.Game).Status.Keys); got != 10 {
+		t.Errorf("expected the keys of every update, got %d", got)
+	}
+	if got := gameKeys(t, file, "", GamePhaseIndex, string(types.GamePending)); len(got) != 0 {
+		t.Errorf("expected the old value to be removed from the index, got %v", got)
+	}
+	if got := gameKeys(t, file, "", GamePhaseIndex, string(types.GameRunning)); len(got) != 1 {
+		t.Errorf("expected the new value in the index, got %v", got)
+	}
+}
+
+func TestListPage(t *testing.T) {
+	int64p := func(v int64) *int64 { return &v }
+	for _, tc := range []struct {
+		name string
+		// prepare runs after listing the first page, e.g.: deleting objects
+		prepare       func(t *testing.T, file string)
+		opts          ListOptions
+		wantFirst     []string
+		wantRemaining *int64
+		wantSecond    []string
+	}{
+		{
+			name:          "all the objects",
+			wantFirst:     []string{"g1", "g2", "g3", "g4", "g5"},
+			wantRemaining: nil,
+		},
+		{
+			name:          "pages",
+			opts:          ListOptions{Limit: 2},
+			wantFirst:     []string{"g1", "g2"},
+			wantRemaining: int64p(3),
+			wantSecond:    []string{"g3", "g4"},
+		},
+		{
+			name:          "last page",
+			opts:          ListOptions{Limit: 5},
+			wantFirst:     []string{"g1", "g2", "g3", "g4", "g5"},
+			wantRemaining: nil,
+		},
+		{
+			name: "continue after the last object of the page was deleted",
+			prepare: func(t *testing.T, file string) {
+				if err := games(file, "meetup").Delete("g2"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			opts:          ListOptions{Limit: 2},
+			wantFirst:     []string{"g1", "g2"},
+			wantRemaining: int64p(3),
+			wantSecond:    []string{"g3", "g4"},
+		},
+		{
+			name: "continue after the next object was deleted",
+			prepare: func(t *testing.T, file string) {
+				if err := games(file, "meetup").Delete("g3"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			opts:          ListOptions{Limit: 2},
+			wantFirst:     []string{"g1", "g2"},
+			wantRemaining: int64p(3),
+			wantSecond:    []string{"g4", "g5"},
+		},
+		{
+			name: "the remaining objects aren't counted with a match",
+			opts: ListOptions{Limit: 1, Match: func(obj types.Object) bool {
+				return obj.(*types.Game).Player == "github|a"
+			}},
+			wantFirst:     []string{"g1"},
+			wantRemaining: nil,
+			wantSecond:    []string{"g3"},
+		},
+		{
+			name: "a match without more objects has no continue token",
+			opts: ListOptions{Limit: 1, Match: func(obj types.Object) bool {
+				return obj.GetObjectMeta().Name == "g5"
+			}},
+			wantFirst: []string{"g5"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			file, cleanup := newTestDB(t)
+			defer cleanup()
+			for i, name := range []string{"g1", "g2", "g3", "g4", "g5"} {
+				player := "github|a"
+				if i%2 == 1 {
+					player = "github|b"
+				}
+				saveGame(t, file, "meetup", name, player, types.GamePending)
+			}
+			// an event sharing the first characters of the name
+			saveGame(t, file, "meetup-2", "g0", "github|a", types.GamePending)
+
+			page, err := games(file, "meetup").ListPage(gameKeyRe, tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(page); !reflect.DeepEqual(got, tc.wantFirst) {
+				t.Errorf("expected the first page %v, got %v", tc.wantFirst, got)
+			}
+			if !reflect.DeepEqual(page.RemainingItemCount, tc.wantRemaining) {
+				t.Errorf("expected %v remaining items, got %v", tc.wantRemaining, page.RemainingItemCount)
+			}
+			if tc.wantSecond == nil {
+				if page.Continue != "" {
+					t.Errorf("expected no continue token, got %q", page.Continue)
+				}
+				return
+			}
+			if page.Continue == "" {
+				t.Fatal("expected a continue token")
+			}
+			if tc.prepare != nil {
+				tc.prepare(t, file)
+			}
+			opts := tc.opts
+			opts.Continue = page.Continue
+			next, err := games(file, "meetup").ListPage(gameKeyRe, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(next); !reflect.DeepEqual(got, tc.wantSecond) {
+				t.Errorf("expected the second page %v, got %v", tc.wantSecond, got)
+			}
+		})
+	}
+}
+
+func TestListPageInvalidContinue(t *testing.T) {
+	file, cleanup := newTestDB(t)
+	defer cleanup()
+	saveGame(t, file, "meetup", "g1", "github|a", types.GamePending)
+	saveGame(t, file, "meetup", "g2", "github|a", types.GamePending)
+	saveGame(t, file, "other", "g1", "github|a", types.GamePending)
+	saveGame(t, file, "other", "g2", "github|a", types.GamePending)
+
+	other, err := games(file, "other").ListPage(gameKeyRe, ListOptions{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{"not base64!", other.Continue} {
+		_, err := games(file, "meetup").ListPage(gameKeyRe, ListOptions{Limit: 1, Continue: token})
+		if err == nil {
+			t.Errorf("expected the continue token %q to be refused", token)
+		}
+	}
+}
@@ -1,25 +1,8 @@
 package store
 
-import (
-	"sync"
-
-	"github.com/pborman/uuid"
-)
-
-var uuidLock sync.Mutex
-var lastUUID uuid.UUID
+import "github.com/kubeplay/gameserver/pkg/utils"
 
+// NewUUID returns a time based UUID, see utils.NewUUID
 func NewUUID() string {
-	uuidLock.Lock()
-	defer uuidLock.Unlock()
-	result := uuid.NewUUID()
-	// The UUID package is naive and can generate identical UUIDs if the
-	// time interval is quick enough.
-	// The UUID uses 100 ns increments so it's short enough to actively
-	// wait for a new value.
-	for uuid.Equal(lastUUID, result) == true {
-		result = uuid.NewUUID()
-	}
-	lastUUID = result
-	return result.String()
+	return utils.NewUUID()
 }
@@ -0,0 +1,63 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// StorageVersion is the version of the objects written to the store
+const StorageVersion = GroupName + "/" + HubVersion
+
+// Conversion converts the fields of the stored objects of a kind from a version
+// to the next one. The fields are the decoded JSON, the types of the previous
+// versions don't exist anymore.
+type Conversion struct {
+	// Kind is the kind converted, an empty kind converts every kind
+	Kind string
+	From string
+	To   string
+	// Convert changes the fields in place, nil only changes the version
+	Convert func(fields map[string]interface{}) error
+}
+
+// ConvertToStorageVersion applies the conversions of a kind until the object
+// has the StorageVersion, it returns the data unchanged when it's current.
+// Objects of unknown versions (e.g.: written by a newer server) fail.
+func ConvertToStorageVersion(kind string, data []byte) ([]byte, bool, error) {
+	meta := &TypeMeta{}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, false, err
+	}
+	if meta.APIVersion == StorageVersion {
+		return data, false, nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, false, err
+	}
+	version := meta.APIVersion
+	for version != StorageVersion {
+		c := lookupConversion(kind, version)
+		if c == nil {
+			return nil, false, fmt.Errorf("%s: unknown version %q, expected %q or a version converted to it", kind, version, StorageVersion)
+		}
+		if c.Convert != nil {
+			if err := c.Convert(fields); err != nil {
+				return nil, false, fmt.Errorf("%s: failed converting from %q to %q: %v", kind, c.From, c.To, err)
+			}
+		}
+		version = c.To
+	}
+	fields["apiVersion"] = version
+	data, err := json.Marshal(fields)
+	return data, true, err
+}
+
+func lookupConversion(kind, from string) *Conversion {
+	for i, c := range Conversions {
+		if c.From == from && (c.Kind == "" || c.Kind == kind) {
+			return &Conversions[i]
+		}
+	}
+	return nil
+}
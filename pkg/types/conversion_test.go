@@ -0,0 +1,119 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestConvertToStorageVersion(t *testing.T) {
+	defer func(c []Conversion) { Conversions = c }(Conversions)
+	Conversions = []Conversion{
+		{From: "", To: "kubeplay.io/v0"},
+		{
+			Kind: EventKind,
+			From: "kubeplay.io/v0",
+			To:   StorageVersion,
+			Convert: func(fields map[string]interface{}) error {
+				if fields["title"] == "invalid" {
+					return fmt.Errorf("invalid title")
+				}
+				fields["displayName"] = fields["title"]
+				delete(fields, "title")
+				return nil
+			},
+		},
+		{From: "kubeplay.io/v0", To: StorageVersion},
+	}
+	for _, tc := range []struct {
+		name          string
+		kind          string
+		data          string
+		wantConverted bool
+		wantFields    map[string]interface{}
+		wantErr       string
+	}{
+		{
+			name:       "storage version",
+			kind:       EventKind,
+			data:       `{"kind":"Event","apiVersion":"kubeplay.io/v1","title":"kept"}`,
+			wantFields: map[string]interface{}{"apiVersion": StorageVersion, "title": "kept"},
+		},
+		{
+			name:          "without version",
+			kind:          ChallengeKind,
+			data:          `{"kind":"Challenge","assetsURL":"https://example.com"}`,
+			wantConverted: true,
+			wantFields:    map[string]interface{}{"apiVersion": StorageVersion, "assetsURL": "https://example.com"},
+		},
+		{
+			name:          "conversions of the kind",
+			kind:          EventKind,
+			data:          `{"kind":"Event","apiVersion":"kubeplay.io/v0","title":"Meetup"}`,
+			wantConverted: true,
+			wantFields:    map[string]interface{}{"apiVersion": StorageVersion, "displayName": "Meetup"},
+		},
+		{
+			name:          "chained conversions",
+			kind:          EventKind,
+			data:          `{"kind":"Event","title":"Meetup"}`,
+			wantConverted: true,
+			wantFields:    map[string]interface{}{"apiVersion": StorageVersion, "displayName": "Meetup"},
+		},
+		{
+			name:          "other kinds skip the conversions of a kind",
+			kind:          ChallengeKind,
+			data:          `{"kind":"Challenge","apiVersion":"kubeplay.io/v0","title":"kept"}`,
+			wantConverted: true,
+			wantFields:    map[string]interface{}{"apiVersion": StorageVersion, "title": "kept"},
+		},
+		{
+			name:    "failed conversion",
+			kind:    EventKind,
+			data:    `{"kind":"Event","apiVersion":"kubeplay.io/v0","title":"invalid"}`,
+			wantErr: "invalid title",
+		},
+		{
+			name:    "newer version",
+			kind:    EventKind,
+			data:    `{"kind":"Event","apiVersion":"kubeplay.io/v2"}`,
+			wantErr: "unknown version",
+		},
+		{
+			name:    "invalid JSON",
+			kind:    EventKind,
+			data:    `{"kind":`,
+			wantErr: "unexpected end",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data, converted, err := ConvertToStorageVersion(tc.kind, []byte(tc.data))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if converted != tc.wantConverted {
+				t.Errorf("expected converted=%v, got %v", tc.wantConverted, converted)
+			}
+			fields := map[string]interface{}{}
+			if err := json.Unmarshal(data, &fields); err != nil {
+				t.Fatal(err)
+			}
+			delete(fields, "kind")
+			if len(fields) != len(tc.wantFields) {
+				t.Errorf("expected the fields %v, got %v", tc.wantFields, fields)
+			}
+			for key, value := range tc.wantFields {
+				if fields[key] != value {
+					t.Errorf("expected %s=%v, got %v", key, value, fields[key])
+				}
+			}
+		})
+	}
+}
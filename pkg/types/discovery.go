@@ -0,0 +1,72 @@
+package types
+
+const APIResourceListKind = "APIResourceList"
+
+// APIResourceList is the discovery document of a version, e.g.: GET /v1
+type APIResourceList struct {
+	TypeMeta `json:",inline"`
+	ListMeta `json:"metadata"`
+
+	GroupVersion string        `json:"groupVersion"`
+	Resources    []APIResource `json:"resources"`
+}
+
+// APIResource is a kind served by the API
+type APIResource struct {
+	// Name is the plural name of the resource, e.g.: games
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// Verbs are the operations on the resource: list, create, get, update and delete
+	Verbs []string `json:"verbs"`
+	// Subresources are the actions and the nested paths of an object, e.g.: solve
+	Subresources []string `json:"subresources,omitempty"`
+	// Paths are the collections of the resource, e.g.: /v1/events/{parent}/games
+	Paths []string `json:"paths"`
+}
+
+// OpenAPI is an OpenAPI v3 document of a version, the schemas of the
+// components are generated from the registered types.
+type OpenAPI struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       OpenAPIInfo                     `json:"info"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components OpenAPIComponents               `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*JSONSchema `json:"schemas"`
+}
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string      `json:"name"`
+	In       string      `json:"in"`
+	Required bool        `json:"required"`
+	Schema   *JSONSchema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *JSONSchema `json:"schema"`
+}
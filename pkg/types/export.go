@@ -0,0 +1,32 @@
+package types
+
+// ExportMediaType is the content type of the exports of the game server,
+// multi-document YAML with one object per document.
+const ExportMediaType = "application/yaml"
+
+// RevisionAnnotation marks the exported revisions of a challenge, the
+// documents of the revisions are Challenge objects with their generation.
+const RevisionAnnotation = "kubeplay.io/revision"
+
+// ExportKinds are the kinds exported in the order they're imported, the
+// challenges and the events come before the games which depend on them.
+var ExportKinds = []string{
+	RoleKind,
+	ChallengeKind,
+	EventKind,
+	GameKind,
+	RoleBindingKind,
+	PolicyKind,
+	EventHookKind,
+	UserKind,
+}
+
+// ImportResult reports the objects of an import, e.g.: game/meetup/foo.
+// Existing objects are skipped, they aren't replaced.
+type ImportResult struct {
+	TypeMeta `json:",inline"`
+	ListMeta `json:"metadata"`
+
+	Created []string `json:"created"`
+	Skipped []string `json:"skipped"`
+}
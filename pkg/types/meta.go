@@ -1,9 +1,15 @@
 package types
 
-import "fmt"
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
 
 type Object interface {
 	GetObjectKind() string
+	GetAPIVersion() string
+	SetAPIVersion(version string)
 	GetObjectMeta() *Metadata
 	New() Object
 }
@@ -20,17 +26,77 @@ type TypeMeta struct {
 
 	// APIVersion defines the versioned schema of this representation of an object.
 	// Servers should convert recognized schemas to the latest internal value, and
-	// may reject unrecognized values.
-	// APIVersion string `json:"apiVersion"`
+	// may reject unrecognized values. Objects are stored with the StorageVersion.
+	APIVersion string `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
 }
 
-type ListMeta struct{}
+// ListMeta describes a list, lists are paged with the query params limit and continue
+type ListMeta struct {
+	// Continue is the token requesting the next page, it's empty in the last one
+	Continue string `json:"continue,omitempty"`
+	// RemainingItemCount is the number of items after the page, it's omitted
+	// when the list is filtered by selectors.
+	RemainingItemCount *int64 `json:"remainingItemCount,omitempty"`
+}
 
 type Metadata struct {
 	Name        string            `json:"name"`
 	UID         string            `json:"uid"`
 	CreatedAt   string            `json:"createdAt"`
 	Annotations map[string]string `json:"annotations,omitempty"`
+	// Labels organize objects, lists are filtered by them with label selectors
+	Labels map[string]string `json:"labels,omitempty"`
+	// Generation is incremented by the server every time the object changes
+	Generation int64 `json:"generation,omitempty"`
+	// OwnerReferences are the objects this object depends on, e.g.: the event
+	// and the challenge of a game
+	OwnerReferences []OwnerReference `json:"ownerReferences,omitempty"`
+}
+
+// OwnerReference identifies an object which owns another one
+type OwnerReference struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	UID  string `json:"uid"`
+}
+
+// DeletionPolicy decides what happens to the dependents of an object when it's deleted
+type DeletionPolicy string
+
+const (
+	// DeletionBlock refuses to delete objects which still have dependents
+	DeletionBlock DeletionPolicy = "block"
+	// DeletionCascade deletes the dependents with the object
+	DeletionCascade DeletionPolicy = "cascade"
+	// DeletionOrphan deletes the object and keeps its dependents without the owner reference
+	DeletionOrphan DeletionPolicy = "orphan"
+)
+
+// NewOwnerReference returns a reference to obj
+func NewOwnerReference(obj Object) OwnerReference {
+	meta := obj.GetObjectMeta()
+	return OwnerReference{Kind: obj.GetObjectKind(), Name: meta.Name, UID: meta.UID}
+}
+
+// IsOwnedBy returns true if the object has a reference to the owner
+func (m *Metadata) IsOwnedBy(kind, name string) bool {
+	for _, ref := range m.OwnerReferences {
+		if ref.Kind == kind && ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveOwnerReference removes the references to the owner
+func (m *Metadata) RemoveOwnerReference(kind, name string) {
+	var refs []OwnerReference
+	for _, ref := range m.OwnerReferences {
+		if ref.Kind != kind || ref.Name != name {
+			refs = append(refs, ref)
+		}
+	}
+	m.OwnerReferences = refs
 }
 
 func (m *ListMeta) GetObjectMeta() *Metadata {
@@ -45,15 +111,75 @@ func (t *TypeMeta) GetObjectKind() string {
 	return t.Kind
 }
 
+func (t *TypeMeta) GetAPIVersion() string {
+	return t.APIVersion
+}
+
+func (t *TypeMeta) SetAPIVersion(version string) {
+	t.APIVersion = version
+}
+
 func (o *Game) New() Object          { return &Game{} }
 func (o *GameList) New() Object      { return &GameList{} }
 func (o *Challenge) New() Object     { return &Challenge{} }
 func (o *ChallengeList) New() Object { return &ChallengeList{} }
 func (o *Policy) New() Object        { return &Policy{} }
 func (o *PolicyList) New() Object    { return &PolicyList{} }
+func (o *Role) New() Object          { return &Role{} }
+func (o *RoleList) New() Object      { return &RoleList{} }
 func (o *Event) New() Object         { return &Event{} }
 func (o *EventList) New() Object     { return &EventList{} }
+func (o *User) New() Object          { return &User{} }
+func (o *UserList) New() Object      { return &UserList{} }
+func (o *Session) New() Object       { return &Session{} }
+func (o *SessionList) New() Object   { return &SessionList{} }
+func (o *RevokedToken) New() Object  { return &RevokedToken{} }
+
+func (o *RoleBinding) New() Object     { return &RoleBinding{} }
+func (o *RoleBindingList) New() Object { return &RoleBindingList{} }
+func (o *EventHook) New() Object       { return &EventHook{} }
+func (o *EventHookList) New() Object   { return &EventHookList{} }
+
+func (o *SelfSubjectAccessReview) New() Object { return &SelfSubjectAccessReview{} }
+func (o *SubjectAccessReview) New() Object     { return &SubjectAccessReview{} }
+func (o *ImportResult) New() Object            { return &ImportResult{} }
+func (o *APIResourceList) New() Object         { return &APIResourceList{} }
 
 func (c *PlayerClaims) Username() string {
-	return fmt.Sprintf("github|%s", c.Login)
+	provider := c.Provider
+	if provider == "" {
+		provider = GitHubProvider
+	}
+	return Subject(provider, c.Login)
+}
+
+// Subject returns the subject of a login of an identity provider, e.g.: github|alice
+func Subject(provider, login string) string {
+	return fmt.Sprintf("%s|%s", provider, login)
+}
+
+// SplitSubject returns the identity provider and the login of a subject,
+// subjects without a provider are GitHub logins.
+func SplitSubject(subject string) (provider, login string) {
+	parts := strings.SplitN(subject, "|", 2)
+	if len(parts) == 1 {
+		return GitHubProvider, parts[0]
+	}
+	return parts[0], parts[1]
+}
+
+// ClientCertificateClaims maps a verified client certificate to a player, the
+// common name is the login and the organizations are the roles of the player.
+func ClientCertificateClaims(cert *x509.Certificate) (*PlayerClaims, error) {
+	if cert.Subject.CommonName == "" {
+		return nil, fmt.Errorf("the client certificate has an empty common name")
+	}
+	p := &PlayerClaims{
+		Name:     cert.Subject.CommonName,
+		Login:    cert.Subject.CommonName,
+		Provider: X509Provider,
+		Roles:    cert.Subject.Organization,
+	}
+	p.ExpiresAt = cert.NotAfter.Unix()
+	return p, nil
 }
@@ -0,0 +1,24 @@
+package types
+
+import "testing"
+
+func TestSplitSubject(t *testing.T) {
+	for _, tc := range []struct {
+		subject      string
+		wantProvider string
+		wantLogin    string
+	}{
+		{subject: "github|alice", wantProvider: GitHubProvider, wantLogin: "alice"},
+		{subject: "local|bob", wantProvider: LocalProvider, wantLogin: "bob"},
+		{subject: "alice", wantProvider: GitHubProvider, wantLogin: "alice"},
+		{subject: "local|a|b", wantProvider: LocalProvider, wantLogin: "a|b"},
+	} {
+		provider, login := SplitSubject(tc.subject)
+		if provider != tc.wantProvider || login != tc.wantLogin {
+			t.Errorf("%q: expected %s and %s, got %s and %s", tc.subject, tc.wantProvider, tc.wantLogin, provider, login)
+		}
+	}
+	if got := Subject(SplitSubject("local|bob")); got != "local|bob" {
+		t.Errorf("expected the subject to be kept, got %q", got)
+	}
+}
@@ -8,10 +8,19 @@ import (
 type Kind string
 
 const (
-	ChallengeKind = "Challenge"
-	GameKind      = "Game"
-	EventKind     = "Event"
-	PolicyKind    = "Policy"
+	ChallengeKind    = "Challenge"
+	GameKind         = "Game"
+	EventKind        = "Event"
+	PolicyKind       = "Policy"
+	RoleKind         = "Role"
+	RoleBindingKind  = "RoleBinding"
+	UserKind         = "User"
+	SessionKind      = "Session"
+	RevokedTokenKind = "RevokedToken"
+	EventHookKind    = "EventHook"
+
+	SelfSubjectAccessReviewKind = "SelfSubjectAccessReview"
+	SubjectAccessReviewKind     = "SubjectAccessReview"
 )
 
 var RegisteredTypes = []Object{
@@ -19,6 +28,25 @@ var RegisteredTypes = []Object{
 	&Challenge{TypeMeta: TypeMeta{Kind: ChallengeKind}},
 	&Event{TypeMeta: TypeMeta{Kind: EventKind}},
 	&Policy{TypeMeta: TypeMeta{Kind: PolicyKind}},
+	&Role{TypeMeta: TypeMeta{Kind: RoleKind}},
+	&RoleBinding{TypeMeta: TypeMeta{Kind: RoleBindingKind}},
+	&User{TypeMeta: TypeMeta{Kind: UserKind}},
+	&Session{TypeMeta: TypeMeta{Kind: SessionKind}},
+	&RevokedToken{TypeMeta: TypeMeta{Kind: RevokedTokenKind}},
+	&EventHook{TypeMeta: TypeMeta{Kind: EventHookKind}},
+	&SelfSubjectAccessReview{TypeMeta: TypeMeta{Kind: SelfSubjectAccessReviewKind}},
+	&SubjectAccessReview{TypeMeta: TypeMeta{Kind: SubjectAccessReviewKind}},
+}
+
+// Conversions upgrade the stored objects to the StorageVersion, they're applied
+// in order when an object is read or migrated. Objects stored before the
+// versions existed have an empty apiVersion and the same fields as v1.
+//
+// E.g.: changing the LastSolvedKey of the games to a pointer requires a new
+// StorageVersion (kubeplay.io/v2) and a conversion of the kind Game from v1
+// removing the empty keys.
+var Conversions = []Conversion{
+	{From: "", To: StorageVersion},
 }
 
 func Decode(meta *TypeMeta, payload []byte) (Object, error) {
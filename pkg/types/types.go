@@ -4,20 +4,53 @@ import (
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
-const GameKeyHeaderName = "X-Game-Key"
+const (
+	GameKeyHeaderName      = "X-Game-Key"
+	RefreshTokenHeaderName = "X-Refresh-Token"
+)
 
 // /v1/challenges
 type Challenge struct {
 	TypeMeta `json:",inline" yaml:",inline"`
 	Metadata `json:"metadata"`
 
-	Keys      map[string]Key `json:"keys"`
-	AssetsURL string         `json:"assetsURL"`
+	Keys map[string]Key `json:"keys"`
+	// AssetsURL is where players pull the bundle of the challenge, it's set when a bundle is pushed
+	AssetsURL string `json:"assetsURL"`
+	// Provisioner creates the environment of the player when a game is created,
+	// games without a provisioner are started by a host.
+	Provisioner *ProvisionerSpec `json:"provisioner,omitempty"`
+	// Bundle describes the files pushed with the challenge
+	Bundle *ChallengeBundle `json:"bundle,omitempty"`
+}
+
+// ChallengeBundle lists the files of a challenge bundle, the files are
+// stored by their content hash and served in /v1/assets/<digest>
+type ChallengeBundle struct {
+	// Digest is the content hash of the archive pushed
+	Digest   string       `json:"digest"`
+	PushedAt string       `json:"pushedAt"`
+	Files    []BundleFile `json:"files"`
+}
+
+// BundleFile is a file of a bundle, e.g.: README.md, assets/cluster.yaml or provisioner/deployment.yaml
+type BundleFile struct {
+	Path   string `json:"path"`
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+// ProvisionerSpec selects a provisioner registered in the game server
+type ProvisionerSpec struct {
+	// Name is the provisioner, e.g.: kubernetes or exec
+	Name string `json:"name"`
+	// Params are passed to the provisioner, e.g.: the labels of a namespace
+	Params map[string]string `json:"params,omitempty"`
 }
 
 type ChallengeList struct {
 	TypeMeta `json:",inline"`
-	ListMeta
+	ListMeta `json:"metadata"`
 
 	Items []Challenge `json:"items"`
 }
@@ -29,6 +62,9 @@ type Event struct {
 
 	// Paused blocks new games from starting
 	Paused bool `json:"paused"`
+	// GameTimeout expires the games running longer than the duration, e.g.: 2h.
+	// The games never expire when it's empty.
+	GameTimeout string `json:"gameTimeout,omitempty"`
 
 	// Score *Score `json:"score"`
 	// Raking
@@ -36,7 +72,7 @@ type Event struct {
 
 type EventList struct {
 	TypeMeta `json:",inline"`
-	ListMeta
+	ListMeta `json:"metadata"`
 
 	Items []Event `json:"items"`
 }
@@ -46,14 +82,18 @@ type Game struct {
 	TypeMeta `json:",inline" yaml:",inline"`
 	Metadata `json:"metadata"`
 
-	Challenge string     `json:"challenge"`
-	Player    string     `json:"player"`
-	Status    GameStatus `json:"status,omitempty"`
+	// Event is where the game was created, it's set by the server
+	Event     string `json:"event,omitempty"`
+	Challenge string `json:"challenge"`
+	// ChallengeGeneration pins the revision of the challenge the game was created with
+	ChallengeGeneration int64      `json:"challengeGeneration,omitempty"`
+	Player              string     `json:"player"`
+	Status              GameStatus `json:"status,omitempty"`
 }
 
 type GameList struct {
 	TypeMeta `json:",inline"`
-	ListMeta
+	ListMeta `json:"metadata"`
 
 	Items []Game `json:"items"`
 }
@@ -64,6 +104,8 @@ const (
 	GamePending   GamePhase = "Pending"
 	GameRunning   GamePhase = "Running"
 	GameCompleted GamePhase = "Completed"
+	// GameExpired games ran out of time before solving every key
+	GameExpired GamePhase = "Expired"
 )
 
 type GameStatus struct {
@@ -73,6 +115,25 @@ type GameStatus struct {
 	RegisteredKeys int             `json:"registeredKeys"`
 	Phase          GamePhase       `json:"phase"`
 	Keys           []GameKeyStatus `json:"keys"`
+	// Provisioning is the status of the environment of the player
+	Provisioning *ProvisioningStatus `json:"provisioning,omitempty"`
+}
+
+type ProvisioningPhase string
+
+const (
+	ProvisioningInProgress ProvisioningPhase = "Provisioning"
+	ProvisioningReady      ProvisioningPhase = "Ready"
+	ProvisioningFailed     ProvisioningPhase = "Failed"
+)
+
+type ProvisioningStatus struct {
+	Provisioner string            `json:"provisioner"`
+	Phase       ProvisioningPhase `json:"phase"`
+	Message     string            `json:"message,omitempty"`
+	// Outputs describe the environment, e.g.: the namespace of the player
+	Outputs   map[string]string `json:"outputs,omitempty"`
+	UpdatedAt string            `json:"updatedAt"`
 }
 
 type GameKeyStatus struct {
@@ -99,7 +160,7 @@ type Policy struct {
 
 type PolicyList struct {
 	TypeMeta `json:",inline"`
-	ListMeta
+	ListMeta `json:"metadata"`
 
 	Items []Policy `json:"items"`
 }
@@ -110,14 +171,210 @@ type PolicyRule struct {
 	Actions string `json:"actions"`
 }
 
+// /v1/roles
+// Role is a named set of rules granted to the subjects of its role bindings
+type Role struct {
+	TypeMeta `json:",inline" yaml:",inline"`
+	Metadata `json:"metadata"`
+
+	Rules []PolicyRule `json:"rules"`
+}
+
+type RoleList struct {
+	TypeMeta `json:",inline"`
+	ListMeta `json:"metadata"`
+
+	Items []Role `json:"items"`
+}
+
+// /v1/rolebindings
+// RoleBinding grants a role to a list of subjects
+type RoleBinding struct {
+	TypeMeta `json:",inline" yaml:",inline"`
+	Metadata `json:"metadata"`
+
+	Role     string   `json:"role"`
+	Subjects []string `json:"subjects"`
+	// Event scopes the binding to a single event, an empty value
+	// grants the role in every event.
+	Event string `json:"event,omitempty"`
+}
+
+type RoleBindingList struct {
+	TypeMeta `json:",inline"`
+	ListMeta `json:"metadata"`
+
+	Items []RoleBinding `json:"items"`
+}
+
+// /v1/eventhooks
+// EventHook notifies an HTTP endpoint about the lifecycle of games
+type EventHook struct {
+	TypeMeta `json:",inline" yaml:",inline"`
+	Metadata `json:"metadata"`
+
+	URL string `json:"url"`
+	// Secret signs the deliveries (HMAC-SHA256), it's never returned by the API
+	Secret string `json:"secret,omitempty"`
+	// Event scopes the hook to a single event, an empty value notifies all events
+	Event string `json:"event,omitempty"`
+	// Triggers filters the notifications, an empty list notifies all of them
+	Triggers []HookTrigger `json:"triggers,omitempty"`
+	// Template formats the payload: json (default), slack or discord
+	Template HookTemplate `json:"template,omitempty"`
+
+	Status EventHookStatus `json:"status,omitempty"`
+}
+
+type EventHookList struct {
+	TypeMeta `json:",inline"`
+	ListMeta `json:"metadata"`
+
+	Items []EventHook `json:"items"`
+}
+
+type HookTrigger string
+
+const (
+	HookGameCreated   HookTrigger = "game.created"
+	HookGameStarted   HookTrigger = "game.started"
+	HookKeySolved     HookTrigger = "game.key_solved"
+	HookGameCompleted HookTrigger = "game.completed"
+	HookGameExpired   HookTrigger = "game.expired"
+)
+
+type HookTemplate string
+
+const (
+	HookTemplateJSON    HookTemplate = "json"
+	HookTemplateSlack   HookTemplate = "slack"
+	HookTemplateDiscord HookTemplate = "discord"
+)
+
+type EventHookStatus struct {
+	// Deliveries are the most recent attempts of notifying the endpoint
+	Deliveries []HookDelivery `json:"deliveries,omitempty"`
+}
+
+type HookDelivery struct {
+	ID         string      `json:"id"`
+	Trigger    HookTrigger `json:"trigger"`
+	Timestamp  string      `json:"timestamp"`
+	Attempts   int         `json:"attempts"`
+	StatusCode int         `json:"statusCode,omitempty"`
+	Delivered  bool        `json:"delivered"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// /v1/selfsubjectaccessreviews
+// SelfSubjectAccessReview checks whether the caller can perform an action
+type SelfSubjectAccessReview struct {
+	TypeMeta `json:",inline" yaml:",inline"`
+	Metadata `json:"metadata"`
+
+	Spec   AccessReviewSpec   `json:"spec"`
+	Status AccessReviewStatus `json:"status,omitempty"`
+}
+
+// /v1/subjectaccessreviews
+// SubjectAccessReview checks whether an arbitrary subject can perform an action
+type SubjectAccessReview struct {
+	TypeMeta `json:",inline" yaml:",inline"`
+	Metadata `json:"metadata"`
+
+	Spec   AccessReviewSpec   `json:"spec"`
+	Status AccessReviewStatus `json:"status,omitempty"`
+}
+
+type AccessReviewSpec struct {
+	// Subject is ignored by self subject access reviews, e.g.: github|user
+	Subject string `json:"subject,omitempty"`
+	// Verb is the HTTP method, e.g.: POST
+	Verb string `json:"verb"`
+	// Path is the request path, e.g.: /v1/challenges
+	Path string `json:"path"`
+}
+
+type AccessReviewStatus struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// /v1/users
+// User is a local account used to authenticate players when the event
+// doesn't have access to an external identity provider (e.g.: GitHub).
+type User struct {
+	TypeMeta `json:",inline" yaml:",inline"`
+	Metadata `json:"metadata"`
+
+	DisplayName string `json:"displayName,omitempty"`
+	Email       string `json:"email,omitempty"`
+	// Password is the plain text password, it's only used when creating or
+	// updating a user and it's never persisted.
+	Password string `json:"password,omitempty"`
+	// PasswordHash is the bcrypt hash of the user password
+	PasswordHash string `json:"passwordHash,omitempty"`
+}
+
+type UserList struct {
+	TypeMeta `json:",inline"`
+	ListMeta `json:"metadata"`
+
+	Items []User `json:"items"`
+}
+
+// /v1/sessions
+// Session tracks a refresh token issued for a player
+type Session struct {
+	TypeMeta `json:",inline" yaml:",inline"`
+	Metadata `json:"metadata"`
+
+	Subject string       `json:"subject"`
+	Claims  PlayerClaims `json:"claims"`
+	// AccessTokenID is the JWT ID of the last access token issued by the session
+	AccessTokenID    string `json:"accessTokenID"`
+	RefreshTokenHash string `json:"refreshTokenHash,omitempty"`
+	ExpiresAt        string `json:"expiresAt"`
+}
+
+type SessionList struct {
+	TypeMeta `json:",inline"`
+	ListMeta `json:"metadata"`
+
+	Items []Session `json:"items"`
+}
+
+// RevokedToken denies an access token by its JWT ID until it expires
+type RevokedToken struct {
+	TypeMeta `json:",inline" yaml:",inline"`
+	Metadata `json:"metadata"`
+
+	ExpiresAt string `json:"expiresAt"`
+}
+
+const (
+	GitHubProvider = "github"
+	LocalProvider  = "local"
+	// X509Provider authenticates players by client certificates
+	X509Provider = "x509"
+)
+
 type PlayerClaims struct {
 	Name      string `json:"name"`
 	Login     string `json:"login"`
 	AvatarURL string `json:"avatar_url"`
 	Location  string `json:"location"`
 	Email     string `json:"email"`
+	// Provider is the identity provider which authenticated the player
+	Provider string `json:"provider,omitempty"`
+	// Roles are granted by the memberships of the player in the identity provider
+	Roles []string `json:"roles,omitempty"`
+
+	// SessionID is the server-side session which issued the token
+	SessionID string `json:"sid,omitempty"`
 
-	AccessToken string `json:"access_token,omitempty"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 
 	jwt.StandardClaims
 }
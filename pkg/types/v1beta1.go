@@ -0,0 +1,94 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+)
+
+// The v1beta1 version differs from the hub in:
+//
+// Challenge: keys is a list of keys with their names
+//   keys: [{name: main, value: ..., weight: 0.9}]
+// Game: the challenge and its generation are a reference
+//   challengeRef: {name: foo, generation: 2}
+
+func v1beta1ChallengeToHub(fields map[string]interface{}) error {
+	list, ok := fields["keys"].([]interface{})
+	if !ok {
+		if fields["keys"] != nil {
+			return fmt.Errorf("keys: expected a list")
+		}
+		return nil
+	}
+	keys := map[string]interface{}{}
+	for i, item := range list {
+		key, ok := item.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("keys[%d]: expected an object", i)
+		}
+		name, _ := key["name"].(string)
+		if name == "" {
+			return fmt.Errorf("keys[%d]: missing the name", i)
+		}
+		if _, exists := keys[name]; exists {
+			return fmt.Errorf("keys[%d]: duplicated key %q", i, name)
+		}
+		delete(key, "name")
+		keys[name] = key
+	}
+	fields["keys"] = keys
+	return nil
+}
+
+func v1beta1ChallengeFromHub(fields map[string]interface{}) error {
+	keys, ok := fields["keys"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	var names []string
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := []interface{}{}
+	for _, name := range names {
+		key, ok := keys[name].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("keys.%s: expected an object", name)
+		}
+		key["name"] = name
+		list = append(list, key)
+	}
+	fields["keys"] = list
+	return nil
+}
+
+func v1beta1GameToHub(fields map[string]interface{}) error {
+	ref, ok := fields["challengeRef"].(map[string]interface{})
+	if !ok {
+		if fields["challengeRef"] != nil {
+			return fmt.Errorf("challengeRef: expected an object")
+		}
+		return nil
+	}
+	delete(fields, "challengeRef")
+	fields["challenge"] = ref["name"]
+	if generation, ok := ref["generation"]; ok {
+		fields["challengeGeneration"] = generation
+	}
+	return nil
+}
+
+func v1beta1GameFromHub(fields map[string]interface{}) error {
+	// the reference is only served for games with a challenge
+	if name, ok := fields["challenge"]; ok && name != "" {
+		ref := map[string]interface{}{"name": name}
+		if generation, ok := fields["challengeGeneration"]; ok {
+			ref["generation"] = generation
+		}
+		fields["challengeRef"] = ref
+	}
+	delete(fields, "challenge")
+	delete(fields, "challengeGeneration")
+	return nil
+}
@@ -0,0 +1,107 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// GroupName prefixes the versions of the objects, e.g.: kubeplay.io/v1
+	GroupName = "kubeplay.io"
+	// HubVersion is the version of the internal types, the other versions are
+	// converted to it and from it (hub and spoke). It's the StorageVersion.
+	HubVersion = "v1"
+)
+
+// ConvertFunc changes the fields of an object in place, the fields are the decoded JSON
+type ConvertFunc func(fields map[string]interface{}) error
+
+// ServedVersion is a version of the API served in its own path, e.g.: /v1beta1
+type ServedVersion struct {
+	Name string
+	// ToHub and FromHub convert the kinds with different fields in the version,
+	// the other kinds have the same fields as the hub.
+	ToHub   map[string]ConvertFunc
+	FromHub map[string]ConvertFunc
+}
+
+// ServedVersions are the versions of the API, the first one is the hub
+var ServedVersions = []ServedVersion{
+	{Name: HubVersion},
+	{
+		Name: "v1beta1",
+		ToHub: map[string]ConvertFunc{
+			ChallengeKind: v1beta1ChallengeToHub,
+			GameKind:      v1beta1GameToHub,
+		},
+		FromHub: map[string]ConvertFunc{
+			ChallengeKind: v1beta1ChallengeFromHub,
+			GameKind:      v1beta1GameFromHub,
+		},
+	},
+}
+
+// GroupVersion returns the apiVersion of a served version, e.g.: kubeplay.io/v1beta1
+func GroupVersion(name string) string {
+	return GroupName + "/" + name
+}
+
+// LookupVersion returns a served version by its apiVersion, e.g.: kubeplay.io/v1
+func LookupVersion(apiVersion string) *ServedVersion {
+	for i, v := range ServedVersions {
+		if GroupVersion(v.Name) == apiVersion {
+			return &ServedVersions[i]
+		}
+	}
+	return nil
+}
+
+// ConvertToHub converts an object of a served version to the hub version,
+// objects of unknown versions are refused.
+func ConvertToHub(apiVersion, kind string, data []byte) ([]byte, error) {
+	v := LookupVersion(apiVersion)
+	if v == nil {
+		return nil, fmt.Errorf("unknown apiVersion %q, expected one of: %s", apiVersion, strings.Join(servedGroupVersions(), ", "))
+	}
+	if v.Name == HubVersion {
+		return data, nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if convert := v.ToHub[kind]; convert != nil {
+		if err := convert(fields); err != nil {
+			return nil, fmt.Errorf("failed converting %s from %s: %v", kind, apiVersion, err)
+		}
+	}
+	fields["apiVersion"] = GroupVersion(HubVersion)
+	return json.Marshal(fields)
+}
+
+// ConvertFromHub converts the fields of an object of the hub version to a served version
+func ConvertFromHub(name string, fields map[string]interface{}) error {
+	kind, _ := fields["kind"].(string)
+	for _, v := range ServedVersions {
+		if v.Name != name {
+			continue
+		}
+		if convert := v.FromHub[kind]; convert != nil {
+			if err := convert(fields); err != nil {
+				return fmt.Errorf("failed converting %s to %s: %v", kind, GroupVersion(name), err)
+			}
+		}
+		fields["apiVersion"] = GroupVersion(name)
+		return nil
+	}
+	return fmt.Errorf("unknown version %q", name)
+}
+
+func servedGroupVersions() []string {
+	var versions []string
+	for _, v := range ServedVersions {
+		versions = append(versions, GroupVersion(v.Name))
+	}
+	return versions
+}
@@ -0,0 +1,240 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const v1beta1 = GroupName + "/v1beta1"
+
+func TestConvertToHub(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		kind    string
+		data    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "challenge keys",
+			kind: ChallengeKind,
+			data: `{"kind":"Challenge","apiVersion":"kubeplay.io/v1beta1","keys":[{"name":"main","value":"a","weight":0.9},{"name":"bonus","value":"b"}]}`,
+			want: `{"kind":"Challenge","apiVersion":"kubeplay.io/v1","keys":{"bonus":{"value":"b"},"main":{"value":"a","weight":0.9}}}`,
+		},
+		{
+			name: "challenge without keys",
+			kind: ChallengeKind,
+			data: `{"kind":"Challenge","apiVersion":"kubeplay.io/v1beta1"}`,
+			want: `{"kind":"Challenge","apiVersion":"kubeplay.io/v1"}`,
+		},
+		{
+			name: "challenge with an empty list of keys",
+			kind: ChallengeKind,
+			data: `{"kind":"Challenge","apiVersion":"kubeplay.io/v1beta1","keys":[]}`,
+			want: `{"kind":"Challenge","apiVersion":"kubeplay.io/v1","keys":{}}`,
+		},
+		{
+			name:    "duplicated key names",
+			kind:    ChallengeKind,
+			data:    `{"kind":"Challenge","apiVersion":"kubeplay.io/v1beta1","keys":[{"name":"main"},{"name":"main"}]}`,
+			wantErr: `duplicated key "main"`,
+		},
+		{
+			name:    "missing key name",
+			kind:    ChallengeKind,
+			data:    `{"kind":"Challenge","apiVersion":"kubeplay.io/v1beta1","keys":[{"value":"a"}]}`,
+			wantErr: "keys[0]: missing the name",
+		},
+		{
+			name:    "keys of the hub in v1beta1",
+			kind:    ChallengeKind,
+			data:    `{"kind":"Challenge","apiVersion":"kubeplay.io/v1beta1","keys":{"main":{}}}`,
+			wantErr: "expected a list",
+		},
+		{
+			name:    "invalid key",
+			kind:    ChallengeKind,
+			data:    `{"kind":"Challenge","apiVersion":"kubeplay.io/v1beta1","keys":["main"]}`,
+			wantErr: "keys[0]: expected an object",
+		},
+		{
+			name: "game reference",
+			kind: GameKind,
+			data: `{"kind":"Game","apiVersion":"kubeplay.io/v1beta1","challengeRef":{"name":"foo","generation":2}}`,
+			want: `{"kind":"Game","apiVersion":"kubeplay.io/v1","challenge":"foo","challengeGeneration":2}`,
+		},
+		{
+			name: "game reference without generation",
+			kind: GameKind,
+			data: `{"kind":"Game","apiVersion":"kubeplay.io/v1beta1","challengeRef":{"name":"foo"}}`,
+			want: `{"kind":"Game","apiVersion":"kubeplay.io/v1","challenge":"foo"}`,
+		},
+		{
+			name: "kinds without conversions",
+			kind: EventKind,
+			data: `{"kind":"Event","apiVersion":"kubeplay.io/v1beta1","metadata":{"name":"meetup"}}`,
+			want: `{"kind":"Event","apiVersion":"kubeplay.io/v1","metadata":{"name":"meetup"}}`,
+		},
+		{
+			name: "hub version",
+			kind: ChallengeKind,
+			data: `{"kind":"Challenge","apiVersion":"kubeplay.io/v1","keys":{"main":{}}}`,
+			want: `{"kind":"Challenge","apiVersion":"kubeplay.io/v1","keys":{"main":{}}}`,
+		},
+		{
+			name:    "unknown version",
+			kind:    ChallengeKind,
+			data:    `{"kind":"Challenge","apiVersion":"kubeplay.io/v2"}`,
+			wantErr: "unknown apiVersion",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := &TypeMeta{}
+			if err := json.Unmarshal([]byte(tc.data), meta); err != nil {
+				t.Fatal(err)
+			}
+			data, err := ConvertToHub(meta.APIVersion, tc.kind, []byte(tc.data))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSONEqual(t, tc.want, string(data))
+		})
+	}
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		obj  Object
+		// v1beta1 holds the converted fields in the served version
+		v1beta1 string
+		removed []string
+	}{
+		{
+			name: "challenge",
+			obj: &Challenge{
+				TypeMeta: TypeMeta{Kind: ChallengeKind, APIVersion: StorageVersion},
+				Metadata: Metadata{Name: "foo"},
+				Keys: map[string]Key{
+					"main":  {Value: "a", Description: "the main key", Weight: 0.5},
+					"bonus": {Value: "b", Weight: 0.25},
+				},
+			},
+			v1beta1: `{"apiVersion":"kubeplay.io/v1beta1",
+				"keys":[{"name":"bonus","value":"b","description":"","weight":0.25},{"name":"main","value":"a","description":"the main key","weight":0.5}]}`,
+		},
+		{
+			name: "challenge without keys",
+			obj: &Challenge{
+				TypeMeta: TypeMeta{Kind: ChallengeKind, APIVersion: StorageVersion},
+				Metadata: Metadata{Name: "foo"},
+			},
+			v1beta1: `{"apiVersion":"kubeplay.io/v1beta1","keys":null}`,
+		},
+		{
+			name: "game",
+			obj: &Game{
+				TypeMeta:            TypeMeta{Kind: GameKind, APIVersion: StorageVersion},
+				Metadata:            Metadata{Name: "g1"},
+				Challenge:           "foo",
+				ChallengeGeneration: 3,
+				Player:              "github|user",
+			},
+			v1beta1: `{"apiVersion":"kubeplay.io/v1beta1","player":"github|user","challengeRef":{"name":"foo","generation":3}}`,
+			removed: []string{"challenge", "challengeGeneration"},
+		},
+		{
+			name: "game without generation",
+			obj: &Game{
+				TypeMeta:  TypeMeta{Kind: GameKind, APIVersion: StorageVersion},
+				Metadata:  Metadata{Name: "g1"},
+				Challenge: "foo",
+			},
+			v1beta1: `{"apiVersion":"kubeplay.io/v1beta1","challengeRef":{"name":"foo"}}`,
+			removed: []string{"challenge", "challengeGeneration"},
+		},
+		{
+			name: "game without challenge",
+			obj: &Game{
+				TypeMeta: TypeMeta{Kind: GameKind, APIVersion: StorageVersion},
+				Metadata: Metadata{Name: "g1"},
+				Player:   "github|user",
+			},
+			v1beta1: `{"apiVersion":"kubeplay.io/v1beta1","player":"github|user"}`,
+			removed: []string{"challenge", "challengeGeneration", "challengeRef"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := json.Marshal(tc.obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fields := map[string]interface{}{}
+			if err := json.Unmarshal(data, &fields); err != nil {
+				t.Fatal(err)
+			}
+			if err := ConvertFromHub("v1beta1", fields); err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(tc.v1beta1), &want); err != nil {
+				t.Fatal(err)
+			}
+			for key, value := range want {
+				if !reflect.DeepEqual(fields[key], value) {
+					t.Errorf("expected %s=%v in v1beta1, got %v", key, value, fields[key])
+				}
+			}
+			for _, key := range tc.removed {
+				if _, ok := fields[key]; ok {
+					t.Errorf("expected %s to be removed in v1beta1", key)
+				}
+			}
+			served, err := json.Marshal(fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hub, err := ConvertToHub(v1beta1, tc.obj.GetObjectKind(), served)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := tc.obj.New()
+			if err := json.Unmarshal(hub, got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.obj) {
+				t.Errorf("expected the object after a round trip\n%#v\ngot\n%#v", tc.obj, got)
+			}
+		})
+	}
+}
+
+func TestConvertFromHubUnknownVersion(t *testing.T) {
+	fields := map[string]interface{}{"kind": ChallengeKind, "apiVersion": StorageVersion}
+	if err := ConvertFromHub("v2", fields); err == nil {
+		t.Error("expected an error for an unknown version")
+	}
+}
+
+func assertJSONEqual(t *testing.T, want, got string) {
+	t.Helper()
+	var w, g interface{}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("invalid JSON %q: %v", want, err)
+	}
+	if err := json.Unmarshal([]byte(got), &g); err != nil {
+		t.Fatalf("invalid JSON %q: %v", got, err)
+	}
+	if !reflect.DeepEqual(w, g) {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}